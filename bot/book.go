@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/chartgen"
+	"github.com/Apakhov/stocks-bot/i18n"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
+	"github.com/Apakhov/stocks-bot/stockapi"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// errEmptyOrderBook error of order book without orders
+var errEmptyOrderBook = errors.New("order book is empty")
+
+// BookHandler sends order book depth chart, args are "TICKER [DEPTH]"
+func (b *VkRocketBot) BookHandler(chatID int64, p *i18n.Printer, args string) {
+	now := time.Now()
+
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	logger := logging.FromContext(ctx, b.logger).With(
+		zap.Int64("chat_id", chatID),
+		zap.String("args", args),
+	)
+	sendText := func(text string) {
+		if err := b.messenger.SendText(ctx, chatID, text); err != nil {
+			logger.Warn("can not send book reply", zap.Error(err))
+		}
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		sendText(p.Sprintf(i18n.KeyBookUsage, stockapi.MaxOrderBookDepth))
+		return
+	}
+	depth := stockapi.MaxOrderBookDepth
+	if len(fields) == 2 {
+		var err error
+		depth, err = strconv.Atoi(fields[1])
+		if err != nil || depth < 1 || depth > stockapi.MaxOrderBookDepth {
+			sendText(p.Sprintf(i18n.KeyBookUsage, stockapi.MaxOrderBookDepth))
+			return
+		}
+	}
+
+	ticker := b.resolveTicker(fields[0])
+	logger = logger.With(zap.String("ticker", ticker))
+	// caption is built from the same book the chart is drawn from
+	imgBytes, book, err := b.requestOrderBook(ctx, ticker, depth)
+	switch {
+	case errors.Is(err, stockapi.ErrUnknownTicker):
+		sendText(p.Sprintf(i18n.KeyQuoteUnknownTicker, fields[0]))
+		return
+	case errors.Is(err, errEmptyOrderBook):
+		sendText(p.Sprintf(i18n.KeyBookEmpty, book.Ticker))
+		return
+	case err != nil:
+		logger.Error("can not get depth chart", zap.Error(err))
+		sendText(p.Sprintf(i18n.KeyBookError))
+		return
+	}
+
+	if err := b.messenger.SendPhoto(ctx, chatID, imgBytes, bookCaption(p, book), nil); err != nil {
+		logger.Warn("can not send depth chart", zap.Error(err))
+	}
+	logger.Info("book command done", zap.Duration("elapsed", time.Since(now)))
+}
+
+// bookCaption describes best prices and spread
+func bookCaption(p *i18n.Printer, book *ohlc.OrderBook) string {
+	lines := []string{p.Sprintf(i18n.KeyBookTitle, book.Name, book.Ticker)}
+	if !book.Trading {
+		lines = append(lines, p.Sprintf(i18n.KeyBookNotTrading))
+	}
+
+	pricePrecision := chartgen.PricePrecision(book.MinPriceIncrement)
+	if bid, ask, spread, ok := book.Spread(); ok {
+		lines = append(lines, p.Sprintf(i18n.KeyBookSpread,
+			p.Money(bid, pricePrecision, book.Currency),
+			p.Money(ask, pricePrecision, book.Currency),
+			p.Number(spread, pricePrecision),
+			p.Number(spread/((bid+ask)/2)*100, 3),
+		))
+	} else {
+		lines = append(lines, p.Sprintf(i18n.KeyBookOneSided))
+	}
+
+	var bidLots, askLots float64
+	for _, level := range book.Bids {
+		bidLots += level.Quantity
+	}
+	for _, level := range book.Asks {
+		askLots += level.Quantity
+	}
+	lines = append(lines, p.Sprintf(i18n.KeyBookLots, p.Number(bidLots, 0), p.Number(askLots, 0)))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+	"github.com/Apakhov/stocks-bot/stockapi"
+	"github.com/Apakhov/stocks-bot/tcpproto"
+)
+
+// serveOrderBook answers one order book request of fake stockserver with status and book
+func serveOrderBook(t *testing.T, image []byte, status string, book *ohlc.OrderBook) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var parts []string
+		tcpproto.ReadMsg(conn, func(buf []byte) error {
+			_, err := tcpproto.ParseStringSlice(buf, &parts)
+			return err
+		})
+		if len(parts) != 4 || parts[0] != tcpproto.OrderBookRequest || parts[1] != "SBER" || parts[2] != "10" {
+			t.Errorf("request parts %v", parts)
+		}
+		bookBytes, _ := json.Marshal(book)
+		response := tcpproto.PrepareBytes(nil, image)
+		response = tcpproto.PrepareString(response, status)
+		tcpproto.WriteMsg(conn, tcpproto.PrepareBytes(response, bookBytes))
+	}()
+	return l.Addr().String()
+}
+
+func TestRequestOrderBook(t *testing.T) {
+	book := &ohlc.OrderBook{
+		Ticker: "SBER",
+		Bids:   []ohlc.PriceLevel{{Price: 100, Quantity: 5}},
+		Asks:   []ohlc.PriceLevel{{Price: 100.5, Quantity: 7}},
+	}
+	for _, tc := range []struct {
+		status   string
+		image    []byte
+		book     *ohlc.OrderBook
+		err      error
+		wantBook bool
+	}{
+		{tcpproto.OrderBookOK, []byte("jpg"), book, nil, true},
+		{tcpproto.OrderBookEmpty, nil, &ohlc.OrderBook{Ticker: "SBER"}, errEmptyOrderBook, true},
+		{tcpproto.OrderBookUnknownTicker, nil, nil, stockapi.ErrUnknownTicker, false},
+	} {
+		t.Run(tc.status, func(t *testing.T) {
+			b := &VkRocketBot{stocksTCPHost: serveOrderBook(t, tc.image, tc.status, tc.book)}
+			image, got, err := b.requestOrderBook(context.Background(), "SBER", 10)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error = %v, want %v", err, tc.err)
+			}
+			if string(image) != string(tc.image) {
+				t.Errorf("image = %q, want %q", image, tc.image)
+			}
+			if (got != nil) != tc.wantBook {
+				t.Fatalf("book = %+v", got)
+			}
+			if got != nil && (got.Ticker != "SBER" || len(got.Bids) != len(tc.book.Bids) || len(got.Asks) != len(tc.book.Asks)) {
+				t.Errorf("book = %+v, want %+v", got, tc.book)
+			}
+		})
+	}
+}
@@ -2,231 +2,520 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"math"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Apakhov/stocks-bot/chartgen"
+	"github.com/Apakhov/stocks-bot/i18n"
+	"github.com/Apakhov/stocks-bot/instruments"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/messenger/discord"
+	"github.com/Apakhov/stocks-bot/messenger/slack"
+	"github.com/Apakhov/stocks-bot/messenger/telegram"
+	"github.com/Apakhov/stocks-bot/messenger/vk"
 	"github.com/Apakhov/stocks-bot/ohlc"
+	"github.com/Apakhov/stocks-bot/portfolio"
 	"github.com/Apakhov/stocks-bot/stockapi"
 	"github.com/Apakhov/stocks-bot/tcpproto"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
-// StockCommand description for stock command
-type StockCommand struct {
-	Command string
-	Ticker  string
-}
+const (
+	// PlatformTelegram telegram bot
+	PlatformTelegram = "telegram"
+	// PlatformVK vk community bot
+	PlatformVK = "vk"
+	// PlatformSlack slack slash command
+	PlatformSlack = "slack"
+	// PlatformDiscord discord application command
+	PlatformDiscord = "discord"
+)
 
 // VkRocketBotConfig config for vk rocket bot
 type VkRocketBotConfig struct {
-	StocksHost    string
-	StocksTCPHost string
-	TelegramToken string
-	TinkoffToken  string
-	CommandStocks []*StockCommand
+	StocksHost     string
+	StocksTCPHost  string
+	TinkoffToken   string
+	StockAPI       stockapi.PolicyConfig
+	Providers      stockapi.ProvidersConfig
+	Featured       []*instruments.Featured
+	DefaultLang    i18n.Lang
+	LangFile       string
+	Themes         chartgen.Themes
+	ChatThemesFile string
+	PortfolioFile  string
+	Log            logging.Config
+	Dispatcher     DispatcherConfig
+	Platform       string
+	Telegram       telegram.Config
+	VK             vk.Config
+	Slack          slack.Config
+	Discord        discord.Config
 }
 
 // VkRocketBot bot for drawing candlesticks
 type VkRocketBot struct {
-	botAPI         *tgbotapi.BotAPI
+	messenger      messenger.Messenger
 	stockAPIClient stockapi.StockClient
+	chartGenerator *chartgen.ChartGenerator
+	dispatcher     *dispatcher
 
 	stocksHost    string
 	stocksTCPHost string
-
-	tickerCommands map[string]string
-	logger         *zap.Logger
+	platform      string
+	langs         *chatLangs
+	themes        *chatThemes
+	portfolios    *chatPortfolios
+
+	tickerCommandsMu sync.RWMutex
+	tickerCommands   map[string]*instruments.Featured
+	logger           *zap.Logger
 }
 
 // NewVkRocketBot returns new CandlesticksBot
 func NewVkRocketBot(cfg *VkRocketBotConfig) (*VkRocketBot, error) {
-	bot, err := tgbotapi.NewBotAPI(cfg.TelegramToken)
+	logger, err := logging.New(cfg.Log)
 	if err != nil {
 		return nil, err
 	}
-	//bot.Debug = true
 
-	stockAPIClient, err := stockapi.NewTinkoffStockClient(cfg.TinkoffToken)
+	var platform messenger.Messenger
+	switch cfg.Platform {
+	case "", PlatformTelegram:
+		platform, err = telegram.New(cfg.Telegram, logger)
+		if err != nil {
+			return nil, err
+		}
+	case PlatformVK:
+		platform = vk.New(cfg.VK, logger)
+	case PlatformSlack:
+		platform = slack.New(cfg.Slack, logger)
+	case PlatformDiscord:
+		platform, err = discord.New(cfg.Discord, logger)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unknown platform %q", cfg.Platform)
+	}
+
+	policyMetrics := stockapi.NewPolicyMetrics(prometheus.DefaultRegisterer)
+	stockAPIClient, err := stockapi.NewStockClient(cfg.TinkoffToken, cfg.Providers, cfg.StockAPI, policyMetrics, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	logger, err := zap.NewProduction()
+	langs, err := newChatLangs(cfg.LangFile, cfg.DefaultLang)
 	if err != nil {
 		return nil, err
 	}
 
-	tickerCommands := make(map[string]string, len(cfg.CommandStocks))
-	for _, command := range cfg.CommandStocks {
-		tickerCommands[command.Command] = command.Ticker
+	themes, err := newChatThemes(cfg.ChatThemesFile, cfg.Themes)
+	if err != nil {
+		return nil, err
 	}
 
-	return &VkRocketBot{
-		botAPI:         bot,
+	portfolios, err := newChatPortfolios(cfg.PortfolioFile)
+	if err != nil {
+		return nil, err
+	}
+
+	vkRocketBot := &VkRocketBot{
+		messenger:      platform,
 		stockAPIClient: stockAPIClient,
+		chartGenerator: &chartgen.ChartGenerator{},
 		stocksHost:     cfg.StocksHost,
 		stocksTCPHost:  cfg.StocksTCPHost,
-		tickerCommands: tickerCommands,
+		platform:       cfg.Platform,
+		langs:          langs,
+		themes:         themes,
+		portfolios:     portfolios,
 		logger:         logger,
-	}, nil
+	}
+	vkRocketBot.SetCommands(cfg.Featured)
+	vkRocketBot.dispatcher = newDispatcher(cfg.Dispatcher, vkRocketBot.handleUpdate, vkRocketBot.handleLimitedUpdate, logger)
+
+	return vkRocketBot, nil
 }
 
-// GenerateDefaultCaption default generates capition
-func (b *VkRocketBot) generateDefaultCaption(ticker string, candles []ohlc.TOHLCV) string {
-	openPrice := candles[0].Open
-	closePrice := candles[len(candles)-1].Close
-	priceDelta := closePrice - openPrice
-	percentDelta := priceDelta / openPrice * 100
-	percentDeltaAbs := math.Abs(percentDelta)
+// SetCommands replaces ticker commands, safe to call while bot is running
+func (b *VkRocketBot) SetCommands(featured []*instruments.Featured) {
+	tickerCommands := make(map[string]*instruments.Featured, len(featured))
+	for _, f := range featured {
+		tickerCommands[f.Command] = f
+	}
+
+	b.tickerCommandsMu.Lock()
+	b.tickerCommands = tickerCommands
+	b.tickerCommandsMu.Unlock()
+}
+
+func (b *VkRocketBot) lookupTicker(command string) (string, bool) {
+	b.tickerCommandsMu.RLock()
+	defer b.tickerCommandsMu.RUnlock()
 
-	grade := "нейтральный"
-	if percentDeltaAbs > 1.5 {
-		grade = "хороший"
+	featured, ok := b.tickerCommands[command]
+	if !ok {
+		return "", false
 	}
-	if percentDeltaAbs > 5 {
-		grade = "прекрасный"
+	return featured.Ticker, true
+}
+
+func (b *VkRocketBot) requestStock(ctx context.Context, state *chartState, theme string, from time.Time, to time.Time) ([]byte, error) {
+	return b.requestImage(
+		state.Ticker,
+		from.Format(time.RFC3339),
+		to.Format(time.RFC3339),
+		state.Interval,
+		logging.RequestID(ctx),
+		string(state.Options.Type),
+		chartgen.FormatIndicators(state.Options.Indicators),
+		state.Currency,
+		theme,
+	)
+}
+
+// requestOrderBook returns depth chart and order book it is drawn from,
+// errEmptyOrderBook is returned with the book
+func (b *VkRocketBot) requestOrderBook(ctx context.Context, ticker string, depth int) ([]byte, *ohlc.OrderBook, error) {
+	var (
+		imageBytes, bookBytes []byte
+		status                string
+	)
+	err := b.request(func(buf []byte) error {
+		buf, err := tcpproto.ParseBytes(buf, &imageBytes)
+		if err != nil {
+			return err
+		}
+		if buf, err = tcpproto.ParseString(buf, &status); err != nil {
+			return err
+		}
+		_, err = tcpproto.ParseBytes(buf, &bookBytes)
+		return err
+	}, tcpproto.OrderBookRequest, ticker, strconv.Itoa(depth), logging.RequestID(ctx))
+	if err != nil {
+		return nil, nil, err
 	}
-	if percentDeltaAbs > 10 {
-		grade = "выдающийся"
+
+	switch status {
+	case tcpproto.OrderBookOK, tcpproto.OrderBookEmpty:
+	case tcpproto.OrderBookUnknownTicker:
+		return nil, nil, stockapi.ErrUnknownTicker
+	default:
+		return nil, nil, fmt.Errorf("unknown order book status %q", status)
 	}
-	if percentDeltaAbs > 15 {
-		grade = "фантастический"
+	var book ohlc.OrderBook
+	if err := json.Unmarshal(bookBytes, &book); err != nil {
+		return nil, nil, fmt.Errorf("can not decode order book: %w", err)
 	}
-
-	negativeAdj := ""
-	if len(grade) > 0 && percentDelta < 0 {
-		negativeAdj = " отрицательно"
+	if status == tcpproto.OrderBookEmpty {
+		return nil, &book, errEmptyOrderBook
 	}
+	return imageBytes, &book, nil
+}
 
-	return fmt.Sprintf("%s стоит %.2f RUB (%+.2f%% за сутки). Какой%s %s результат!", ticker, closePrice, percentDelta, negativeAdj, grade)
+// requestImage sends request frame to stockserver and reads image
+func (b *VkRocketBot) requestImage(parts ...string) ([]byte, error) {
+	var imageBytes []byte
+	err := b.request(func(buf []byte) error {
+		_, err := tcpproto.ParseBytes(buf, &imageBytes)
+		return err
+	}, parts...)
+	return imageBytes, err
 }
 
-func (b *VkRocketBot) requestStock(ticker string, dayAgo time.Time, now time.Time) ([]byte, error) {
+// request sends request frame to stockserver and parses response with parse
+func (b *VkRocketBot) request(parse func(buf []byte) error, parts ...string) error {
 	tcpAddr, err := net.ResolveTCPAddr("tcp", b.stocksTCPHost)
 	if err != nil {
-		return nil, fmt.Errorf("ResolveTCPAddr failed: %w", err)
+		return fmt.Errorf("ResolveTCPAddr failed: %w", err)
 	}
 
 	conn, err := net.DialTCP("tcp", nil, tcpAddr)
 	if err != nil {
-		return nil, fmt.Errorf("dial failed: %w", err)
+		return fmt.Errorf("dial failed: %w", err)
 	}
 	defer conn.Close()
 
-	err = tcpproto.WriteMsg(conn,
-		tcpproto.PrepareStrings(nil,
-			ticker,
-			dayAgo.Format(time.RFC3339),
-			now.Format(time.RFC3339),
-			"5min",
-		),
-	)
+	err = tcpproto.WriteMsg(conn, tcpproto.PrepareStrings(nil, parts...))
 	if err != nil {
-		return nil, fmt.Errorf("write to server failed: %w", err)
+		return fmt.Errorf("write to server failed: %w", err)
 	}
 
-	var imageBytes []byte
-	err = tcpproto.ReadMsg(conn, func(buf []byte) error {
-		_, err = tcpproto.ParseBytes(buf, &imageBytes)
-		return err
-	})
-	if err != nil {
-		return nil, fmt.Errorf("read from server failed: %w", err)
+	if err := tcpproto.ReadMsg(conn, parse); err != nil {
+		return fmt.Errorf("read from server failed: %w", err)
 	}
-
-	fmt.Printf("read %d img bytes\n", len(imageBytes))
-	return imageBytes, nil
+	return nil
 }
 
-func (b *VkRocketBot) generalStockHandler(chatID int64, ticker string) {
+// renderChart returns chart image in chat theme and caption for chart state
+func (b *VkRocketBot) renderChart(ctx context.Context, logger *zap.Logger, p *i18n.Printer, chatID int64, state *chartState) ([]byte, string, error) {
 	now := time.Now()
-	dayAgo := now.Add(-24 * time.Hour)
+	cal := stockapi.CalendarOf(b.stockAPIClient, state.Ticker)
+	from, to := state.Period.window(cal, now)
 
-	imgBytes, err := b.requestStock(ticker, dayAgo, now)
+	start := time.Now()
+	imgBytes, err := b.requestStock(ctx, state, b.themes.Name(chatID), from, to)
 	if err != nil {
-		b.logger.Info("requesting tcp img: ", zap.Error(err))
+		return nil, "", fmt.Errorf("can not request chart image: %w", err)
 	}
+	logger.Debug("chart image received", zap.Int("bytes", len(imgBytes)), zap.Duration("elapsed", time.Since(start)))
 
-	fakeCandle, err := b.stockAPIClient.GetCandlesticks(context.Background(), dayAgo, now, stockapi.CandlestickInterval1Day, ticker)
+	// caption needs only recent sessions, 52 weeks range is left to /quote
+	daily, err := b.fetchDaily(ctx, state.Ticker, now, recentDays)
 	if err != nil {
-		b.logger.Error("can not fetch tinkoff api: " + err.Error())
-		return
+		return nil, "", fmt.Errorf("can not fetch tinkoff api: %w", err)
 	}
 
-	// imageURLRaw := fmt.Sprintf(
-	// 	"http://%s/candlesticks/%s/%s/%s/5min/chart.jpg",
-	// 	b.stocksHost,
-	// 	ticker,
-	// 	dayAgo.Format(time.RFC3339),
-	// 	now.Format(time.RFC3339),
-	// )
-
-	// imageResp, err := http.Get(imageURLRaw)
-	// if err != nil {
-	// 	b.logger.Info("requesting image", zap.Error(err))
-	// 	return
-	// }
-	// defer imageResp.Body.Close()
-
-	// imageBytes, err := ioutil.ReadAll(imageResp.Body)
-	// if err != nil {
-	// 	b.logger.Info("reading image", zap.Error(err))
-	// 	return
-	// }
-
-	resp := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "Some Name", Bytes: imgBytes})
-	resp.Caption = b.generateDefaultCaption(ticker, fakeCandle.TOHLCs)
-	_, err = b.botAPI.Send(resp)
-	if err != nil {
-		b.logger.Info(err.Error())
-	} else {
-		b.logger.Info("ok")
+	return imgBytes, b.generateDefaultCaption(p, daily, cal, now, false), nil
+}
 
+// generalStockHandler sends chart of ticker, currency is optional chart currency
+func (b *VkRocketBot) generalStockHandler(chatID int64, p *i18n.Printer, ticker, currency string) {
+	now := time.Now()
+
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	logger := logging.FromContext(ctx, b.logger).With(
+		zap.Int64("chat_id", chatID),
+		zap.String("ticker", ticker),
+	)
+	logger.Info("stock command received")
+
+	state := newChartState(ticker)
+	state.Currency = currency
+	imgBytes, caption, err := b.renderChart(ctx, logger, p, chatID, state)
+	if err != nil {
+		logger.Error("can not render chart", zap.Error(err))
+		if err := b.messenger.SendText(ctx, chatID, p.Sprintf(i18n.KeyChartError)); err != nil {
+			logger.Warn("can not send error", zap.Error(err))
+		}
+		return
+	}
+
+	err = b.messenger.SendPhoto(ctx, chatID, imgBytes, caption, state.keyboard(p))
+	if err != nil {
+		logger.Warn("can not send chart", zap.Error(err))
 	}
 
-	b.logger.Info(
+	logger.Info(
 		"inline query done",
 		zap.Time("now", now),
+		zap.Duration("elapsed", time.Since(now)),
+	)
+}
+
+// ChartCallbackHandler redraws chart message in place after button press
+func (b *VkRocketBot) ChartCallbackHandler(callback *messenger.Callback) {
+	now := time.Now()
+
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	chatID := callback.ChatID
+	p := b.langs.Printer(chatID, callback.Lang)
+	logger := logging.FromContext(ctx, b.logger).With(
+		zap.String("data", callback.Data),
 		zap.Int64("chat_id", chatID),
-		zap.String("ticker", ticker),
 	)
+
+	callbackText := ""
+	defer func() {
+		if err := b.messenger.AnswerCallback(ctx, callback, callbackText); err != nil {
+			logger.Warn("can not answer callback", zap.Error(err))
+		}
+	}()
+
+	state, err := decodeChartState(callback.Data)
+	if err != nil {
+		logger.Warn("can not decode callback", zap.Error(err))
+		callbackText = p.Sprintf(i18n.KeyUnknownAction)
+		return
+	}
+	logger = logger.With(zap.String("ticker", state.Ticker))
+	logger.Info("chart callback received")
+
+	imgBytes, caption, err := b.renderChart(ctx, logger, p, chatID, state)
+	if err != nil {
+		logger.Error("can not render chart", zap.Error(err))
+		callbackText = p.Sprintf(i18n.KeyChartError)
+		return
+	}
+
+	err = b.messenger.EditPhoto(ctx, chatID, callback.MessageID, imgBytes, caption, state.keyboard(p))
+	if err != nil {
+		logger.Warn("can not edit chart", zap.Error(err))
+	}
+
+	logger.Info("chart callback done", zap.Duration("elapsed", time.Since(now)))
 }
 
 // HelpHandler handles help command
-func (b *VkRocketBot) HelpHandler(chatID int64) {
-	helpMessage := "Available commands:\n"
-	for command, ticker := range b.tickerCommands {
-		helpMessage += fmt.Sprintf("/%s for %s\n", command, ticker)
+func (b *VkRocketBot) HelpHandler(chatID int64, p *i18n.Printer) {
+	b.tickerCommandsMu.RLock()
+	featured := make([]*instruments.Featured, 0, len(b.tickerCommands))
+	for _, f := range b.tickerCommands {
+		featured = append(featured, f)
+	}
+	b.tickerCommandsMu.RUnlock()
+
+	var helpMessage strings.Builder
+	helpMessage.WriteString(p.Plural(i18n.KeyHelpHeader, len(featured)))
+	for _, group := range instruments.GroupFeatured(featured) {
+		fmt.Fprintf(&helpMessage, "\n%s:\n", group.Name)
+		for _, f := range group.Instruments {
+			helpMessage.WriteString(p.Sprintf(i18n.KeyHelpCommand, f.Command, f.DisplayName(), f.Ticker))
+		}
+	}
+	helpMessage.WriteString(p.Sprintf(i18n.KeyHelpFooter))
+
+	if err := b.messenger.SendText(context.Background(), chatID, helpMessage.String()); err != nil {
+		b.logger.Warn("can not send help", zap.Int64("chat_id", chatID), zap.Error(err))
+	}
+}
+
+// LangHandler shows or switches chat language
+func (b *VkRocketBot) LangHandler(chatID int64, p *i18n.Printer, arg string) {
+	langs := i18n.Langs()
+	available := make([]string, 0, len(langs))
+	for _, lang := range langs {
+		available = append(available, string(lang))
+	}
+
+	var text string
+	if arg == "" {
+		text = p.Sprintf(i18n.KeyLangCurrent, p.LangName(), strings.Join(available, ", "))
+	} else if lang, ok := i18n.ParseLang(arg); ok {
+		if err := b.langs.Set(chatID, lang); err != nil {
+			b.logger.Error("can not save chat language", zap.Int64("chat_id", chatID), zap.Error(err))
+		}
+		p = i18n.NewPrinter(lang)
+		text = p.Sprintf(i18n.KeyLangChanged, p.LangName())
+	} else {
+		text = p.Sprintf(i18n.KeyLangUnknown, arg, strings.Join(available, ", "))
+	}
+
+	if err := b.messenger.SendText(context.Background(), chatID, text); err != nil {
+		b.logger.Warn("can not send lang reply", zap.Int64("chat_id", chatID), zap.Error(err))
+	}
+}
+
+// ThemeHandler shows or switches chat chart theme
+func (b *VkRocketBot) ThemeHandler(chatID int64, p *i18n.Printer, arg string) {
+	available := strings.Join(b.themes.Names(), ", ")
+
+	var text string
+	if arg == "" {
+		text = p.Sprintf(i18n.KeyThemeCurrent, b.themes.Name(chatID), available)
+	} else if err := b.themes.Set(chatID, arg); errors.Is(err, chartgen.ErrBadTheme) {
+		text = p.Sprintf(i18n.KeyThemeUnknown, arg, available)
+	} else {
+		if err != nil {
+			b.logger.Error("can not save chat theme", zap.Int64("chat_id", chatID), zap.Error(err))
+		}
+		text = p.Sprintf(i18n.KeyThemeChanged, arg)
 	}
-	helpMessage += "/start or /help prints this message\n"
 
-	resp := tgbotapi.NewMessage(chatID, helpMessage)
-	b.botAPI.Send(resp)
+	if err := b.messenger.SendText(context.Background(), chatID, text); err != nil {
+		b.logger.Warn("can not send theme reply", zap.Int64("chat_id", chatID), zap.Error(err))
+	}
 }
 
-// Run start bot and blocks until end
-func (b *VkRocketBot) Run() {
-	b.logger.Info("bot startup")
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+// Run start bot and blocks until ctx is done,
+// then waits for already received updates to be handled
+func (b *VkRocketBot) Run(ctx context.Context) error {
+	b.logger.Info("bot startup", zap.String("platform", b.platform))
+	defer func() {
+		b.dispatcher.Stop()
+		b.logger.Info("bot stopped")
+	}()
+
+	return b.messenger.Run(ctx, b.dispatcher.Dispatch)
+}
 
-	for update := range b.botAPI.GetUpdatesChan(u) {
-		if update.Message == nil {
-			continue
+// handleLimitedUpdate tells rate limited user to slow down, callbacks are answered to stop button spinner
+func (b *VkRocketBot) handleLimitedUpdate(update messenger.Update) {
+	ctx := context.Background()
+	switch {
+	case update.Callback != nil:
+		callback := update.Callback
+		p := b.langs.Printer(callback.ChatID, callback.Lang)
+		if err := b.messenger.AnswerCallback(ctx, callback, p.Sprintf(i18n.KeyTooManyRequests)); err != nil {
+			b.logger.Warn("can not answer limited callback", zap.Int64("chat_id", callback.ChatID), zap.Error(err))
 		}
-		botCommand := update.Message.Command()
-		chatID := update.Message.Chat.ID
-		if botCommand == "start" || botCommand == "help" {
-			b.HelpHandler(chatID)
-			continue
+	case update.Message != nil:
+		chatID := update.Message.ChatID
+		p := b.langs.Printer(chatID, update.Message.Lang)
+		if err := b.messenger.SendText(ctx, chatID, p.Sprintf(i18n.KeyTooManyRequests)); err != nil {
+			b.logger.Warn("can not send too many requests reply", zap.Int64("chat_id", chatID), zap.Error(err))
 		}
+	}
+}
 
-		if ticker, ok := b.tickerCommands[botCommand]; ok {
-			b.generalStockHandler(chatID, ticker)
+func (b *VkRocketBot) handleUpdate(update messenger.Update) {
+	if update.Callback != nil {
+		if isSearchCallback(update.Callback.Data) {
+			b.SearchCallbackHandler(update.Callback)
+			return
+		}
+		b.ChartCallbackHandler(update.Callback)
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+	botCommand := update.Message.Command
+	chatID := update.Message.ChatID
+	p := b.langs.Printer(chatID, update.Message.Lang)
+	switch botCommand {
+	case "start", "help":
+		b.HelpHandler(chatID, p)
+		return
+	case "lang":
+		b.LangHandler(chatID, p, update.Message.Args)
+		return
+	case "theme":
+		b.ThemeHandler(chatID, p, update.Message.Args)
+		return
+	case "quote":
+		b.QuoteHandler(chatID, p, update.Message.Args)
+		return
+	case "book":
+		b.BookHandler(chatID, p, update.Message.Args)
+		return
+	case "search":
+		b.SearchHandler(chatID, p, update.Message.Args)
+		return
+	case "buy":
+		b.TradeHandler(chatID, p, portfolio.SideBuy, update.Message.Args)
+		return
+	case "sell":
+		b.TradeHandler(chatID, p, portfolio.SideSell, update.Message.Args)
+		return
+	case "portfolio":
+		b.PortfolioHandler(chatID, p)
+		return
+	}
+
+	if ticker, ok := b.lookupTicker(botCommand); ok {
+		// "/sber usd" draws chart converted to currency
+		currency, err := stockapi.ParseCurrency(update.Message.Args)
+		if err != nil {
+			currency = ""
+		}
+		b.generalStockHandler(chatID, p, ticker, currency)
+		return
+	}
+	if update.Message.Interaction {
+		text := p.Sprintf(i18n.KeyUnknownCommand, botCommand)
+		if err := b.messenger.SendText(context.Background(), chatID, text); err != nil {
+			b.logger.Warn("can not send unknown command reply", zap.Int64("chat_id", chatID), zap.Error(err))
 		}
 	}
 }
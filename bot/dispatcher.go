@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/ratelimit"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDispatcherWorkers   = 8
+	defaultDispatcherQueueSize = 64
+	defaultUserRate            = 0.5
+	defaultUserBurst           = 5
+
+	userLimitersCleanupInterval = 10 * time.Minute
+)
+
+// DispatcherConfig config for updates dispatcher
+type DispatcherConfig struct {
+	// Workers count of updates handling goroutines
+	Workers int `json:"Workers"`
+	// QueueSize count of pending updates per worker
+	QueueSize int `json:"QueueSize"`
+	// UserRate allowed updates per second for one user
+	UserRate float64 `json:"UserRate"`
+	// UserBurst allowed updates burst for one user
+	UserBurst int `json:"UserBurst"`
+}
+
+func (c DispatcherConfig) withDefaults() DispatcherConfig {
+	if c.Workers <= 0 {
+		c.Workers = defaultDispatcherWorkers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultDispatcherQueueSize
+	}
+	if c.UserRate == 0 {
+		c.UserRate = defaultUserRate
+	}
+	if c.UserBurst <= 0 {
+		c.UserBurst = defaultUserBurst
+	}
+	return c
+}
+
+// userLimiter rate limiter of one user
+type userLimiter struct {
+	bucket   *ratelimit.Bucket
+	lastSeen time.Time
+	// notified is set when user was told about rate limit since last allowed update
+	notified bool
+}
+
+// queuedUpdate update waiting for worker, limited updates are only answered
+type queuedUpdate struct {
+	update  messenger.Update
+	limited bool
+}
+
+// dispatcher handles updates on worker pool, updates of one chat
+// always go to the same worker, so they are handled in order
+type dispatcher struct {
+	queues        []chan queuedUpdate
+	handle        func(update messenger.Update)
+	handleLimited func(update messenger.Update)
+	wg            sync.WaitGroup
+
+	userLimitersMu sync.Mutex
+	userLimiters   map[int64]*userLimiter
+	userRate       float64
+	userBurst      int
+	lastCleanup    time.Time
+
+	logger *zap.Logger
+}
+
+// newDispatcher creates dispatcher, updates of rate limited users are passed to handleLimited,
+// which must answer them without doing the work
+func newDispatcher(cfg DispatcherConfig, handle, handleLimited func(update messenger.Update), logger *zap.Logger) *dispatcher {
+	cfg = cfg.withDefaults()
+	d := &dispatcher{
+		queues:        make([]chan queuedUpdate, cfg.Workers),
+		handle:        handle,
+		handleLimited: handleLimited,
+		userLimiters:  make(map[int64]*userLimiter),
+		userRate:      cfg.UserRate,
+		userBurst:     cfg.UserBurst,
+		lastCleanup:   time.Now(),
+		logger:        logger,
+	}
+
+	for i := range d.queues {
+		queue := make(chan queuedUpdate, cfg.QueueSize)
+		d.queues[i] = queue
+		d.wg.Add(1)
+		go d.work(queue)
+	}
+	return d
+}
+
+func (d *dispatcher) work(queue chan queuedUpdate) {
+	defer d.wg.Done()
+	for queued := range queue {
+		d.handleSafe(queued)
+	}
+}
+
+func (d *dispatcher) handleSafe(queued queuedUpdate) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Error("update handler panic", zap.Int64("chat_id", queued.update.ChatID()), zap.Any("panic", r))
+		}
+	}()
+	if queued.limited {
+		d.handleLimited(queued.update)
+		return
+	}
+	d.handle(queued.update)
+}
+
+// Dispatch queues update, blocks while worker queue is full,
+// returns error if ctx is done before update is queued.
+// Updates of rate limited users are queued to be answered: callbacks and slash commands
+// always expect answer, messages are answered once until user is allowed again
+func (d *dispatcher) Dispatch(ctx context.Context, update messenger.Update) error {
+	queued := queuedUpdate{update: update}
+	if userID := update.UserID(); userID != 0 {
+		allowed, notify := d.allowUser(userID)
+		if !allowed {
+			d.logger.Info("user rate limited", zap.Int64("user_id", userID))
+			if !notify && !expectsAnswer(update) {
+				return nil
+			}
+			queued.limited = true
+		}
+	}
+
+	chatID := update.ChatID()
+	queue := d.queues[uint64(chatID)%uint64(len(d.queues))]
+
+	select {
+	case queue <- queued:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop waits for queued updates to be handled, Dispatch must not be called after
+func (d *dispatcher) Stop() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.wg.Wait()
+}
+
+// expectsAnswer reports if platform waits for answer to update
+func expectsAnswer(update messenger.Update) bool {
+	return update.Callback != nil || (update.Message != nil && update.Message.Interaction)
+}
+
+// allowUser takes token of user, notify is true for the first limited update since last allowed one
+func (d *dispatcher) allowUser(userID int64) (allowed, notify bool) {
+	d.userLimitersMu.Lock()
+	now := time.Now()
+	if now.Sub(d.lastCleanup) > userLimitersCleanupInterval {
+		// limiters of users idle for cleanup interval are full
+		// and equal to new ones, so they can be dropped
+		for id, limiter := range d.userLimiters {
+			if now.Sub(limiter.lastSeen) > userLimitersCleanupInterval {
+				delete(d.userLimiters, id)
+			}
+		}
+		d.lastCleanup = now
+	}
+	limiter, ok := d.userLimiters[userID]
+	if !ok {
+		limiter = &userLimiter{bucket: ratelimit.NewBucket(d.userRate, d.userBurst)}
+		d.userLimiters[userID] = limiter
+	}
+	limiter.lastSeen = now
+	defer d.userLimitersMu.Unlock()
+
+	if limiter.bucket.Allow() {
+		limiter.notified = false
+		return true, false
+	}
+	notify = !limiter.notified
+	limiter.notified = true
+	return false, notify
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/ratelimit"
+
+	"go.uber.org/zap"
+)
+
+// recorder records handled and limited updates
+type recorder struct {
+	mu      sync.Mutex
+	handled []messenger.Update
+	limited []messenger.Update
+}
+
+func (r *recorder) handle(update messenger.Update) {
+	r.mu.Lock()
+	r.handled = append(r.handled, update)
+	r.mu.Unlock()
+}
+
+func (r *recorder) handleLimited(update messenger.Update) {
+	r.mu.Lock()
+	r.limited = append(r.limited, update)
+	r.mu.Unlock()
+}
+
+func TestDispatcherUserLimit(t *testing.T) {
+	r := &recorder{}
+	// user gets no new tokens during test
+	d := newDispatcher(DispatcherConfig{Workers: 2, UserRate: 1e-6, UserBurst: 2}, r.handle, r.handleLimited, zap.NewNop())
+
+	message := messenger.Update{Message: &messenger.Message{ChatID: 1, UserID: 10, Text: "/sber"}}
+	interaction := messenger.Update{Message: &messenger.Message{ChatID: 1, UserID: 10, Text: "/sber", Interaction: true}}
+	callback := messenger.Update{Callback: &messenger.Callback{ChatID: 1, UserID: 10, ID: "cb"}}
+	other := messenger.Update{Message: &messenger.Message{ChatID: 2, UserID: 20, Text: "/sber"}}
+	for _, update := range []messenger.Update{
+		message, message, // burst
+		message,     // limited, told once
+		message,     // limited, dropped
+		callback,    // limited, always answered
+		interaction, // limited, always answered
+		message,     // limited, dropped
+		other,       // other user is not limited
+	} {
+		if err := d.Dispatch(context.Background(), update); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Stop()
+
+	if len(r.handled) != 3 {
+		t.Errorf("handled %d updates, want 3", len(r.handled))
+	}
+	if len(r.limited) != 3 {
+		t.Fatalf("answered %d limited updates, want 3", len(r.limited))
+	}
+	if r.limited[0].Message == nil || r.limited[1].Callback == nil || !r.limited[2].Message.Interaction {
+		t.Errorf("limited updates %+v", r.limited)
+	}
+}
+
+func TestDispatcherNotifyAgain(t *testing.T) {
+	r := &recorder{}
+	d := newDispatcher(DispatcherConfig{Workers: 1, UserRate: 1e-6, UserBurst: 1}, r.handle, r.handleLimited, zap.NewNop())
+
+	message := messenger.Update{Message: &messenger.Message{ChatID: 1, UserID: 10}}
+	d.Dispatch(context.Background(), message)
+	d.Dispatch(context.Background(), message)
+	// user is allowed again, so the next limit is told again
+	d.userLimiters[10].bucket = ratelimit.NewBucket(1e-6, 1)
+	d.Dispatch(context.Background(), message)
+	d.Dispatch(context.Background(), message)
+	d.Stop()
+
+	if len(r.handled) != 2 || len(r.limited) != 2 {
+		t.Errorf("handled %d, limited %d, want 2 and 2", len(r.handled), len(r.limited))
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/chartgen"
+	"github.com/Apakhov/stocks-bot/i18n"
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/stockapi"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	chartCallbackPrefix    = "ch"
+	callbackDataSeparator  = "|"
+	chartCallbackDataParts = 7
+	selectedButtonMark     = "• "
+	enabledIndicatorMark   = "✓ "
+)
+
+var (
+	// ErrBadCallbackData error for callback data which can not be decoded
+	ErrBadCallbackData = errors.New("can not decode callback data")
+)
+
+// chartPeriod period of chart and candle intervals allowed for it
+type chartPeriod struct {
+	Name      string
+	Duration  time.Duration
+	Intervals []string
+	// Session period shows the whole last trading session instead of Duration
+	Session bool
+}
+
+// window returns chart period ending now, intraday periods end at last trading time,
+// so charts drawn on weekends or at night show the last session
+func (p *chartPeriod) window(cal *calendar.Calendar, now time.Time) (time.Time, time.Time) {
+	if p.Duration > 24*time.Hour {
+		return now.Add(-p.Duration), now
+	}
+	to := cal.LastTradingTime(now)
+	if p.Session {
+		return cal.LastSession(now).Open, to
+	}
+	return to.Add(-p.Duration), to
+}
+
+// DefaultInterval returns interval used after switching to period
+func (p *chartPeriod) DefaultInterval() string {
+	return p.Intervals[0]
+}
+
+func (p *chartPeriod) hasInterval(interval string) bool {
+	for _, i := range p.Intervals {
+		if i == interval {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	chartPeriods = []*chartPeriod{
+		{Name: "1h", Duration: time.Hour, Intervals: []string{"1min", "5min", "15min"}},
+		{Name: "1d", Duration: 24 * time.Hour, Intervals: []string{"5min", "15min", "1hour"}, Session: true},
+		{Name: "1w", Duration: 7 * 24 * time.Hour, Intervals: []string{"1hour", "4hour", "1day"}},
+		{Name: "1m", Duration: 30 * 24 * time.Hour, Intervals: []string{"1day", "1week"}},
+	}
+	defaultChartPeriod = chartPeriods[1]
+
+	chartTypeButtons = []struct {
+		Type  chartgen.ChartType
+		Label i18n.Key
+	}{
+		{Type: chartgen.ChartTypeCandles, Label: i18n.KeyButtonCandles},
+		{Type: chartgen.ChartTypeLine, Label: i18n.KeyButtonLine},
+	}
+
+	indicatorButtons = []struct {
+		Indicator chartgen.Indicator
+		Label     i18n.Key
+	}{
+		{Indicator: chartgen.IndicatorSMA, Label: i18n.KeyButtonSMA},
+		{Indicator: chartgen.IndicatorEMA, Label: i18n.KeyButtonEMA},
+		{Indicator: chartgen.IndicatorVolume, Label: i18n.KeyButtonVolume},
+	}
+)
+
+func findChartPeriod(name string) (*chartPeriod, bool) {
+	for _, period := range chartPeriods {
+		if period.Name == name {
+			return period, true
+		}
+	}
+	return nil, false
+}
+
+// chartState describes chart shown in message,
+// it is stored in callback data of message buttons
+type chartState struct {
+	Ticker   string
+	Period   *chartPeriod
+	Interval string
+	Options  *chartgen.ChartOptions
+	// Currency chart currency, empty means instrument currency
+	Currency string
+}
+
+func newChartState(ticker string) *chartState {
+	return &chartState{
+		Ticker:   ticker,
+		Period:   defaultChartPeriod,
+		Interval: defaultChartPeriod.DefaultInterval(),
+		Options:  chartgen.DefaultChartOptions(),
+	}
+}
+
+// encode returns callback data, telegram allows up to 64 bytes
+func (s *chartState) encode() string {
+	return strings.Join([]string{
+		chartCallbackPrefix,
+		s.Ticker,
+		s.Period.Name,
+		s.Interval,
+		string(s.Options.Type),
+		chartgen.FormatIndicators(s.Options.Indicators),
+		s.Currency,
+	}, callbackDataSeparator)
+}
+
+func decodeChartState(data string) (*chartState, error) {
+	parts := strings.Split(data, callbackDataSeparator)
+	// buttons sent before currency option have no currency part
+	if len(parts) == chartCallbackDataParts-1 {
+		parts = append(parts, "")
+	}
+	if len(parts) != chartCallbackDataParts || parts[0] != chartCallbackPrefix {
+		return nil, ErrBadCallbackData
+	}
+
+	period, ok := findChartPeriod(parts[2])
+	if !ok || !period.hasInterval(parts[3]) {
+		return nil, ErrBadCallbackData
+	}
+
+	options, err := chartgen.ParseChartOptions(parts[4], parts[5])
+	if err != nil {
+		return nil, errors.Wrap(ErrBadCallbackData, err.Error())
+	}
+
+	currency := parts[6]
+	if currency != "" {
+		if currency, err = stockapi.ParseCurrency(currency); err != nil {
+			return nil, errors.Wrap(ErrBadCallbackData, err.Error())
+		}
+	}
+
+	return &chartState{
+		Ticker:   parts[1],
+		Period:   period,
+		Interval: parts[3],
+		Options:  options,
+		Currency: currency,
+	}, nil
+}
+
+func (s *chartState) copy() *chartState {
+	options := *s.Options
+	options.Indicators = append([]chartgen.Indicator(nil), s.Options.Indicators...)
+	state := *s
+	state.Options = &options
+	return &state
+}
+
+func (s *chartState) withPeriod(period *chartPeriod) *chartState {
+	state := s.copy()
+	state.Period = period
+	state.Interval = period.DefaultInterval()
+	return state
+}
+
+func (s *chartState) withInterval(interval string) *chartState {
+	state := s.copy()
+	state.Interval = interval
+	return state
+}
+
+func (s *chartState) withChartType(chartType chartgen.ChartType) *chartState {
+	state := s.copy()
+	state.Options.Type = chartType
+	return state
+}
+
+func (s *chartState) withCurrency(currency string) *chartState {
+	state := s.copy()
+	state.Currency = currency
+	return state
+}
+
+func (s *chartState) withToggledIndicator(indicator chartgen.Indicator) *chartState {
+	state := s.copy()
+	indicators := make([]chartgen.Indicator, 0, len(state.Options.Indicators)+1)
+	for _, i := range state.Options.Indicators {
+		if i != indicator {
+			indicators = append(indicators, i)
+		}
+	}
+	if !s.Options.HasIndicator(indicator) {
+		indicators = append(indicators, indicator)
+	}
+	state.Options.Indicators = indicators
+	return state
+}
+
+// keyboard returns buttons switching chart to neighbour states
+func (s *chartState) keyboard(p *i18n.Printer) messenger.Keyboard {
+	periodRow := make([]messenger.Button, 0, len(chartPeriods))
+	for _, period := range chartPeriods {
+		periodRow = append(periodRow, messenger.Button{
+			Text: markSelected(period.Name, period == s.Period),
+			Data: s.withPeriod(period).encode(),
+		})
+	}
+
+	intervalRow := make([]messenger.Button, 0, len(s.Period.Intervals))
+	for _, interval := range s.Period.Intervals {
+		intervalRow = append(intervalRow, messenger.Button{
+			Text: markSelected(interval, interval == s.Interval),
+			Data: s.withInterval(interval).encode(),
+		})
+	}
+
+	chartTypeRow := make([]messenger.Button, 0, len(chartTypeButtons))
+	for _, button := range chartTypeButtons {
+		chartTypeRow = append(chartTypeRow, messenger.Button{
+			Text: markSelected(p.Sprintf(button.Label), button.Type == s.Options.Type),
+			Data: s.withChartType(button.Type).encode(),
+		})
+	}
+
+	indicatorRow := make([]messenger.Button, 0, len(indicatorButtons))
+	for _, button := range indicatorButtons {
+		label := p.Sprintf(button.Label)
+		if s.Options.HasIndicator(button.Indicator) {
+			label = enabledIndicatorMark + label
+		}
+		indicatorRow = append(indicatorRow, messenger.Button{
+			Text: label,
+			Data: s.withToggledIndicator(button.Indicator).encode(),
+		})
+	}
+
+	currencies := append([]string{""}, stockapi.Currencies()...)
+	currencyRow := make([]messenger.Button, 0, len(currencies))
+	for _, currency := range currencies {
+		label := currency
+		if currency == "" {
+			label = p.Sprintf(i18n.KeyButtonNativeCurrency)
+		}
+		currencyRow = append(currencyRow, messenger.Button{
+			Text: markSelected(label, currency == s.Currency),
+			Data: s.withCurrency(currency).encode(),
+		})
+	}
+
+	return messenger.Keyboard{periodRow, intervalRow, chartTypeRow, indicatorRow, currencyRow}
+}
+
+func markSelected(label string, selected bool) string {
+	if selected {
+		return selectedButtonMark + label
+	}
+	return label
+}
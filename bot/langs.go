@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/Apakhov/stocks-bot/i18n"
+
+	"github.com/pkg/errors"
+)
+
+// chatLangs languages chosen by chats with /lang,
+// persisted to file if path is set
+type chatLangs struct {
+	mu          sync.RWMutex
+	langs       map[int64]i18n.Lang
+	path        string
+	defaultLang i18n.Lang
+}
+
+func newChatLangs(path string, defaultLang i18n.Lang) (*chatLangs, error) {
+	c := &chatLangs{
+		langs:       make(map[int64]i18n.Lang),
+		path:        path,
+		defaultLang: defaultLang,
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can not read chat languages")
+	}
+	if err := json.Unmarshal(data, &c.langs); err != nil {
+		return nil, errors.Wrapf(err, "can not decode chat languages %s", path)
+	}
+	return c, nil
+}
+
+// Printer returns printer of chat language, chats without chosen
+// language use user language if it is supported
+func (c *chatLangs) Printer(chatID int64, userLang string) *i18n.Printer {
+	c.mu.RLock()
+	lang, ok := c.langs[chatID]
+	c.mu.RUnlock()
+	if ok {
+		return i18n.NewPrinter(lang)
+	}
+
+	if lang, ok := i18n.ParseLang(userLang); ok {
+		return i18n.NewPrinter(lang)
+	}
+	return i18n.NewPrinter(c.defaultLang)
+}
+
+// Set saves chat language
+func (c *chatLangs) Set(chatID int64, lang i18n.Lang) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.langs[chatID] = lang
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c.langs)
+	if err != nil {
+		return errors.Wrap(err, "can not encode chat languages")
+	}
+	return errors.Wrap(writeFileAtomic(c.path, data), "can not save chat languages")
+}
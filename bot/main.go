@@ -1,118 +1,237 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/chartgen"
 	"github.com/Apakhov/stocks-bot/config"
+	"github.com/Apakhov/stocks-bot/i18n"
+	"github.com/Apakhov/stocks-bot/instruments"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/messenger/discord"
+	"github.com/Apakhov/stocks-bot/messenger/slack"
+	"github.com/Apakhov/stocks-bot/messenger/telegram"
+	"github.com/Apakhov/stocks-bot/messenger/vk"
+	"github.com/Apakhov/stocks-bot/stockapi"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	envPrefix = "BOT"
+	// themesEnvPrefix and holidaysEnvPrefix prefix environment overrides of ThemesFile and HolidaysFile
+	themesEnvPrefix   = "THEMES"
+	holidaysEnvPrefix = "HOLIDAYS"
 )
 
 type Config struct {
-	StocksHost    string `json:"StocksHost"`
-	StockTCPHost  string `json:"StockTCPHost"`
-	TelegramToken string `json:"TelegramToken"`
-	TinkoffToken  string `json:"TinkoffToken"`
+	StocksHost          string                   `json:"StocksHost"`
+	StockTCPHost        string                   `json:"StockTCPHost" validate:"required"`
+	TinkoffToken        string                   `json:"TinkoffToken" validate:"required"`
+	StockAPI            stockapi.PolicyConfig    `json:"StockAPI"`
+	Providers           stockapi.ProvidersConfig `json:"Providers"`
+	FeaturedFile        string                   `json:"FeaturedFile" validate:"required"`
+	Log                 logging.Config           `json:"Log"`
+	Dispatcher          DispatcherConfig         `json:"Dispatcher"`
+	DefaultLang         string                   `json:"DefaultLang"`
+	LangFile            string                   `json:"LangFile"`
+	ThemesFile          string                   `json:"ThemesFile"`
+	HolidaysFile        string                   `json:"HolidaysFile"`
+	ChatThemesFile      string                   `json:"ChatThemesFile"`
+	PortfolioFile       string                   `json:"PortfolioFile"`
+	MetricsHost         string                   `json:"MetricsHost"`
+	Platform            string                   `json:"Platform"`
+	TelegramToken       string                   `json:"TelegramToken"`
+	TelegramAPIEndpoint string                   `json:"TelegramAPIEndpoint"`
+	Sender              telegram.SenderConfig    `json:"Sender"`
+	UpdatesMode         string                   `json:"UpdatesMode"`
+	Webhook             telegram.WebhookConfig   `json:"Webhook"`
+	VK                  vk.Config                `json:"VK"`
+	Slack               slack.Config             `json:"Slack"`
+	Discord             discord.Config           `json:"Discord"`
+}
+
+// Validate checks language and platform settings
+func (c *Config) Validate() error {
+	if c.DefaultLang != "" {
+		if _, ok := i18n.ParseLang(c.DefaultLang); !ok {
+			return errors.Errorf("unsupported DefaultLang %q", c.DefaultLang)
+		}
+	}
+
+	switch c.Platform {
+	case "", PlatformTelegram:
+		if c.TelegramToken == "" {
+			return errors.New("TelegramToken is required")
+		}
+	case PlatformVK:
+		if c.VK.Token == "" || c.VK.GroupID == 0 {
+			return errors.New("VK.Token and VK.GroupID are required on vk platform")
+		}
+		return nil
+	case PlatformSlack:
+		if c.Slack.SigningSecret == "" || c.Slack.BotToken == "" || c.Slack.Listen == "" {
+			return errors.New("Slack.SigningSecret, Slack.BotToken and Slack.Listen are required on slack platform")
+		}
+		return nil
+	case PlatformDiscord:
+		if c.Discord.PublicKey == "" || c.Discord.ApplicationID == "" || c.Discord.Listen == "" {
+			return errors.New("Discord.PublicKey, Discord.ApplicationID and Discord.Listen are required on discord platform")
+		}
+		if c.Discord.RegisterCommand && c.Discord.BotToken == "" {
+			return errors.New("Discord.BotToken is required to register command")
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown Platform %q", c.Platform)
+	}
+
+	switch c.UpdatesMode {
+	case "", telegram.UpdatesModePolling:
+		return nil
+	case telegram.UpdatesModeWebhook:
+		if c.Webhook.Listen == "" || c.Webhook.SecretToken == "" {
+			return errors.New("Webhook.Listen and Webhook.SecretToken are required in webhook mode")
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown UpdatesMode %q", c.UpdatesMode)
+	}
 }
 
 func main() {
+	loader := config.NewLoader(os.Args, envPrefix)
 	var conf Config
-	config.GetConfig(os.Args, &conf)
+	if err := loader.Load(&conf); err != nil {
+		fmt.Fprintln(os.Stderr, "can not load config:", err)
+		os.Exit(1)
+	}
+	featured, err := instruments.LoadFeatured(conf.FeaturedFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	rand.Seed(time.Now().UnixNano())
 
+	themes, err := loadThemes(conf.ThemesFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := loadHolidays(conf.HolidaysFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	defaultLang := i18n.DefaultLang
+	if conf.DefaultLang != "" {
+		defaultLang, _ = i18n.ParseLang(conf.DefaultLang)
+	}
+
 	cfg := &VkRocketBotConfig{
-		StocksHost:    conf.StocksHost,
-		StocksTCPHost: conf.StockTCPHost,
-		TelegramToken: conf.TelegramToken,
-		TinkoffToken:  conf.TinkoffToken,
-		CommandStocks: []*StockCommand{
-			{
-				Command: "vkco",
-				Ticker:  "VKCO",
-			},
-			{
-				Command: "sber",
-				Ticker:  "SBER",
-			},
-			{
-				Command: "sberp",
-				Ticker:  "SBERP",
-			},
-			{
-				Command: "yndx",
-				Ticker:  "YNDX",
-			},
-			{
-				Command: "gazp",
-				Ticker:  "GAZP",
-			},
-			{
-				Command: "gazp",
-				Ticker:  "GAZP",
-			},
-			{
-				Command: "vtbr",
-				Ticker:  "VTBR",
-			},
-			{
-				Command: "fixp",
-				Ticker:  "FIXP",
-			},
-			{
-				Command: "moex",
-				Ticker:  "MOEX",
-			},
-			{
-				Command: "ozon",
-				Ticker:  "OZON",
-			},
-			{
-				Command: "rasp",
-				Ticker:  "RASP",
-			},
-			{
-				Command: "poly",
-				Ticker:  "POLY",
-			},
-			{
-				Command: "aapl",
-				Ticker:  "AAPL",
-			},
-			{
-				Command: "tal",
-				Ticker:  "TAL",
-			},
-			{
-				Command: "msft",
-				Ticker:  "MSFT",
-			},
-			{
-				Command: "spce",
-				Ticker:  "SPCE",
-			},
-			{
-				Command: "pfe",
-				Ticker:  "PFE",
-			},
-			{
-				Command: "mrna",
-				Ticker:  "MRNA",
-			},
-			{
-				Command: "baba",
-				Ticker:  "BABA",
-			},
-			{
-				Command: "usd",
-				Ticker:  "USDRUB",
-			},
+		StocksHost:     conf.StocksHost,
+		StocksTCPHost:  conf.StockTCPHost,
+		TinkoffToken:   conf.TinkoffToken,
+		StockAPI:       conf.StockAPI,
+		Providers:      conf.Providers,
+		Log:            conf.Log,
+		Featured:       featured,
+		Dispatcher:     conf.Dispatcher,
+		DefaultLang:    defaultLang,
+		LangFile:       conf.LangFile,
+		Themes:         themes,
+		ChatThemesFile: conf.ChatThemesFile,
+		PortfolioFile:  conf.PortfolioFile,
+		Platform:       conf.Platform,
+		Telegram: telegram.Config{
+			Token:       conf.TelegramToken,
+			APIEndpoint: conf.TelegramAPIEndpoint,
+			UpdatesMode: conf.UpdatesMode,
+			Webhook:     conf.Webhook,
+			Sender:      conf.Sender,
 		},
+		VK:      conf.VK,
+		Slack:   conf.Slack,
+		Discord: conf.Discord,
 	}
 
 	bot, err := NewVkRocketBot(cfg)
 	if err != nil {
 		panic(err)
 	}
+	if conf.MetricsHost != "" {
+		go serveMetrics(conf.MetricsHost)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	loader.OnReload(ctx, func() {
+		var newConf Config
+		if err := loader.Load(&newConf); err != nil {
+			bot.logger.Error("can not reload config", zap.Error(err))
+			return
+		}
+		// only featured instruments are reloaded, values of other fields are not logged as they hold secrets
+		if changed := config.ChangedFields(conf, newConf, "FeaturedFile"); len(changed) > 0 {
+			bot.logger.Warn("config changes are ignored until restart", zap.Strings("fields", changed))
+		}
+
+		featured, err := instruments.LoadFeatured(newConf.FeaturedFile)
+		if err != nil {
+			bot.logger.Error("can not reload featured instruments", zap.Error(err))
+			return
+		}
+		bot.SetCommands(featured)
+		bot.logger.Info("config reloaded", zap.Int("commands", len(featured)))
+	})
 
-	bot.Run()
+	if err := bot.Run(ctx); err != nil {
+		bot.logger.Error("bot stopped with error", zap.Error(err))
+		os.Exit(1)
+	}
+}
+
+// serveMetrics serves prometheus metrics on /metrics
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		panic(err)
+	}
+}
+
+// loadThemes returns built-in themes and custom themes from file, empty path means only built-in ones
+func loadThemes(path string) (chartgen.Themes, error) {
+	var conf chartgen.ThemesConfig
+	if path != "" {
+		loader := &config.Loader{Path: path, EnvPrefix: themesEnvPrefix}
+		if err := loader.Load(&conf); err != nil {
+			return nil, errors.Wrap(err, "can not load themes")
+		}
+	}
+	return chartgen.NewThemes(conf)
+}
+
+// loadHolidays adds holidays from file to exchange calendars, empty path means only built-in ones
+func loadHolidays(path string) error {
+	if path == "" {
+		return nil
+	}
+	var conf calendar.HolidaysConfig
+	loader := &config.Loader{Path: path, EnvPrefix: holidaysEnvPrefix}
+	if err := loader.Load(&conf); err != nil {
+		return errors.Wrap(err, "can not load holidays")
+	}
+	return calendar.AddExchangeHolidays(conf)
 }
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/chartgen"
+	"github.com/Apakhov/stocks-bot/i18n"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
+	"github.com/Apakhov/stocks-bot/portfolio"
+	"github.com/Apakhov/stocks-bot/stockapi"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// portfolioCurrency currency portfolios are valued in
+	portfolioCurrency = stockapi.BaseCurrency
+	// captionLimit max length of photo caption, longer summary is sent as text
+	captionLimit      = 1024
+	maxQuantityDigits = 6
+)
+
+// parseTradeArgs parses "TICKER QUANTITY [@ PRICE]", zero price means last price
+func parseTradeArgs(args string) (string, float64, float64, error) {
+	fields := strings.Fields(strings.ReplaceAll(args, "@", " "))
+	if len(fields) < 2 || len(fields) > 3 {
+		return "", 0, 0, portfolio.ErrBadTrade
+	}
+
+	parse := func(s string) (float64, error) {
+		v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+		if err != nil || !(v > 0) {
+			return 0, portfolio.ErrBadTrade
+		}
+		return v, nil
+	}
+	quantity, err := parse(fields[1])
+	if err != nil {
+		return "", 0, 0, err
+	}
+	var price float64
+	if len(fields) == 3 {
+		if price, err = parse(fields[2]); err != nil {
+			return "", 0, 0, err
+		}
+	}
+	return fields[0], quantity, price, nil
+}
+
+// formatQuantity formats quantity without trailing zeros
+func formatQuantity(p *i18n.Printer, quantity float64) string {
+	formatted := strconv.FormatFloat(quantity, 'f', maxQuantityDigits, 64)
+	formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	precision := 0
+	if i := strings.IndexByte(formatted, '.'); i >= 0 {
+		precision = len(formatted) - i - 1
+	}
+	return p.Number(quantity, precision)
+}
+
+// TradeHandler adds paper trade to chat portfolio, args are "TICKER QUANTITY [@ PRICE]"
+func (b *VkRocketBot) TradeHandler(chatID int64, p *i18n.Printer, side portfolio.Side, args string) {
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	logger := logging.FromContext(ctx, b.logger).With(
+		zap.Int64("chat_id", chatID),
+		zap.String("side", string(side)),
+		zap.String("args", args),
+	)
+	sendText := func(text string) {
+		if err := b.messenger.SendText(ctx, chatID, text); err != nil {
+			logger.Warn("can not send trade reply", zap.Error(err))
+		}
+	}
+
+	arg, quantity, price, err := parseTradeArgs(args)
+	if err != nil {
+		sendText(p.Sprintf(i18n.KeyTradeUsage, side))
+		return
+	}
+
+	ticker := b.resolveTicker(arg)
+	now := time.Now()
+	daily, err := b.fetchDaily(ctx, ticker, now, recentDays)
+	switch {
+	case errors.Is(err, stockapi.ErrUnknownTicker):
+		sendText(p.Sprintf(i18n.KeyQuoteUnknownTicker, arg))
+		return
+	case err != nil:
+		logger.Error("can not fetch price", zap.Error(err))
+		sendText(p.Sprintf(i18n.KeyQuoteError))
+		return
+	}
+	if price == 0 {
+		if len(daily.TOHLCs) == 0 {
+			sendText(p.Sprintf(i18n.KeyQuoteNoData, ticker))
+			return
+		}
+		price = daily.TOHLCs[len(daily.TOHLCs)-1].Close
+	}
+
+	trade := &portfolio.Trade{
+		Time:     now,
+		Side:     side,
+		Ticker:   ticker,
+		Quantity: quantity,
+		Price:    price,
+		Currency: daily.Currency,
+	}
+	held := b.portfolios.Get(chatID).Position(ticker)
+	err = b.portfolios.AddTrade(chatID, trade)
+	switch {
+	case errors.Is(err, portfolio.ErrNotEnoughQuantity):
+		sendText(p.Sprintf(i18n.KeyTradeNotEnough, formatQuantity(p, quantity), ticker, formatQuantity(p, held.Quantity)))
+		return
+	case err != nil:
+		logger.Error("can not add trade", zap.Error(err))
+		sendText(p.Sprintf(i18n.KeyTradeError))
+		return
+	}
+	logger.Info("trade added", zap.String("ticker", ticker), zap.Float64("quantity", quantity), zap.Float64("price", price))
+
+	money := func(value float64) string {
+		return p.Money(value, moneyPrecision, trade.Currency)
+	}
+	key := i18n.KeyTradeBought
+	if side == portfolio.SideSell {
+		key = i18n.KeyTradeSold
+	}
+	lines := []string{p.Sprintf(key, formatQuantity(p, quantity), ticker, money(price))}
+	position := b.portfolios.Get(chatID).Position(ticker)
+	if position.Open() {
+		lines = append(lines, p.Sprintf(i18n.KeyTradePosition, formatQuantity(p, position.Quantity), ticker, money(position.AverageCost)))
+	} else {
+		lines = append(lines, p.Sprintf(i18n.KeyTradeClosed, ticker, money(position.Realized)))
+	}
+	sendText(strings.Join(lines, "\n"))
+}
+
+// fetchPrices returns daily candles of portfolio instruments and exchange rates since from
+func (b *VkRocketBot) fetchPrices(ctx context.Context, pf *portfolio.Portfolio, from, now time.Time) (*portfolio.Prices, error) {
+	prices := portfolio.NewPrices(portfolioCurrency)
+	fetch := func(ticker string) ([]ohlc.TOHLCV, error) {
+		data, err := b.stockAPIClient.GetCandlesticks(ctx, from, now, stockapi.CandlestickInterval1Day, ticker)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not fetch %s candles", ticker)
+		}
+		return data.TOHLCs, nil
+	}
+
+	for _, trade := range pf.Trades {
+		if _, ok := prices.Candles[trade.Ticker]; !ok {
+			candles, err := fetch(trade.Ticker)
+			if err != nil {
+				return nil, err
+			}
+			prices.Candles[trade.Ticker] = candles
+		}
+
+		if _, ok := prices.Rates[trade.Currency]; ok || trade.Currency == portfolioCurrency {
+			continue
+		}
+		fxTicker, ok := stockapi.FXTickers[trade.Currency]
+		if !ok {
+			return nil, errors.Errorf("no exchange rate for %s", trade.Currency)
+		}
+		candles, err := fetch(fxTicker)
+		if err != nil {
+			return nil, err
+		}
+		prices.Rates[trade.Currency] = candles
+	}
+	return prices, nil
+}
+
+// portfolioSummary returns positions valued at now and totals
+func portfolioSummary(p *i18n.Printer, pf *portfolio.Portfolio, prices *portfolio.Prices, now time.Time) string {
+	money := func(value float64, currency string) string {
+		return p.Money(value, moneyPrecision, currency)
+	}
+	percent := func(pnl, cost float64) string {
+		if cost == 0 {
+			return p.Percent(0, moneyPrecision)
+		}
+		return p.Percent(pnl/cost*100, moneyPrecision)
+	}
+
+	lines := []string{p.Sprintf(i18n.KeyPortfolioTitle, portfolioCurrency)}
+	var value, cost, realized float64
+	for _, position := range pf.Positions(time.Time{}) {
+		if rate, ok := prices.Rate(position.Currency, now.Unix()); ok {
+			realized += position.Realized * rate
+		}
+		if !position.Open() {
+			continue
+		}
+
+		valuation, ok := prices.Value(position, now.Unix())
+		if !ok {
+			lines = append(lines, p.Sprintf(i18n.KeyPortfolioNoPrice, position.Ticker, formatQuantity(p, position.Quantity)))
+			continue
+		}
+		value += valuation.Value
+		cost += valuation.Cost
+		lines = append(lines, p.Sprintf(i18n.KeyPortfolioPosition,
+			position.Ticker,
+			formatQuantity(p, position.Quantity),
+			money(valuation.Price, position.Currency),
+			money(position.AverageCost, position.Currency),
+			money(valuation.Value, portfolioCurrency),
+			p.SignedNumber(valuation.PnL(), moneyPrecision),
+			percent(valuation.PnL(), valuation.Cost),
+		))
+	}
+
+	lines = append(lines, p.Sprintf(i18n.KeyPortfolioTotal,
+		money(value, portfolioCurrency),
+		money(cost, portfolioCurrency),
+		p.SignedNumber(value-cost, moneyPrecision),
+		percent(value-cost, cost),
+	))
+	if realized != 0 {
+		lines = append(lines, p.Sprintf(i18n.KeyPortfolioRealized, money(realized, portfolioCurrency)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PortfolioHandler sends chat portfolio positions, P&L and equity curve chart
+func (b *VkRocketBot) PortfolioHandler(chatID int64, p *i18n.Printer) {
+	now := time.Now()
+
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	logger := logging.FromContext(ctx, b.logger).With(zap.Int64("chat_id", chatID))
+	sendText := func(text string) {
+		if err := b.messenger.SendText(ctx, chatID, text); err != nil {
+			logger.Warn("can not send portfolio", zap.Error(err))
+		}
+	}
+
+	pf := b.portfolios.Get(chatID)
+	start, ok := pf.Start()
+	if !ok {
+		sendText(p.Sprintf(i18n.KeyPortfolioEmpty))
+		return
+	}
+	start = start.In(exchangeLocation)
+	from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, exchangeLocation)
+
+	prices, err := b.fetchPrices(ctx, pf, from, now)
+	if err != nil {
+		logger.Error("can not fetch portfolio prices", zap.Error(err))
+		sendText(p.Sprintf(i18n.KeyPortfolioError))
+		return
+	}
+	summary := portfolioSummary(p, pf, prices, now)
+
+	imgBytes, err := b.chartGenerator.GenerateEquityChart(&chartgen.EquityChart{
+		Title:      p.Sprintf(i18n.KeyEquityTitle),
+		Currency:   portfolioCurrency,
+		ValueLabel: p.Sprintf(i18n.KeyEquityValue),
+		CostLabel:  p.Sprintf(i18n.KeyEquityCost),
+		Points:     portfolio.EquityCurve(pf, prices, from),
+		Theme:      b.themes.Theme(chatID),
+	})
+	switch {
+	case errors.Is(err, chartgen.ErrNotEnoughPoints):
+		sendText(summary)
+		return
+	case err != nil:
+		logger.Error("can not render equity chart", zap.Error(err))
+		sendText(summary)
+		return
+	}
+
+	if len([]rune(summary)) > captionLimit {
+		sendText(summary)
+		summary = ""
+	}
+	if err := b.messenger.SendPhoto(ctx, chatID, imgBytes, summary, nil); err != nil {
+		logger.Warn("can not send equity chart", zap.Error(err))
+	}
+	logger.Info("portfolio command done", zap.Duration("elapsed", time.Since(now)))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/Apakhov/stocks-bot/portfolio"
+
+	"github.com/pkg/errors"
+)
+
+// chatPortfolios paper portfolios of chats,
+// persisted to file if path is set
+type chatPortfolios struct {
+	mu         sync.Mutex
+	portfolios map[int64]*portfolio.Portfolio
+	path       string
+}
+
+func newChatPortfolios(path string) (*chatPortfolios, error) {
+	c := &chatPortfolios{
+		portfolios: make(map[int64]*portfolio.Portfolio),
+		path:       path,
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can not read portfolios")
+	}
+	if err := json.Unmarshal(data, &c.portfolios); err != nil {
+		return nil, errors.Wrapf(err, "can not decode portfolios %s", path)
+	}
+	return c, nil
+}
+
+// Get returns copy of chat portfolio, empty if chat has no trades
+func (c *chatPortfolios) Get(chatID int64) *portfolio.Portfolio {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.portfolios[chatID]
+	if !ok {
+		return &portfolio.Portfolio{}
+	}
+	return &portfolio.Portfolio{Trades: append([]*portfolio.Trade(nil), p.Trades...)}
+}
+
+// AddTrade adds trade to chat portfolio and saves portfolios,
+// trade is not added if it is invalid or portfolios can not be saved
+func (c *chatPortfolios) AddTrade(chatID int64, trade *portfolio.Trade) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.portfolios[chatID]
+	if !ok {
+		p = &portfolio.Portfolio{}
+	}
+	updated := &portfolio.Portfolio{Trades: append([]*portfolio.Trade(nil), p.Trades...)}
+	if err := updated.Add(trade); err != nil {
+		return err
+	}
+
+	c.portfolios[chatID] = updated
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c.portfolios)
+	if err != nil {
+		c.portfolios[chatID] = p
+		return errors.Wrap(err, "can not encode portfolios")
+	}
+	if err := writeFileAtomic(c.path, data); err != nil {
+		c.portfolios[chatID] = p
+		return errors.Wrap(err, "can not save portfolios")
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/chartgen"
+	"github.com/Apakhov/stocks-bot/i18n"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
+	"github.com/Apakhov/stocks-bot/stockapi"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	averageVolumeDays = 20
+	// quoteDays days of daily candles of /quote summary with 52 weeks range
+	quoteDays = 365
+	// recentDays days of daily candles of chart caption and trade price,
+	// enough for averageVolumeDays sessions around long holidays
+	recentDays = 45
+	// moneyPrecision decimals of money amounts which are not prices of one instrument
+	moneyPrecision = 2
+	openTimeLayout = "15:04 MST"
+)
+
+// exchangeLocation timezone of trading days, most instruments trade on MOEX
+var exchangeLocation = calendar.MOEX.Location
+
+// fetchDaily returns daily candles of last days
+func (b *VkRocketBot) fetchDaily(ctx context.Context, ticker string, now time.Time, days int) (*ohlc.CandlesticksData, error) {
+	return b.stockAPIClient.GetCandlesticks(ctx, now.AddDate(0, 0, -days), now, stockapi.CandlestickInterval1Day, ticker)
+}
+
+// GenerateDefaultCaption generates price summary of daily candles,
+// cal tells if market is closed now, yearRange adds 52 weeks range of candles covering quoteDays
+func (b *VkRocketBot) generateDefaultCaption(p *i18n.Printer, data *ohlc.CandlesticksData, cal *calendar.Calendar, now time.Time, yearRange bool) string {
+	summary, err := ohlc.Summarize(data.TOHLCs, averageVolumeDays)
+	if err != nil {
+		return p.Sprintf(i18n.KeyQuoteNoData, data.Ticker)
+	}
+	pricePrecision := chartgen.PricePrecision(data.MinPriceIncrement)
+	money := func(value float64) string {
+		return p.Money(value, pricePrecision, data.Currency)
+	}
+
+	lines := []string{p.Sprintf(i18n.KeyQuoteTitle, data.Name, data.Ticker, money(summary.Last))}
+
+	if !cal.IsOpen(now) {
+		lastDay := time.Unix(summary.LastTimestamp, 0).In(cal.Location)
+		nextOpen := cal.NextOpen(now)
+		lines = append(lines,
+			p.Sprintf(i18n.KeyQuoteMarketClosed, p.Date(lastDay)),
+			p.Sprintf(i18n.KeyQuoteMarketOpens, cal.Name, p.Date(nextOpen), nextOpen.Format(openTimeLayout)),
+		)
+	}
+
+	if summary.HasPrevClose {
+		lines = append(lines, p.Sprintf(i18n.KeyQuoteChange,
+			p.SignedNumber(summary.Change, pricePrecision),
+			p.Percent(summary.ChangePercent, 2),
+			money(summary.PrevClose),
+		))
+	} else {
+		lines = append(lines, p.Sprintf(i18n.KeyQuoteNoPrevClose))
+	}
+
+	lines = append(lines, p.Sprintf(i18n.KeyQuoteDayRange, money(summary.DayLow), money(summary.DayHigh)))
+	if summary.AvgVolume > 0 {
+		lines = append(lines, p.Plural(i18n.KeyQuoteVolumeAverage, summary.AvgVolumeDays,
+			p.Number(summary.Volume, 0),
+			p.Number(summary.Volume/summary.AvgVolume, 2),
+		))
+	} else {
+		lines = append(lines, p.Sprintf(i18n.KeyQuoteVolume, p.Number(summary.Volume, 0)))
+	}
+	if yearRange {
+		lines = append(lines, p.Sprintf(i18n.KeyQuoteYearRange, money(summary.RangeLow), money(summary.RangeHigh)))
+	}
+
+	if summary.HasPrevClose {
+		lines = append(lines, "", verdict(p, summary.ChangePercent))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// verdict grades daily change
+func verdict(p *i18n.Printer, percentDelta float64) string {
+	percentDeltaAbs := math.Abs(percentDelta)
+
+	grade := i18n.KeyGradeNeutral
+	if percentDeltaAbs > 1.5 {
+		grade = i18n.KeyGradeGood
+	}
+	if percentDeltaAbs > 5 {
+		grade = i18n.KeyGradeGreat
+	}
+	if percentDeltaAbs > 10 {
+		grade = i18n.KeyGradeOutstanding
+	}
+	if percentDeltaAbs > 15 {
+		grade = i18n.KeyGradeFantastic
+	}
+
+	negativeAdj := ""
+	if percentDelta < 0 {
+		negativeAdj = p.Sprintf(i18n.KeyCaptionNegative)
+	}
+	return p.Sprintf(i18n.KeyCaptionVerdict, negativeAdj, p.Sprintf(grade))
+}
+
+// resolveTicker returns ticker of featured command or arg as exchange ticker
+func (b *VkRocketBot) resolveTicker(arg string) string {
+	if ticker, ok := b.lookupTicker(strings.ToLower(arg)); ok {
+		return ticker
+	}
+	return strings.ToUpper(arg)
+}
+
+// QuoteHandler sends price summary, ticker is featured command or exchange ticker
+func (b *VkRocketBot) QuoteHandler(chatID int64, p *i18n.Printer, arg string) {
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	logger := logging.FromContext(ctx, b.logger).With(
+		zap.Int64("chat_id", chatID),
+		zap.String("arg", arg),
+	)
+
+	var text string
+	if arg == "" {
+		text = p.Sprintf(i18n.KeyQuoteUsage)
+	} else {
+		ticker := b.resolveTicker(arg)
+		now := time.Now()
+		daily, err := b.fetchDaily(ctx, ticker, now, quoteDays)
+		switch {
+		case errors.Is(err, stockapi.ErrUnknownTicker):
+			text = p.Sprintf(i18n.KeyQuoteUnknownTicker, arg)
+		case err != nil:
+			logger.Error("can not fetch quote", zap.Error(err))
+			text = p.Sprintf(i18n.KeyQuoteError)
+		default:
+			text = b.generateDefaultCaption(p, daily, stockapi.CalendarOf(b.stockAPIClient, ticker), now, true)
+		}
+	}
+
+	if err := b.messenger.SendText(ctx, chatID, text); err != nil {
+		logger.Warn("can not send quote", zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/i18n"
+	"github.com/Apakhov/stocks-bot/instruments"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/stockapi"
+
+	"go.uber.org/zap"
+)
+
+const (
+	searchCallbackPrefix = "op"
+	searchResultsLimit   = 8
+	searchButtonNameLen  = 32
+)
+
+var instrumentTypeKeys = map[instruments.Type]i18n.Key{
+	instruments.TypeStock:    i18n.KeyTypeStock,
+	instruments.TypeETF:      i18n.KeyTypeETF,
+	instruments.TypeBond:     i18n.KeyTypeBond,
+	instruments.TypeCurrency: i18n.KeyTypeCurrency,
+}
+
+// SearchHandler sends instruments matching query as buttons opening chart
+func (b *VkRocketBot) SearchHandler(chatID int64, p *i18n.Printer, query string) {
+	now := time.Now()
+
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	logger := logging.FromContext(ctx, b.logger).With(
+		zap.Int64("chat_id", chatID),
+		zap.String("query", query),
+	)
+	sendText := func(text string) {
+		if err := b.messenger.SendText(ctx, chatID, text); err != nil {
+			logger.Warn("can not send search reply", zap.Error(err))
+		}
+	}
+
+	instrumentClient, ok := b.stockAPIClient.(stockapi.InstrumentClient)
+	if query == "" || !ok {
+		sendText(p.Sprintf(i18n.KeySearchUsage))
+		return
+	}
+	registry := instrumentClient.Instruments()
+
+	found := registry.Search(query, searchResultsLimit)
+	if len(found) == 0 {
+		sendText(p.Sprintf(i18n.KeySearchNotFound, query))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(p.Sprintf(i18n.KeySearchFound, query))
+	keyboard := make(messenger.Keyboard, 0, len(found))
+	for _, instrument := range found {
+		key := registry.Key(instrument)
+		text.WriteString(p.Sprintf(i18n.KeySearchResult, key, instrument.Name, p.Sprintf(instrumentTypeKeys[instrument.Type])))
+		keyboard = append(keyboard, []messenger.Button{{
+			Text: instrument.Ticker + " — " + truncate(instrument.Name, searchButtonNameLen),
+			Data: searchCallbackPrefix + callbackDataSeparator + key,
+		}})
+	}
+
+	if err := b.messenger.SendButtons(ctx, chatID, text.String(), keyboard); err != nil {
+		logger.Warn("can not send search results", zap.Error(err))
+	}
+	logger.Info("search command done", zap.Int("found", len(found)), zap.Duration("elapsed", time.Since(now)))
+}
+
+// SearchCallbackHandler sends chart of instrument chosen in search results
+func (b *VkRocketBot) SearchCallbackHandler(callback *messenger.Callback) {
+	key := strings.TrimPrefix(callback.Data, searchCallbackPrefix+callbackDataSeparator)
+	if err := b.messenger.AnswerCallback(context.Background(), callback, ""); err != nil {
+		b.logger.Warn("can not answer callback", zap.Int64("chat_id", callback.ChatID), zap.Error(err))
+	}
+	b.generalStockHandler(callback.ChatID, b.langs.Printer(callback.ChatID, callback.Lang), key, "")
+}
+
+func isSearchCallback(data string) bool {
+	return strings.HasPrefix(data, searchCallbackPrefix+callbackDataSeparator)
+}
+
+// truncate cuts s to n runes adding ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
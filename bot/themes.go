@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/Apakhov/stocks-bot/chartgen"
+
+	"github.com/pkg/errors"
+)
+
+// chatThemes chart themes chosen by chats with /theme,
+// persisted to file if path is set
+type chatThemes struct {
+	mu     sync.RWMutex
+	names  map[int64]string
+	path   string
+	themes chartgen.Themes
+}
+
+func newChatThemes(path string, themes chartgen.Themes) (*chatThemes, error) {
+	c := &chatThemes{
+		names:  make(map[int64]string),
+		path:   path,
+		themes: themes,
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can not read chat themes")
+	}
+	if err := json.Unmarshal(data, &c.names); err != nil {
+		return nil, errors.Wrapf(err, "can not decode chat themes %s", path)
+	}
+	return c, nil
+}
+
+// Name returns theme name of chat, chats without known chosen theme use light theme
+func (c *chatThemes) Name(chatID int64) string {
+	c.mu.RLock()
+	name, ok := c.names[chatID]
+	c.mu.RUnlock()
+	if _, known := c.themes[name]; !ok || !known {
+		return chartgen.ThemeLight
+	}
+	return name
+}
+
+// Theme returns theme of chat
+func (c *chatThemes) Theme(chatID int64) *chartgen.Theme {
+	return c.themes[c.Name(chatID)]
+}
+
+// Names returns available theme names
+func (c *chatThemes) Names() []string {
+	return c.themes.Names()
+}
+
+// Set saves chat theme, unknown theme is chartgen.ErrBadTheme
+func (c *chatThemes) Set(chatID int64, name string) error {
+	if _, err := c.themes.Get(name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.names[chatID] = name
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c.names)
+	if err != nil {
+		return errors.Wrap(err, "can not encode chat themes")
+	}
+	return errors.Wrap(writeFileAtomic(c.path, data), "can not save chat themes")
+}
@@ -0,0 +1,154 @@
+// Package calendar describes trading sessions, holidays and timezones of exchanges
+package calendar
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// maxSearchDays limits search of trading days around long holidays
+	maxSearchDays = 30
+)
+
+// Calendar trading sessions of exchange, one session per trading day
+type Calendar struct {
+	Name     string
+	Location *time.Location
+	// Open and Close offsets of session from midnight
+	Open  time.Duration
+	Close time.Duration
+
+	holidays map[string]bool
+}
+
+// Session trading session
+type Session struct {
+	Open  time.Time
+	Close time.Time
+}
+
+// Contains reports if t is within session
+func (s Session) Contains(t time.Time) bool {
+	return !t.Before(s.Open) && t.Before(s.Close)
+}
+
+// Duration returns session length
+func (s Session) Duration() time.Duration {
+	return s.Close.Sub(s.Open)
+}
+
+// New creates calendar with sessions on weekdays except holidays in "2006-01-02" format
+func New(name string, location *time.Location, open, close time.Duration, holidays []string) *Calendar {
+	c := &Calendar{
+		Name:     name,
+		Location: location,
+		Open:     open,
+		Close:    close,
+		holidays: make(map[string]bool, len(holidays)),
+	}
+	for _, holiday := range holidays {
+		c.holidays[holiday] = true
+	}
+	return c
+}
+
+// AddHolidays adds holidays in "2006-01-02" format, it must be called before calendar is used concurrently
+func (c *Calendar) AddHolidays(holidays []string) error {
+	for _, holiday := range holidays {
+		if _, err := time.Parse(dateLayout, holiday); err != nil {
+			return errors.Wrapf(err, "bad %s holiday", c.Name)
+		}
+	}
+	for _, holiday := range holidays {
+		c.holidays[holiday] = true
+	}
+	return nil
+}
+
+// LoadLocation returns location by name or fixed zone with offset seconds if tz database is missing
+func LoadLocation(name string, offset int) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone(name, offset)
+	}
+	return loc
+}
+
+// midnight returns start of day of t in calendar location
+func (c *Calendar) midnight(t time.Time) time.Time {
+	t = t.In(c.Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.Location)
+}
+
+// IsTradingDay reports if there is session on day of t
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	t = t.In(c.Location)
+	if weekday := t.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	return !c.holidays[t.Format(dateLayout)]
+}
+
+// Session returns session on day of t, ok is false on weekends and holidays
+func (c *Calendar) Session(t time.Time) (Session, bool) {
+	if !c.IsTradingDay(t) {
+		return Session{}, false
+	}
+	midnight := c.midnight(t)
+	return Session{Open: midnight.Add(c.Open), Close: midnight.Add(c.Close)}, true
+}
+
+// IsOpen reports if market is open at t
+func (c *Calendar) IsOpen(t time.Time) bool {
+	session, ok := c.Session(t)
+	return ok && session.Contains(t)
+}
+
+// LastSession returns the latest session opened at or before t, it is current session if market is open
+func (c *Calendar) LastSession(t time.Time) Session {
+	day := c.midnight(t)
+	for i := 0; i < maxSearchDays; i++ {
+		if session, ok := c.Session(day); ok && !session.Open.After(t) {
+			return session
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	// no trading days found, treat t day as session
+	return Session{Open: c.midnight(t).Add(c.Open), Close: c.midnight(t).Add(c.Close)}
+}
+
+// NextOpen returns open time of the first session opening after t
+func (c *Calendar) NextOpen(t time.Time) time.Time {
+	day := c.midnight(t)
+	for i := 0; i < maxSearchDays; i++ {
+		if session, ok := c.Session(day); ok && session.Open.After(t) {
+			return session.Open
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return c.midnight(t).AddDate(0, 0, 1).Add(c.Open)
+}
+
+// LastTradingTime returns t if market is open or close of last session
+func (c *Calendar) LastTradingTime(t time.Time) time.Time {
+	session := c.LastSession(t)
+	if session.Contains(t) {
+		return t
+	}
+	return session.Close
+}
+
+// Sessions returns sessions overlapping [from, to] sorted by time
+func (c *Calendar) Sessions(from, to time.Time) []Session {
+	var sessions []Session
+	for day := c.midnight(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		session, ok := c.Session(day)
+		if ok && session.Close.After(from) && !session.Open.After(to) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
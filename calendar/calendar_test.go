@@ -0,0 +1,59 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddHolidays(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	c := New("TEST", loc, 10*time.Hour, 19*time.Hour, []string{"2021-12-31"})
+	day := time.Date(2027, 1, 4, 12, 0, 0, 0, loc)
+	if !c.IsTradingDay(day) {
+		t.Fatal("monday is not trading day")
+	}
+	if err := c.AddHolidays([]string{"2027-01-04"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.IsTradingDay(day) {
+		t.Error("added holiday is trading day")
+	}
+	// session before holiday and weekend is on friday
+	if got := c.LastSession(day).Open; !got.Equal(time.Date(2027, 1, 1, 10, 0, 0, 0, loc)) {
+		t.Errorf("last session opens at %v", got)
+	}
+	if err := c.AddHolidays([]string{"2027-01-05", "05.01.2027"}); err == nil {
+		t.Error("no error for bad date")
+	}
+	if !c.IsTradingDay(day.AddDate(0, 0, 1)) {
+		t.Error("holidays are added despite error")
+	}
+}
+
+func TestAddExchangeHolidays(t *testing.T) {
+	if err := AddExchangeHolidays(HolidaysConfig{NYSE: []string{"2030-01-02"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(NYSE.holidays, "2030-01-02")
+	if NYSE.IsTradingDay(time.Date(2030, 1, 2, 12, 0, 0, 0, NYSE.Location)) {
+		t.Error("added holiday is trading day")
+	}
+	if !MOEX.IsTradingDay(time.Date(2030, 1, 2, 12, 0, 0, 0, MOEX.Location)) {
+		t.Error("holiday of NYSE is added to MOEX")
+	}
+}
+
+func TestBuiltinHolidays(t *testing.T) {
+	for _, c := range []*Calendar{MOEX, NYSE} {
+		for holiday := range c.holidays {
+			day, err := time.ParseInLocation(dateLayout, holiday, c.Location)
+			if err != nil {
+				t.Errorf("%s holiday %q: %v", c.Name, holiday, err)
+				continue
+			}
+			if weekday := day.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+				t.Errorf("%s holiday %s is on weekend", c.Name, holiday)
+			}
+		}
+	}
+}
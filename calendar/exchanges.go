@@ -0,0 +1,67 @@
+package calendar
+
+import (
+	"time"
+)
+
+// moexHolidays weekdays without trading on MOEX stock market,
+// later years are added with HolidaysFile until the list is updated
+var moexHolidays = []string{
+	"2021-01-01", "2021-01-07", "2021-02-23", "2021-03-08", "2021-05-03",
+	"2021-05-10", "2021-06-14", "2021-11-04", "2021-12-31",
+	"2022-01-07", "2022-02-23", "2022-03-08", "2022-05-03", "2022-05-10",
+	"2022-06-13", "2022-11-04",
+	"2023-01-02", "2023-02-23", "2023-03-08", "2023-05-01", "2023-05-08",
+	"2023-05-09", "2023-06-12", "2023-11-06",
+	"2024-01-01", "2024-01-02", "2024-01-08", "2024-02-23", "2024-03-08",
+	"2024-05-01", "2024-05-09", "2024-05-10", "2024-06-12", "2024-11-04",
+	"2024-12-31",
+	"2025-01-01", "2025-01-02", "2025-01-07", "2025-01-08", "2025-05-01",
+	"2025-05-02", "2025-05-08", "2025-05-09", "2025-06-12", "2025-06-13",
+	"2025-11-03", "2025-11-04", "2025-12-31",
+	"2026-01-01", "2026-01-02", "2026-01-07", "2026-01-08", "2026-02-23",
+	"2026-03-09", "2026-05-01", "2026-05-11", "2026-06-12", "2026-11-04",
+	"2026-12-31",
+}
+
+// nyseHolidays weekdays without trading on NYSE,
+// later years are added with HolidaysFile until the list is updated
+var nyseHolidays = []string{
+	"2021-01-01", "2021-01-18", "2021-02-15", "2021-04-02", "2021-05-31",
+	"2021-07-05", "2021-09-06", "2021-11-25", "2021-12-24",
+	"2022-01-17", "2022-02-21", "2022-04-15", "2022-05-30", "2022-06-20",
+	"2022-07-04", "2022-09-05", "2022-11-24", "2022-12-26",
+	"2023-01-02", "2023-01-16", "2023-02-20", "2023-04-07", "2023-05-29",
+	"2023-06-19", "2023-07-04", "2023-09-04", "2023-11-23", "2023-12-25",
+	"2024-01-01", "2024-01-15", "2024-02-19", "2024-03-29", "2024-05-27",
+	"2024-06-19", "2024-07-04", "2024-09-02", "2024-11-28", "2024-12-25",
+	"2025-01-01", "2025-01-09", "2025-01-20", "2025-02-17", "2025-04-18",
+	"2025-05-26", "2025-06-19", "2025-07-04", "2025-09-01", "2025-11-27",
+	"2025-12-25",
+	"2026-01-01", "2026-01-19", "2026-02-16", "2026-04-03", "2026-05-25",
+	"2026-06-19", "2026-07-03", "2026-09-07", "2026-11-26", "2026-12-25",
+	"2027-01-01", "2027-01-18", "2027-02-15", "2027-03-26", "2027-05-31",
+	"2027-06-18", "2027-07-05", "2027-09-06", "2027-11-25", "2027-12-24",
+}
+
+var (
+	// MOEX Moscow exchange, main and evening sessions are treated as one
+	MOEX = New("MOEX", LoadLocation("Europe/Moscow", 3*60*60), 10*time.Hour, 23*time.Hour+50*time.Minute, moexHolidays)
+	// NYSE New York stock exchange regular session
+	NYSE = New("NYSE", LoadLocation("America/New_York", -5*60*60), 9*time.Hour+30*time.Minute, 16*time.Hour, nyseHolidays)
+)
+
+// HolidaysConfig holidays added to built-in calendars, e.g. of the next year
+type HolidaysConfig struct {
+	MOEX []string `json:"MOEX"`
+	NYSE []string `json:"NYSE"`
+}
+
+// AddExchangeHolidays adds holidays of conf to MOEX and NYSE calendars.
+// It must be called on start before calendars are used
+func AddExchangeHolidays(conf HolidaysConfig) error {
+	if err := MOEX.AddHolidays(conf.MOEX); err != nil {
+		return err
+	}
+	return NYSE.AddHolidays(conf.NYSE)
+}
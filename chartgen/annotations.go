@@ -0,0 +1,184 @@
+package chartgen
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	annotationPadding = vg.Length(3)
+	markerSize        = vg.Length(4)
+
+	prevCloseLabel = "prev close "
+)
+
+// priceLinePlotter draws horizontal line at price across data area with label,
+// label is drawn in a box at the right edge if box colour is set, above line at the left edge otherwise
+type priceLinePlotter struct {
+	price float64
+	label string
+	style draw.LineStyle
+	// clip leaves Y range to prices, line outside of it is not drawn
+	clip bool
+
+	box       color.Color
+	textColor color.Color
+}
+
+// newLevelPlotter returns dashed line of user price level, level outside of price range is not drawn
+func newLevelPlotter(price float64, format priceFormat, theme *Theme) *priceLinePlotter {
+	return &priceLinePlotter{
+		price: price,
+		label: format(price),
+		clip:  true,
+		style: draw.LineStyle{
+			Color:  theme.Foreground,
+			Width:  vg.Points(0.75),
+			Dashes: []vg.Length{vg.Points(6), vg.Points(3)},
+		},
+		textColor: theme.Foreground,
+	}
+}
+
+// newLastPricePlotter returns line of last close with label coloured by change since open of chart
+func newLastPricePlotter(data []ohlc.TOHLCV, format priceFormat, theme *Theme) *priceLinePlotter {
+	first, last := data[0], data[len(data)-1]
+	lineColor := theme.DefaultColor
+	switch {
+	case last.Close > first.Open:
+		lineColor = theme.GrowColor
+	case last.Close < first.Open:
+		lineColor = theme.FallColor
+	}
+	return &priceLinePlotter{
+		price: last.Close,
+		label: format(last.Close),
+		style: draw.LineStyle{
+			Color:  lineColor,
+			Width:  vg.Points(0.75),
+			Dashes: []vg.Length{vg.Points(2), vg.Points(2)},
+		},
+		box:       lineColor,
+		textColor: theme.Background,
+	}
+}
+
+// newPrevClosePlotter returns line of previous session close
+func newPrevClosePlotter(price float64, format priceFormat, theme *Theme) *priceLinePlotter {
+	return &priceLinePlotter{
+		price: price,
+		label: prevCloseLabel + format(price),
+		style: draw.LineStyle{
+			Color:  theme.Grid.Color,
+			Width:  vg.Points(1),
+			Dashes: []vg.Length{vg.Points(1), vg.Points(3)},
+		},
+		textColor: theme.Foreground,
+	}
+}
+
+// Plot implements the Plot method of the plot.Plotter interface.
+func (p *priceLinePlotter) Plot(c draw.Canvas, plt *plot.Plot) {
+	_, trY := plt.Transforms(&c)
+	y := trY(p.price)
+	if y < c.Min.Y || y > c.Max.Y {
+		return
+	}
+	c.StrokeLine2(p.style, c.Min.X, y, c.Max.X, y)
+
+	style := plt.Y.Tick.Label
+	style.Color = p.textColor
+	if p.box == nil {
+		style.XAlign = draw.XLeft
+		style.YAlign = draw.YBottom
+		c.FillText(style, vg.Point{X: c.Min.X + annotationPadding, Y: y + annotationPadding/2}, p.label)
+		return
+	}
+
+	width := style.Width(p.label) + 2*annotationPadding
+	height := style.Height(p.label) + annotationPadding
+	c.SetColor(p.box)
+	c.Fill(vg.Rectangle{
+		Min: vg.Point{X: c.Max.X - width, Y: y - height/2},
+		Max: vg.Point{X: c.Max.X, Y: y + height/2},
+	}.Path())
+	style.XAlign = draw.XRight
+	style.YAlign = draw.YCenter
+	c.FillText(style, vg.Point{X: c.Max.X - annotationPadding, Y: y}, p.label)
+}
+
+// DataRange implements the DataRange method of the plot.DataRanger interface,
+// price is kept visible unless line is clipped, X range is not affected
+func (p *priceLinePlotter) DataRange() (xmin, xmax, ymin, ymax float64) {
+	if p.clip {
+		return math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	}
+	return math.Inf(1), math.Inf(-1), p.price, p.price
+}
+
+// highLowPlotter marks candles with period high and low and labels their values
+type highLowPlotter struct {
+	high, low ohlc.TOHLCV
+	format    priceFormat
+	color     color.Color
+}
+
+func newHighLowPlotter(data []ohlc.TOHLCV, format priceFormat, theme *Theme) *highLowPlotter {
+	p := &highLowPlotter{
+		high:   data[0],
+		low:    data[0],
+		format: format,
+		color:  theme.Foreground,
+	}
+	for _, tohlc := range data {
+		if tohlc.High > p.high.High {
+			p.high = tohlc
+		}
+		if tohlc.Low < p.low.Low {
+			p.low = tohlc
+		}
+	}
+	return p
+}
+
+// Plot implements the Plot method of the plot.Plotter interface.
+func (p *highLowPlotter) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+	style := plt.Y.Tick.Label
+	style.Color = p.color
+	c.SetColor(p.color)
+
+	marks := []struct {
+		x, y  vg.Length
+		price float64
+	}{
+		{trX(float64(p.high.Timestamp)), trY(p.high.High), p.high.High},
+		{trX(float64(p.low.Timestamp)), trY(p.low.Low), p.low.Low},
+	}
+	style.YAlign = draw.YCenter
+	for _, mark := range marks {
+		// marker points at candle from the side of chart middle, so marker and label stay inside data area
+		dir := vg.Length(1)
+		style.XAlign = draw.XLeft
+		if mark.x > (c.Min.X+c.Max.X)/2 {
+			dir = -1
+			style.XAlign = draw.XRight
+		}
+		tip := mark.x + dir*markerSize
+		base := tip + dir*2*markerSize
+
+		var marker vg.Path
+		marker.Move(vg.Point{X: tip, Y: mark.y})
+		marker.Line(vg.Point{X: base, Y: mark.y + markerSize})
+		marker.Line(vg.Point{X: base, Y: mark.y - markerSize})
+		marker.Close()
+		c.Fill(marker)
+		c.FillText(style, vg.Point{X: base + dir*annotationPadding, Y: mark.y}, p.format(mark.price))
+	}
+}
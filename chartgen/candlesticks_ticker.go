@@ -270,6 +270,10 @@ func (w *weights) score(s, c, d, l float64) float64 {
 // CandlesticksTicker helps draw candlesticks ticks
 type CandlesticksTicker struct {
 	WantLables int
+	// MinPriceIncrement limits decimals of labels, 0 means decimals of tick step
+	MinPriceIncrement float64
+	// Log places ticks for logarithmic scale
+	Log bool
 }
 
 // Ticks returns Ticks in the specified range.
@@ -277,6 +281,9 @@ func (t *CandlesticksTicker) Ticks(min, max float64) []plot.Tick {
 	if max <= min {
 		panic("illegal range")
 	}
+	if t.Log && min > 0 && max/min >= logTicksRatio {
+		return logTicks(min, max, t.MinPriceIncrement)
+	}
 
 	labels, step, q, mag := talbotLinHanrahan(min, max, t.WantLables, free, nil, nil, nil)
 	majorDelta := step * math.Pow10(mag)
@@ -286,19 +293,19 @@ func (t *CandlesticksTicker) Ticks(min, max float64) []plot.Tick {
 		majorDelta = labels[1] - labels[0]
 	}
 
-	// Choose a reasonable, but ad
-	// hoc formatting for labels.
+	// labels are multiples of major step, min and max are rounded to the same precision
 	fc := byte('f')
-	prec := 2
+	prec := labelPrecision(majorDelta, t.MinPriceIncrement)
 
 	ticks := make([]plot.Tick, 0, len(labels)+2)
-	if math.Abs(min-labels[0]) > 1e-6 {
+	// range edges are labelled only if they do not crowd the nearest label
+	if edgeLabelled(min, labels, majorDelta) {
 		ticks = append(ticks, plot.Tick{Value: min, Label: strconv.FormatFloat(min, fc, prec, 64)})
 	}
 	for _, v := range labels {
 		ticks = append(ticks, plot.Tick{Value: v, Label: strconv.FormatFloat(v, fc, prec, 64)})
 	}
-	if math.Abs(max-labels[len(labels)-1]) > 1e-6 {
+	if edgeLabelled(max, labels, majorDelta) {
 		ticks = append(ticks, plot.Tick{Value: max, Label: strconv.FormatFloat(max, fc, prec, 64)})
 	}
 
@@ -345,6 +352,16 @@ func (t *CandlesticksTicker) Ticks(min, max float64) []plot.Tick {
 	return ticks
 }
 
+// edgeLabelled reports if range edge is at least half of major step from every label
+func edgeLabelled(edge float64, labels []float64, majorDelta float64) bool {
+	for _, label := range labels {
+		if math.Abs(edge-label) < majorDelta/2 {
+			return false
+		}
+	}
+	return true
+}
+
 func minInt(a, b int) int {
 	if a < b {
 		return a
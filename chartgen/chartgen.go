@@ -2,9 +2,13 @@ package chartgen
 
 import (
 	"bytes"
+	"math"
+	"strconv"
 	"time"
 
 	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
 
 	"github.com/Apakhov/stocks-bot/ohlc"
 
@@ -14,36 +18,147 @@ import (
 const (
 	// GraphImageFormat graph image format
 	graphImageFormat = "jpg"
-	timeTicksFormat  = "15:04"
-)
+	timeTicksCount   = 8
 
-var (
-	defaultTimezone, _ = time.LoadLocation("Europe/Moscow")
+	chartWidth  = 720
+	chartHeight = 480
 )
 
+var defaultTimezone, _ = time.LoadLocation("Europe/Moscow")
+
 // ChartGenerator generates image with graph
 type ChartGenerator struct {
 }
 
-// GenerateChart creates graph from CandlesticksData
-func (g *ChartGenerator) GenerateChart(data *ohlc.CandlesticksData) ([]byte, error) {
+// GenerateChart creates graph from CandlesticksData,
+// nil opts means DefaultChartOptions
+func (g *ChartGenerator) GenerateChart(data *ohlc.CandlesticksData, opts *ChartOptions) ([]byte, error) {
+	if opts == nil {
+		opts = DefaultChartOptions()
+	}
+
+	theme := opts.Theme
+	if theme == nil {
+		theme = LightTheme
+	}
+
 	candlesticksPlot := plot.New()
 	candlesticksPlot.Title.Text = data.Name + " (" + data.Ticker + " : " + data.Interval + ") "
-	candlesticksPlot.X.Tick.Marker = plot.TimeTicks{
-		Ticker: &TimeTicker{Delta: 3600, BetweenCount: 3},
-		Format: timeTicksFormat,
-		Time:   plot.UnixTimeIn(defaultTimezone),
-	}
 	candlesticksPlot.Y.Label.Text = data.Currency
-	candlesticksPlot.Y.Tick.Marker = &CandlesticksTicker{WantLables: 15}
+	priceTicker := &CandlesticksTicker{WantLables: 15, MinPriceIncrement: data.MinPriceIncrement}
+	candlesticksPlot.Y.Tick.Marker = priceTicker
+	candlesticksOptions := newCandlesticksPlotterOptions(theme)
+
+	tohlcs := data.TOHLCs
+	levels, prevClose := opts.Levels, opts.PrevClose
+	format := func(price float64) string {
+		return formatPrice(price, data.MinPriceIncrement)
+	}
+	scale := opts.Scale
+	if len(tohlcs) == 0 || lowestPrice(tohlcs) <= 0 {
+		// log and percent scales need positive prices
+		scale = ScaleLinear
+	}
+	switch scale {
+	case ScaleLog:
+		candlesticksPlot.Y.Scale = plot.LogScale{}
+		priceTicker.Log = true
+	case ScalePercent:
+		base := tohlcs[0].Open
+		tohlcs = percentCandles(tohlcs, base)
+		percentLevels := make([]float64, 0, len(levels))
+		for _, level := range levels {
+			percentLevels = append(percentLevels, percentChange(level, base))
+		}
+		levels = percentLevels
+		if prevClose > 0 {
+			prevClose = percentChange(prevClose, base)
+		}
+		format = formatPercent
+		candlesticksPlot.Y.Label.Text = percentLabel
+		// percents are not prices, precision comes from tick step
+		priceTicker.MinPriceIncrement = 0
+	}
+	location := opts.location()
+	if opts.Axis == AxisIndex {
+		var times []int64
+		tohlcs, times = indexCandles(tohlcs)
+		candlesticksPlot.X.Tick.Marker = &IndexTicker{Times: times, Location: location}
+		candlesticksOptions.XPadding = PaddingConfig{FromMin: indexPadding, FromMax: indexPadding}
+	} else {
+		ticker := &TimeTicker{WantLabels: timeTicksCount, Location: location}
+		var axis *sessionAxis
+		if opts.Calendar != nil {
+			axis = newSessionAxis(opts.Calendar, tohlcs)
+		}
+		// real time is kept if no candles are within sessions
+		if sessionCandles := axis.apply(tohlcs); len(sessionCandles) > 0 {
+			tohlcs = sessionCandles
+			ticker.Time = axis.Time
+			ticker.X = func(t time.Time) float64 {
+				return axis.X(t.Unix())
+			}
+		}
+		candlesticksPlot.X.Tick.Marker = ticker
+	}
+
+	if opts.HasIndicator(IndicatorVolume) {
+		volumeOptions := newVolumePlotterOptions(theme)
+		candlesticksPlot.Add(newVolumePlotter(tohlcs, volumeOptions))
+		// leave space for volume bars below price
+		if scale == ScaleLog {
+			candlesticksOptions.YPadding.FromMin = logVolumePadding(tohlcs, volumeOptions.HeightRatio)
+		} else {
+			candlesticksOptions.YPadding.FromMin = volumePadding(tohlcs, volumeOptions.HeightRatio)
+		}
+	}
+
+	switch opts.Type {
+	case ChartTypeLine:
+		pricePlotter := newLinePlotter(closePoints(tohlcs), theme.LineColor)
+		candlesticksPlot.Add(pricePlotter)
+		// candlesticks plotter keeps the same axis ranges for both chart types
+		candlesticksPlot.X.Min, candlesticksPlot.X.Max, candlesticksPlot.Y.Min, candlesticksPlot.Y.Max =
+			newCandlesticksPlotter(tohlcs, candlesticksOptions).DataRange()
+	default:
+		candlesticksPlot.Add(newCandlesticksPlotter(tohlcs, candlesticksOptions))
+	}
 
-	candlesticksPlotter := newCandlesticksPlotter(data.TOHLCs, newCandlesticksPlotterOptions())
-	candlesticksPlot.Add(candlesticksPlotter)
+	period := strconv.Itoa(movingAveragePeriod)
+	if opts.HasIndicator(IndicatorSMA) {
+		smaPlotter := newLinePlotter(simpleMovingAverage(tohlcs, movingAveragePeriod), theme.SMAColor)
+		candlesticksPlot.Add(smaPlotter)
+		candlesticksPlot.Legend.Add("SMA "+period, smaPlotter)
+	}
+	if opts.HasIndicator(IndicatorEMA) {
+		emaPlotter := newLinePlotter(exponentialMovingAverage(tohlcs, movingAveragePeriod), theme.EMAColor)
+		candlesticksPlot.Add(emaPlotter)
+		candlesticksPlot.Legend.Add("EMA "+period, emaPlotter)
+	}
+	candlesticksPlot.Legend.Top = true
+	candlesticksPlot.Legend.Left = true
 
-	gridPlotter := newGrid()
-	candlesticksPlot.Add(gridPlotter)
+	candlesticksPlot.Add(newGrid(theme))
+	for _, level := range levels {
+		candlesticksPlot.Add(newLevelPlotter(level, format, theme))
+	}
+	if len(tohlcs) > 0 {
+		if opts.HasAnnotation(AnnotationPrevClose) && opts.PrevClose > 0 {
+			candlesticksPlot.Add(newPrevClosePlotter(prevClose, format, theme))
+		}
+		if opts.HasAnnotation(AnnotationHighLow) {
+			candlesticksPlot.Add(newHighLowPlotter(tohlcs, format, theme))
+		}
+		if opts.HasAnnotation(AnnotationLastPrice) {
+			candlesticksPlot.Add(newLastPricePlotter(tohlcs, format, theme))
+		}
+	}
+	candlesticksPlot.Add(&brandingPlotter{theme: theme})
+	theme.apply(candlesticksPlot, chartWidth)
+	// legend font is set by theme
+	legendHeadroom(candlesticksPlot, chartWidth, chartHeight, scale == ScaleLog)
 
-	writerTo, err := candlesticksPlot.WriterTo(720, 480, graphImageFormat)
+	writerTo, err := candlesticksPlot.WriterTo(chartWidth, chartHeight, graphImageFormat)
 	if err != nil {
 		return nil, errors.Wrap(err, "can not generate graph image")
 	}
@@ -54,3 +169,51 @@ func (g *ChartGenerator) GenerateChart(data *ohlc.CandlesticksData) ([]byte, err
 	}
 	return buf.Bytes(), nil
 }
+
+// legendHeadroom raises top of Y axis, so legend in the top corner takes a band
+// without candles, high marker and other annotations
+func legendHeadroom(p *plot.Plot, width, height vg.Length, log bool) {
+	legend := p.Legend.Rectangle(draw.Canvas{}).Size().Y
+	if legend == 0 || !p.Legend.Top {
+		return
+	}
+	data := p.DataCanvas(draw.Canvas{Rectangle: vg.Rectangle{Max: vg.Point{X: width, Y: height}}}).Size().Y
+	// high marker label is centered at the highest price
+	band := legend + annotationPadding + p.Y.Tick.Label.Height("0")/2
+	if data <= band {
+		return
+	}
+	ratio := float64(band / (data - band))
+	if log {
+		p.Y.Max *= math.Pow(p.Y.Max/p.Y.Min, ratio)
+		return
+	}
+	p.Y.Max += (p.Y.Max - p.Y.Min) * ratio
+}
+
+// lowestPrice returns the lowest low of candles
+func lowestPrice(data []ohlc.TOHLCV) float64 {
+	lowest := math.Inf(1)
+	for _, tohlc := range data {
+		lowest = math.Min(lowest, tohlc.Low)
+	}
+	return lowest
+}
+
+// volumePadding returns price padding below candles,
+// so candles are drawn above volume bars taking heightRatio of canvas
+func volumePadding(data []ohlc.TOHLCV, heightRatio float64) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	minY, maxY := data[0].Low, data[0].High
+	for _, tohlc := range data {
+		if minY > tohlc.Low {
+			minY = tohlc.Low
+		}
+		if maxY < tohlc.High {
+			maxY = tohlc.High
+		}
+	}
+	return (maxY - minY) * heightRatio / (1 - heightRatio)
+}
@@ -0,0 +1,76 @@
+package chartgen
+
+import (
+	"testing"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+func TestLegendHeadroom(t *testing.T) {
+	newPlot := func(entries int) *plot.Plot {
+		p := plot.New()
+		p.Y.Min, p.Y.Max = 100, 200
+		line := newLinePlotter(nil, LightTheme.SMAColor)
+		for i := 0; i < entries; i++ {
+			p.Legend.Add("SMA 20", line)
+		}
+		p.Legend.Top = true
+		LightTheme.apply(p, chartWidth)
+		return p
+	}
+
+	p := newPlot(0)
+	legendHeadroom(p, chartWidth, chartHeight, false)
+	if p.Y.Max != 200 {
+		t.Errorf("Y max without legend = %v, want 200", p.Y.Max)
+	}
+
+	for _, log := range []bool{false, true} {
+		p := newPlot(2)
+		legendHeadroom(p, chartWidth, chartHeight, log)
+		if p.Y.Max <= 200 {
+			t.Fatalf("log %v: Y max = %v, want headroom above 200", log, p.Y.Max)
+		}
+		// the highest price is below legend band of data canvas
+		c := p.DataCanvas(draw.Canvas{Rectangle: vg.Rectangle{Max: vg.Point{X: chartWidth, Y: chartHeight}}})
+		if log {
+			p.Y.Scale = plot.LogScale{}
+		}
+		_, trY := p.Transforms(&c)
+		legendBottom := c.Max.Y - p.Legend.Rectangle(c).Size().Y
+		if top := trY(200) + p.Y.Tick.Label.Height("0")/2; top > legendBottom {
+			t.Errorf("log %v: high label top %v is above legend bottom %v", log, top, legendBottom)
+		}
+	}
+}
+
+func TestGenerateChartWithLegend(t *testing.T) {
+	var candles []ohlc.TOHLCV
+	for i := 0; i < 40; i++ {
+		price := 100 + float64(i%7)
+		candles = append(candles, ohlc.TOHLCV{Timestamp: int64(1638316800 + i*86400), OHLCV: ohlc.OHLCV{Open: price, High: price + 1, Low: price - 1, Close: price, Volume: 1}})
+	}
+	for _, scale := range []Scale{ScaleLinear, ScaleLog, ScalePercent} {
+		opts := DefaultChartOptions()
+		opts.Scale = scale
+		opts.Indicators = []Indicator{IndicatorSMA, IndicatorEMA, IndicatorVolume}
+		img, err := (&ChartGenerator{}).GenerateChart(&ohlc.CandlesticksData{Ticker: "SBER", TOHLCs: candles}, opts)
+		if err != nil || len(img) == 0 {
+			t.Errorf("scale %s: %d bytes, %v", scale, len(img), err)
+		}
+	}
+}
+
+func TestLevelsDoNotExtendPriceRange(t *testing.T) {
+	format := func(price float64) string { return "" }
+	p := plot.New()
+	p.Add(&priceLinePlotter{price: 150})
+	p.Add(newLevelPlotter(1000, format, LightTheme), newLevelPlotter(1, format, LightTheme))
+	if p.Y.Min != 150 || p.Y.Max != 150 {
+		t.Errorf("Y range = [%v, %v], want [150, 150] of price line only", p.Y.Min, p.Y.Max)
+	}
+}
@@ -0,0 +1,168 @@
+package chartgen
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+var (
+	// ErrEmptyOrderBook error for order book without orders
+	ErrEmptyOrderBook = errors.New("order book is empty")
+
+	bidColor     = color.NRGBA{R: 0, G: 168, B: 90, A: 255}
+	bidFillColor = color.NRGBA{R: 0, G: 198, B: 107, A: 96}
+	askColor     = color.NRGBA{R: 220, G: 60, B: 66, A: 255}
+	askFillColor = color.NRGBA{R: 255, G: 98, B: 103, A: 96}
+
+	spreadLineStyle = draw.LineStyle{
+		Color:  color.Gray{96},
+		Width:  vg.Points(0.75),
+		Dashes: []vg.Length{vg.Points(4), vg.Points(2)},
+	}
+)
+
+// depthSide cumulative staircase of one order book side
+type depthSide struct {
+	points    []linePoint
+	style     draw.LineStyle
+	fillColor color.Color
+}
+
+// newDepthSide accumulates quantity from best price outwards
+func newDepthSide(levels []ohlc.PriceLevel, lineColor, fillColor color.Color) *depthSide {
+	points := make([]linePoint, 0, 2*len(levels))
+	var cumulative float64
+	for _, level := range levels {
+		points = append(points, linePoint{X: level.Price, Y: cumulative})
+		cumulative += level.Quantity
+		points = append(points, linePoint{X: level.Price, Y: cumulative})
+	}
+
+	return &depthSide{
+		points: points,
+		style: draw.LineStyle{
+			Color: lineColor,
+			Width: vg.Points(1.5),
+		},
+		fillColor: fillColor,
+	}
+}
+
+// depthPlotter draws cumulative bids and asks with spread annotation
+type depthPlotter struct {
+	bids *depthSide
+	asks *depthSide
+
+	// spreadText is drawn between best bid and best ask, empty if one side is empty
+	spreadText string
+	bestBid    float64
+	bestAsk    float64
+
+	minX float64
+	maxX float64
+	maxY float64
+}
+
+func newDepthPlotter(book *ohlc.OrderBook) *depthPlotter {
+	p := &depthPlotter{
+		bids: newDepthSide(book.Bids, bidColor, bidFillColor),
+		asks: newDepthSide(book.Asks, askColor, askFillColor),
+		minX: math.Inf(1),
+		maxX: math.Inf(-1),
+	}
+
+	for _, side := range []*depthSide{p.bids, p.asks} {
+		for _, point := range side.points {
+			p.minX = math.Min(p.minX, point.X)
+			p.maxX = math.Max(p.maxX, point.X)
+			p.maxY = math.Max(p.maxY, point.Y)
+		}
+	}
+
+	if bid, ask, spread, ok := book.Spread(); ok {
+		p.bestBid, p.bestAsk = bid, ask
+		p.spreadText = "spread " + formatPrice(spread, book.MinPriceIncrement)
+		if mid := (bid + ask) / 2; mid > 0 {
+			p.spreadText += " (" + strconv.FormatFloat(spread/mid*100, 'f', 3, 64) + "%)"
+		}
+	}
+	return p
+}
+
+// formatPrice formats price with precision of min price increment
+func formatPrice(price, minPriceIncrement float64) string {
+	return strconv.FormatFloat(price, 'f', PricePrecision(minPriceIncrement), 64)
+}
+
+// Plot implements the Plot method of the plot.Plotter interface.
+func (p *depthPlotter) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+
+	for _, side := range []*depthSide{p.bids, p.asks} {
+		if len(side.points) == 0 {
+			continue
+		}
+		line := make([]vg.Point, 0, len(side.points))
+		for _, point := range side.points {
+			line = append(line, vg.Point{X: trX(point.X), Y: trY(point.Y)})
+		}
+		last := line[len(line)-1]
+		polygon := append(append([]vg.Point{}, line...), vg.Point{X: last.X, Y: trY(0)})
+		c.FillPolygon(side.fillColor, c.ClipPolygonXY(polygon))
+		c.StrokeLines(side.style, c.ClipLinesXY(line)...)
+	}
+
+	if p.spreadText == "" {
+		return
+	}
+	bidX, askX := trX(p.bestBid), trX(p.bestAsk)
+	c.StrokeLine2(spreadLineStyle, bidX, c.Min.Y, bidX, c.Max.Y)
+	c.StrokeLine2(spreadLineStyle, askX, c.Min.Y, askX, c.Max.Y)
+
+	style := plt.X.Tick.Label
+	style.XAlign = draw.XCenter
+	style.YAlign = draw.YTop
+	c.FillText(style, vg.Point{X: (bidX + askX) / 2, Y: c.Max.Y - vg.Points(4)}, p.spreadText)
+}
+
+// DataRange implements the DataRange method of the plot.DataRanger interface.
+func (p *depthPlotter) DataRange() (xmin, xmax, ymin, ymax float64) {
+	return p.minX, p.maxX, 0, p.maxY
+}
+
+// GenerateDepthChart creates cumulative depth chart of order book
+func (g *ChartGenerator) GenerateDepthChart(book *ohlc.OrderBook) ([]byte, error) {
+	if book.Empty() {
+		return nil, ErrEmptyOrderBook
+	}
+
+	depthPlot := plot.New()
+	depthPlot.Title.Text = book.Name + " (" + book.Ticker + " : order book)"
+	depthPlot.X.Label.Text = book.Currency
+	depthPlot.Y.Label.Text = "lots"
+
+	depthPlotter := newDepthPlotter(book)
+	depthPlot.Add(newGrid(LightTheme), depthPlotter)
+	// leave space above the deepest level for spread annotation
+	depthPlot.Y.Max = depthPlotter.maxY * 1.1
+
+	writerTo, err := depthPlot.WriterTo(chartWidth, chartHeight, graphImageFormat)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not generate depth chart image")
+	}
+
+	var buf bytes.Buffer
+	if _, err := writerTo.WriteTo(&buf); err != nil {
+		return nil, errors.Wrap(err, "can not save image to bytes")
+	}
+	return buf.Bytes(), nil
+}
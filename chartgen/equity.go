@@ -0,0 +1,78 @@
+package chartgen
+
+import (
+	"bytes"
+	"image/color"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+)
+
+const equityTicksCount = 6
+
+var (
+	// ErrNotEnoughPoints error for equity curve with less than two points
+	ErrNotEnoughPoints = errors.New("not enough points for chart")
+
+	costColor = color.RGBA{R: 127, G: 140, B: 141, A: 255}
+)
+
+// EquityChart portfolio equity curve with labels in chat language
+type EquityChart struct {
+	Title      string
+	Currency   string
+	ValueLabel string
+	CostLabel  string
+	Points     []ohlc.EquityPoint
+	// Theme of chart, nil means light theme
+	Theme *Theme
+}
+
+// GenerateEquityChart draws portfolio value and cost basis over time
+func (g *ChartGenerator) GenerateEquityChart(chart *EquityChart) ([]byte, error) {
+	if len(chart.Points) < 2 {
+		return nil, ErrNotEnoughPoints
+	}
+
+	valuePoints := make([]linePoint, 0, len(chart.Points))
+	costPoints := make([]linePoint, 0, len(chart.Points))
+	for _, point := range chart.Points {
+		valuePoints = append(valuePoints, linePoint{X: float64(point.Timestamp), Y: point.Value})
+		costPoints = append(costPoints, linePoint{X: float64(point.Timestamp), Y: point.Cost})
+	}
+
+	theme := chart.Theme
+	if theme == nil {
+		theme = LightTheme
+	}
+
+	equityPlot := plot.New()
+	equityPlot.Title.Text = chart.Title
+	equityPlot.X.Tick.Marker = &TimeTicker{WantLabels: equityTicksCount}
+	equityPlot.Y.Label.Text = chart.Currency
+	equityPlot.Y.Tick.Marker = &CandlesticksTicker{WantLables: 10}
+
+	costPlotter := newLinePlotter(costPoints, costColor)
+	costPlotter.style.Dashes = []vg.Length{vg.Points(4), vg.Points(3)}
+	valuePlotter := newLinePlotter(valuePoints, theme.LineColor)
+	equityPlot.Add(costPlotter, valuePlotter, newGrid(theme), &brandingPlotter{theme: theme})
+	equityPlot.Legend.Add(chart.ValueLabel, valuePlotter)
+	equityPlot.Legend.Add(chart.CostLabel, costPlotter)
+	equityPlot.Legend.Top = true
+	equityPlot.Legend.Left = true
+	theme.apply(equityPlot, chartWidth)
+
+	writerTo, err := equityPlot.WriterTo(chartWidth, chartHeight, graphImageFormat)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not generate equity image")
+	}
+
+	var buf bytes.Buffer
+	if _, err := writerTo.WriteTo(&buf); err != nil {
+		return nil, errors.Wrap(err, "can not save image to bytes")
+	}
+	return buf.Bytes(), nil
+}
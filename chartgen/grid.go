@@ -29,11 +29,11 @@ type Grid struct {
 }
 
 // NewGrid returns a new grid with both vertical and
-// horizontal lines using the default grid line style.
-func newGrid() *Grid {
+// horizontal lines using the theme grid line style.
+func newGrid(theme *Theme) *Grid {
 	return &Grid{
-		Vertical:   DefaultGridLineStyle,
-		Horizontal: DefaultGridLineStyle,
+		Vertical:   theme.Grid,
+		Horizontal: theme.Grid,
 	}
 }
 
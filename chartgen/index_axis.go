@@ -0,0 +1,119 @@
+package chartgen
+
+import (
+	"math"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"gonum.org/v1/plot"
+)
+
+const (
+	hourLabelFormat  = "15:04"
+	dayLabelFormat   = "02 Jan"
+	monthLabelFormat = "Jan 2006"
+
+	// hourLabelsSpan and dayLabelsSpan longest spans labelled with hours and days
+	hourLabelsSpan = 2 * 24 * time.Hour
+	dayLabelsSpan  = 180 * 24 * time.Hour
+
+	defaultIndexLabels = 8
+	// indexPadding free candle slots on both sides of index axis
+	indexPadding = 1
+)
+
+// indexCandles returns candles with timestamps replaced by their indexes and real timestamps of candles
+func indexCandles(candles []ohlc.TOHLCV) ([]ohlc.TOHLCV, []int64) {
+	result := make([]ohlc.TOHLCV, 0, len(candles))
+	times := make([]int64, 0, len(candles))
+	for i, candle := range candles {
+		times = append(times, candle.Timestamp)
+		candle.Timestamp = int64(i)
+		result = append(result, candle)
+	}
+	return result, times
+}
+
+// labelLayout returns label format of span and format of key which changes on label boundaries
+func labelLayout(span time.Duration) (layout, boundary string) {
+	switch {
+	case span <= hourLabelsSpan:
+		return hourLabelFormat, "2006-01-02 15"
+	case span <= dayLabelsSpan:
+		return dayLabelFormat, "2006-01-02"
+	default:
+		return monthLabelFormat, "2006-01"
+	}
+}
+
+// IndexTicker ticks of index X axis, where candles are evenly spaced:
+// ticks are placed at candles starting new hour, day or month
+// depending on visible span and labelled with real time.
+// On hourly labels the first candle of each session is labelled with date, so days are told apart
+type IndexTicker struct {
+	// Times real timestamps of candles by index
+	Times      []int64
+	Location   *time.Location
+	WantLabels int
+}
+
+// Ticks returns Ticks in the specified range.
+func (t *IndexTicker) Ticks(min, max float64) []plot.Tick {
+	first := maxInt(int(math.Ceil(min)), 0)
+	last := minInt(int(math.Floor(max)), len(t.Times)-1)
+	if first > last {
+		return nil
+	}
+
+	timeOf := func(i int) time.Time {
+		return time.Unix(t.Times[i], 0).In(t.Location)
+	}
+	layout, boundary := labelLayout(timeOf(last).Sub(timeOf(first)))
+
+	var bounds []int
+	var prev string
+	for i := first; i <= last; i++ {
+		key := timeOf(i).Format(boundary)
+		if i == first || key != prev {
+			bounds = append(bounds, i)
+		}
+		prev = key
+	}
+
+	want := t.WantLabels
+	if want <= 0 {
+		want = defaultIndexLabels
+	}
+	// boundaries without label stay as minor ticks
+	step := (len(bounds) + want - 1) / want
+	// first candle of session is labelled with date on hourly labels
+	sessionStart := func(j int) bool {
+		return layout == hourLabelFormat && (j == 0 || !sameDay(timeOf(bounds[j]), timeOf(bounds[j-1])))
+	}
+	ticks := make([]plot.Tick, 0, len(bounds))
+	sinceLabel := step
+	for j, i := range bounds {
+		tick := plot.Tick{Value: float64(i)}
+		switch {
+		case sessionStart(j):
+			tick.Label = timeOf(i).Format(dayLabelFormat)
+		case sinceLabel >= step && (j+1 == len(bounds) || !sessionStart(j+1)):
+			// label right before date label would touch it
+			tick.Label = timeOf(i).Format(layout)
+		}
+		if tick.Label != "" {
+			sinceLabel = 0
+		}
+		sinceLabel++
+		ticks = append(ticks, tick)
+	}
+	return ticks
+}
+
+// sameDay reports if a and b are on the same date of their location
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
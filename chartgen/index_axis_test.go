@@ -0,0 +1,67 @@
+package chartgen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIndexTickerSessions(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	// hourly candles of two sessions 10:00-18:00
+	var times []int64
+	for _, day := range []int{1, 2} {
+		for hour := 10; hour <= 18; hour++ {
+			times = append(times, time.Date(2021, 12, day, hour, 0, 0, 0, loc).Unix())
+		}
+	}
+	ticker := &IndexTicker{Times: times, Location: loc, WantLabels: 6}
+	ticks := ticker.Ticks(0, float64(len(times)-1))
+	if len(ticks) != len(times) {
+		t.Fatalf("got %d ticks, want tick per hour", len(ticks))
+	}
+
+	labels := map[int]string{}
+	for _, tick := range ticks {
+		if tick.Label != "" {
+			labels[int(tick.Value)] = tick.Label
+		}
+	}
+	if labels[0] != "01 Dec" || labels[9] != "02 Dec" {
+		t.Errorf("session starts are labelled %q and %q, want dates", labels[0], labels[9])
+	}
+	for i, label := range labels {
+		if i == 0 || i == 9 {
+			continue
+		}
+		if _, err := time.Parse(hourLabelFormat, label); err != nil {
+			t.Errorf("label %q inside session is not time", label)
+		}
+	}
+	if _, ok := labels[8]; ok {
+		t.Error("last candle of session is labelled next to date label")
+	}
+	if len(labels) > 6 {
+		t.Errorf("%d labels, want at most 6: %v", len(labels), labels)
+	}
+}
+
+func TestIndexTickerDays(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	var times []int64
+	for day := 1; day <= 30; day++ {
+		times = append(times, time.Date(2021, 11, day, 0, 0, 0, 0, loc).Unix())
+	}
+	ticks := (&IndexTicker{Times: times, Location: loc, WantLabels: 5}).Ticks(0, 29)
+	labelled := 0
+	for _, tick := range ticks {
+		if tick.Label != "" {
+			labelled++
+			if _, err := time.Parse(dayLabelFormat, tick.Label); err != nil {
+				t.Errorf("label %q is not date", tick.Label)
+			}
+		}
+	}
+	if labelled != 5 {
+		t.Errorf("%d labels, want 5", labelled)
+	}
+}
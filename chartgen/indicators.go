@@ -0,0 +1,51 @@
+package chartgen
+
+import (
+	"github.com/Apakhov/stocks-bot/ohlc"
+)
+
+const movingAveragePeriod = 20
+
+// simpleMovingAverage returns SMA of close prices,
+// first period-1 candles have no value
+func simpleMovingAverage(data []ohlc.TOHLCV, period int) []linePoint {
+	if len(data) < period {
+		return nil
+	}
+
+	points := make([]linePoint, 0, len(data)-period+1)
+	sum := 0.
+	for i, tohlc := range data {
+		sum += tohlc.Close
+		if i >= period {
+			sum -= data[i-period].Close
+		}
+		if i >= period-1 {
+			points = append(points, linePoint{X: float64(tohlc.Timestamp), Y: sum / float64(period)})
+		}
+	}
+	return points
+}
+
+// exponentialMovingAverage returns EMA of close prices
+// seeded with SMA of first period candles
+func exponentialMovingAverage(data []ohlc.TOHLCV, period int) []linePoint {
+	if len(data) < period {
+		return nil
+	}
+
+	alpha := 2. / float64(period+1)
+	ema := 0.
+	for _, tohlc := range data[:period] {
+		ema += tohlc.Close
+	}
+	ema /= float64(period)
+
+	points := make([]linePoint, 0, len(data)-period+1)
+	points = append(points, linePoint{X: float64(data[period-1].Timestamp), Y: ema})
+	for _, tohlc := range data[period:] {
+		ema = alpha*tohlc.Close + (1-alpha)*ema
+		points = append(points, linePoint{X: float64(tohlc.Timestamp), Y: ema})
+	}
+	return points
+}
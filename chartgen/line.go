@@ -0,0 +1,86 @@
+package chartgen
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// linePoint point of line series
+type linePoint struct {
+	X float64
+	Y float64
+}
+
+// linePlotter draws line through points
+type linePlotter struct {
+	points []linePoint
+	style  draw.LineStyle
+
+	minX float64
+	minY float64
+	maxX float64
+	maxY float64
+}
+
+func newLinePlotter(points []linePoint, lineColor color.Color) *linePlotter {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, point := range points {
+		minX = math.Min(minX, point.X)
+		maxX = math.Max(maxX, point.X)
+		minY = math.Min(minY, point.Y)
+		maxY = math.Max(maxY, point.Y)
+	}
+
+	return &linePlotter{
+		points: points,
+		style: draw.LineStyle{
+			Color: lineColor,
+			Width: vg.Points(1.5),
+		},
+		minX: minX,
+		minY: minY,
+		maxX: maxX,
+		maxY: maxY,
+	}
+}
+
+// closePoints returns close prices as line points
+func closePoints(data []ohlc.TOHLCV) []linePoint {
+	points := make([]linePoint, 0, len(data))
+	for _, tohlc := range data {
+		points = append(points, linePoint{X: float64(tohlc.Timestamp), Y: tohlc.Close})
+	}
+	return points
+}
+
+// Plot implements the Plot method of the plot.Plotter interface.
+func (p *linePlotter) Plot(c draw.Canvas, plt *plot.Plot) {
+	if len(p.points) < 2 {
+		return
+	}
+	trX, trY := plt.Transforms(&c)
+
+	line := make([]vg.Point, 0, len(p.points))
+	for _, point := range p.points {
+		line = append(line, vg.Point{X: trX(point.X), Y: trY(point.Y)})
+	}
+	c.StrokeLines(p.style, c.ClipLinesXY(line)...)
+}
+
+// DataRange implements the DataRange method of the plot.DataRanger interface.
+func (p *linePlotter) DataRange() (xmin, xmax, ymin, ymax float64) {
+	return p.minX, p.maxX, p.minY, p.maxY
+}
+
+// Thumbnail implements the Thumbnail method of the plot.Thumbnailer interface.
+func (p *linePlotter) Thumbnail(c *draw.Canvas) {
+	y := c.Center().Y
+	c.StrokeLine2(p.style, c.Min.X, y, c.Max.X, y)
+}
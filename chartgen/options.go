@@ -0,0 +1,247 @@
+package chartgen
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/calendar"
+
+	"github.com/pkg/errors"
+)
+
+// ChartType type of price chart
+type ChartType string
+
+// Chart types
+const (
+	ChartTypeCandles ChartType = "candles"
+	ChartTypeLine    ChartType = "line"
+)
+
+// Indicator additional series drawn over price chart
+type Indicator string
+
+// Available indicators
+const (
+	IndicatorSMA    Indicator = "sma"
+	IndicatorEMA    Indicator = "ema"
+	IndicatorVolume Indicator = "vol"
+)
+
+// Axis mode of chart X axis
+type Axis string
+
+// Available X axis modes
+const (
+	// AxisTime places candles at their time
+	AxisTime Axis = "time"
+	// AxisIndex places candles evenly one after another, skipping time without candles
+	AxisIndex Axis = "index"
+)
+
+// Scale mode of price axis
+type Scale string
+
+// Available price scales
+const (
+	ScaleLinear Scale = "linear"
+	// ScaleLog logarithmic scale, equal percent moves take equal height,
+	// ranges narrower than logTicksRatio are labelled with equal steps as they look linear
+	ScaleLog Scale = "log"
+	// ScalePercent linear scale of percent change from open of the first candle
+	ScalePercent Scale = "percent"
+)
+
+// Annotation price context drawn over chart
+type Annotation string
+
+// Available annotations
+const (
+	// AnnotationLastPrice line and right edge label at last close
+	AnnotationLastPrice Annotation = "last"
+	// AnnotationHighLow markers with values at period high and low
+	AnnotationHighLow Annotation = "hilo"
+	// AnnotationPrevClose line at close of previous session
+	AnnotationPrevClose Annotation = "prev"
+	// annotationsNone explicitly requests no annotations
+	annotationsNone = "none"
+)
+
+const (
+	indicatorsSeparator = ","
+	maxLevels           = 10
+)
+
+var (
+	// ErrBadChartType error for unknown chart type
+	ErrBadChartType = errors.New("unknown chart type")
+	// ErrBadIndicator error for unknown indicator
+	ErrBadIndicator = errors.New("unknown indicator")
+	// ErrBadAxis error for unknown X axis mode
+	ErrBadAxis = errors.New("unknown axis")
+	// ErrBadScale error for unknown price scale
+	ErrBadScale = errors.New("unknown scale")
+	// ErrBadAnnotation error for unknown annotation
+	ErrBadAnnotation = errors.New("unknown annotation")
+	// ErrBadLevels error for malformed price levels
+	ErrBadLevels = errors.New("bad price levels")
+)
+
+// ChartOptions options of generated chart
+type ChartOptions struct {
+	Type       ChartType
+	Indicators []Indicator
+	Axis       Axis
+	Scale      Scale
+	// Calendar removes time between sessions from X axis of intraday charts in AxisTime mode,
+	// nil keeps real time
+	Calendar *calendar.Calendar
+	// Location timezone of X axis labels, nil means calendar timezone or Europe/Moscow
+	Location *time.Location
+	// Theme of chart, nil means light theme
+	Theme *Theme
+	// Annotations price context drawn over chart
+	Annotations []Annotation
+	// Levels prices of horizontal support and resistance lines
+	Levels []float64
+	// PrevClose close of previous session for AnnotationPrevClose, 0 means unknown
+	PrevClose float64
+}
+
+// location returns timezone of X axis labels
+func (o *ChartOptions) location() *time.Location {
+	switch {
+	case o.Location != nil:
+		return o.Location
+	case o.Calendar != nil:
+		return o.Calendar.Location
+	default:
+		return defaultTimezone
+	}
+}
+
+// DefaultChartOptions returns candlesticks chart without indicators
+func DefaultChartOptions() *ChartOptions {
+	return &ChartOptions{Type: ChartTypeCandles, Axis: AxisTime, Scale: ScaleLinear}
+}
+
+// ParseScale parses price scale, empty value means ScaleLinear
+func ParseScale(scale string) (Scale, error) {
+	switch Scale(scale) {
+	case "":
+		return ScaleLinear, nil
+	case ScaleLinear, ScaleLog, ScalePercent:
+		return Scale(scale), nil
+	default:
+		return "", errors.Wrapf(ErrBadScale, "%q", scale)
+	}
+}
+
+// ParseAnnotations parses comma separated annotations, empty value and "none" mean no annotations
+func ParseAnnotations(annotations string) ([]Annotation, error) {
+	if annotations == "" || annotations == annotationsNone {
+		return nil, nil
+	}
+
+	var result []Annotation
+	for _, annotation := range strings.Split(annotations, indicatorsSeparator) {
+		switch Annotation(annotation) {
+		case AnnotationLastPrice, AnnotationHighLow, AnnotationPrevClose:
+			result = append(result, Annotation(annotation))
+		default:
+			return nil, errors.Wrapf(ErrBadAnnotation, "%q", annotation)
+		}
+	}
+	return result, nil
+}
+
+// ParseLevels parses comma separated prices of horizontal levels, e.g. "250,262.5"
+func ParseLevels(levels string) ([]float64, error) {
+	if levels == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(levels, indicatorsSeparator)
+	if len(parts) > maxLevels {
+		return nil, errors.Wrapf(ErrBadLevels, "more than %d levels", maxLevels)
+	}
+	result := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		level, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || level <= 0 || math.IsInf(level, 0) {
+			return nil, errors.Wrapf(ErrBadLevels, "%q", part)
+		}
+		result = append(result, level)
+	}
+	return result, nil
+}
+
+// ParseAxis parses X axis mode, empty value means AxisTime
+func ParseAxis(axis string) (Axis, error) {
+	switch Axis(axis) {
+	case "":
+		return AxisTime, nil
+	case AxisTime, AxisIndex:
+		return Axis(axis), nil
+	default:
+		return "", errors.Wrapf(ErrBadAxis, "%q", axis)
+	}
+}
+
+// ParseChartOptions parses chart type and comma separated indicators,
+// empty values mean defaults
+func ParseChartOptions(chartType, indicators string) (*ChartOptions, error) {
+	opts := DefaultChartOptions()
+
+	switch ChartType(chartType) {
+	case "":
+	case ChartTypeCandles, ChartTypeLine:
+		opts.Type = ChartType(chartType)
+	default:
+		return nil, errors.Wrapf(ErrBadChartType, "%q", chartType)
+	}
+
+	if indicators == "" {
+		return opts, nil
+	}
+	for _, indicator := range strings.Split(indicators, indicatorsSeparator) {
+		switch Indicator(indicator) {
+		case IndicatorSMA, IndicatorEMA, IndicatorVolume:
+			opts.Indicators = append(opts.Indicators, Indicator(indicator))
+		default:
+			return nil, errors.Wrapf(ErrBadIndicator, "%q", indicator)
+		}
+	}
+	return opts, nil
+}
+
+// HasAnnotation reports if annotation is enabled
+func (o *ChartOptions) HasAnnotation(annotation Annotation) bool {
+	for _, a := range o.Annotations {
+		if a == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIndicator reports if indicator is enabled
+func (o *ChartOptions) HasIndicator(indicator Indicator) bool {
+	for _, i := range o.Indicators {
+		if i == indicator {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatIndicators returns comma separated indicators
+func FormatIndicators(indicators []Indicator) string {
+	parts := make([]string, 0, len(indicators))
+	for _, indicator := range indicators {
+		parts = append(parts, string(indicator))
+	}
+	return strings.Join(parts, indicatorsSeparator)
+}
@@ -12,6 +12,9 @@ import (
 	"gonum.org/v1/plot/vg/draw"
 )
 
+// candleBodyRatio part of distance between candles taken by candle body
+const candleBodyRatio = 0.8
+
 // PaddingConfig padding config
 type PaddingConfig struct {
 	FromMin float64
@@ -29,8 +32,8 @@ type candlesticksPlotterOptions struct {
 }
 
 // NewCandlesticksPlotterOptions returns CandlesticksPlotterOptions
-// with default config
-func newCandlesticksPlotterOptions() *candlesticksPlotterOptions {
+// with default config and theme colours
+func newCandlesticksPlotterOptions(theme *Theme) *candlesticksPlotterOptions {
 	return &candlesticksPlotterOptions{
 		XPadding: PaddingConfig{
 			FromMin: 0,
@@ -40,9 +43,9 @@ func newCandlesticksPlotterOptions() *candlesticksPlotterOptions {
 			FromMin: 0,
 			FromMax: 0,
 		},
-		GrowColor:    color.RGBA{R: 0, G: 198, B: 107, A: 255},
-		FallColor:    color.RGBA{R: 255, G: 98, B: 103, A: 255},
-		DefaultColor: color.Black,
+		GrowColor:    theme.GrowColor,
+		FallColor:    theme.FallColor,
+		DefaultColor: theme.DefaultColor,
 	}
 }
 
@@ -89,7 +92,7 @@ func newCandlesticksPlotter(data []ohlc.TOHLCV, opt *candlesticksPlotterOptions)
 func (p *candlesticksPlotter) Plot(c draw.Canvas, plt *plot.Plot) {
 	trX, trY := plt.Transforms(&c)
 
-	candleBodyWidth := font.Length(float64(c.Size().X) / float64(len(p.tohlcs)))
+	candleBodyWidth := candleWidth(c, trX, p.tohlcs)
 	for _, tohlc := range p.tohlcs {
 		tsX := trX(float64(tohlc.Timestamp))
 		openY := trY(tohlc.Open)
@@ -125,6 +128,18 @@ func (p *candlesticksPlotter) Plot(c draw.Canvas, plt *plot.Plot) {
 	}
 }
 
+// candleWidth returns width of candle body, the smallest distance between
+// neighbour candles without a gap, so candles do not overlap around time gaps
+func candleWidth(c draw.Canvas, trX func(float64) vg.Length, data []ohlc.TOHLCV) vg.Length {
+	width := font.Length(float64(c.Size().X) / float64(len(data)))
+	for i := 1; i < len(data); i++ {
+		if d := trX(float64(data[i].Timestamp)) - trX(float64(data[i-1].Timestamp)); d > 0 && d < width {
+			width = d
+		}
+	}
+	return width * candleBodyRatio
+}
+
 // DataRange implements the DataRange method of the plot.DataRanger interface.
 func (p *candlesticksPlotter) DataRange() (xmin, xmax, ymin, ymax float64) {
 	return p.minX - p.options.XPadding.FromMin,
@@ -0,0 +1,119 @@
+package chartgen
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"gonum.org/v1/plot"
+)
+
+const (
+	percentLabel = "%"
+	// maxPrecision limits decimals of price labels
+	maxPrecision = 10
+	// logTicksRatio smallest max/min ratio labelled by 1-2-5 steps on log scale,
+	// narrower ranges look linear and use linear ticks
+	logTicksRatio = 10
+	// defaultPricePrecision decimals of prices with unknown min price increment
+	defaultPricePrecision = 2
+)
+
+// priceFormat formats price of chart annotations
+type priceFormat func(price float64) string
+
+// precision returns count of decimals needed to print step exactly
+func precision(step float64) int {
+	step = math.Abs(step)
+	for prec := 0; prec < maxPrecision; prec++ {
+		scaled := step * math.Pow10(prec)
+		if math.Abs(scaled-math.Round(scaled)) < 1e-6*scaled {
+			return prec
+		}
+	}
+	return maxPrecision
+}
+
+// PricePrecision returns decimals of prices with min price increment, 2 if it is unknown
+func PricePrecision(minPriceIncrement float64) int {
+	if minPriceIncrement > 0 {
+		return precision(minPriceIncrement)
+	}
+	return defaultPricePrecision
+}
+
+// labelPrecision returns decimals of labels with step between them,
+// labels never have more decimals than min price increment if it is known
+func labelPrecision(step, minPriceIncrement float64) int {
+	prec := precision(step)
+	if minPriceIncrement > 0 {
+		if incrementPrec := precision(minPriceIncrement); prec > incrementPrec {
+			prec = incrementPrec
+		}
+	}
+	return prec
+}
+
+// percentCandles returns candles with prices replaced by percent change from base
+func percentCandles(candles []ohlc.TOHLCV, base float64) []ohlc.TOHLCV {
+	result := make([]ohlc.TOHLCV, 0, len(candles))
+	for _, candle := range candles {
+		candle.Open = percentChange(candle.Open, base)
+		candle.High = percentChange(candle.High, base)
+		candle.Low = percentChange(candle.Low, base)
+		candle.Close = percentChange(candle.Close, base)
+		result = append(result, candle)
+	}
+	return result
+}
+
+func percentChange(price, base float64) float64 {
+	return (price/base - 1) * 100
+}
+
+// formatPercent formats percent change with sign
+func formatPercent(percent float64) string {
+	s := strconv.FormatFloat(percent, 'f', 2, 64) + percentLabel
+	if percent > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// logVolumePadding returns price padding below candles on log scale,
+// so candles are drawn above volume bars taking heightRatio of canvas
+func logVolumePadding(data []ohlc.TOHLCV, heightRatio float64) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	minY, maxY := data[0].Low, data[0].High
+	for _, tohlc := range data {
+		minY = math.Min(minY, tohlc.Low)
+		maxY = math.Max(maxY, tohlc.High)
+	}
+	if minY <= 0 {
+		return 0
+	}
+	return minY - minY/math.Pow(maxY/minY, heightRatio/(1-heightRatio))
+}
+
+// logTicks returns ticks at 1, 2 and 5 times powers of ten labelled and other integer multiples as minor ticks
+func logTicks(min, max, minPriceIncrement float64) []plot.Tick {
+	var ticks []plot.Tick
+	for exp := int(math.Floor(math.Log10(min))); exp <= int(math.Ceil(math.Log10(max))); exp++ {
+		magnitude := math.Pow10(exp)
+		for m := 1; m < 10; m++ {
+			value := float64(m) * magnitude
+			if value < min || value > max {
+				continue
+			}
+			tick := plot.Tick{Value: value}
+			if m == 1 || m == 2 || m == 5 {
+				tick.Label = strconv.FormatFloat(value, 'f', labelPrecision(magnitude, minPriceIncrement), 64)
+			}
+			ticks = append(ticks, tick)
+		}
+	}
+	return ticks
+}
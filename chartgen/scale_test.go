@@ -0,0 +1,109 @@
+package chartgen
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestPricePrecision(t *testing.T) {
+	for _, tc := range []struct {
+		increment float64
+		want      int
+	}{
+		{0, 2},
+		{1, 0},
+		{0.5, 1},
+		{0.01, 2},
+		{0.05, 2},
+		{0.0025, 4},
+		{0.00001, 5},
+	} {
+		if got := PricePrecision(tc.increment); got != tc.want {
+			t.Errorf("PricePrecision(%v) = %d, want %d", tc.increment, got, tc.want)
+		}
+		if got := formatPrice(1.5, tc.increment); tc.want > 0 && len(got) != len("1.")+tc.want {
+			t.Errorf("formatPrice(1.5, %v) = %s", tc.increment, got)
+		}
+	}
+}
+
+func TestLabelPrecision(t *testing.T) {
+	for _, tc := range []struct {
+		step, increment float64
+		want            int
+	}{
+		{0.5, 0, 1},
+		{0.25, 0, 2},
+		{0.001, 0.01, 2},
+		{10, 0.0025, 0},
+	} {
+		if got := labelPrecision(tc.step, tc.increment); got != tc.want {
+			t.Errorf("labelPrecision(%v, %v) = %d, want %d", tc.step, tc.increment, got, tc.want)
+		}
+	}
+}
+
+func TestEdgeLabelled(t *testing.T) {
+	labels := []float64{100, 105, 110}
+	for _, tc := range []struct {
+		edge float64
+		want bool
+	}{
+		{99.9, false},
+		{97.6, false},
+		{97.5, true},
+		{96, true},
+		{111, false},
+		{113, true},
+	} {
+		if got := edgeLabelled(tc.edge, labels, 5); got != tc.want {
+			t.Errorf("edgeLabelled(%v) = %v, want %v", tc.edge, got, tc.want)
+		}
+	}
+}
+
+func TestCandlesticksTickerLabelsApart(t *testing.T) {
+	for _, r := range [][2]float64{{99.9, 120.1}, {97, 123}, {101.3, 118.2}, {0.0312, 0.0377}} {
+		ticks := (&CandlesticksTicker{WantLables: 5}).Ticks(r[0], r[1])
+		var labelled []float64
+		for _, tick := range ticks {
+			if tick.Label != "" {
+				labelled = append(labelled, tick.Value)
+			}
+		}
+		sort.Float64s(labelled)
+		if len(labelled) < 3 {
+			t.Errorf("range %v: labels %v", r, labelled)
+			continue
+		}
+		gaps := make([]float64, 0, len(labelled)-1)
+		for k := 1; k < len(labelled); k++ {
+			gaps = append(gaps, labelled[k]-labelled[k-1])
+		}
+		sort.Float64s(gaps)
+		// most gaps are major step, edges are at least half of it from labels
+		if major := gaps[len(gaps)/2]; gaps[0] < major/2*0.999 {
+			t.Errorf("range %v: labels %v crowd", r, labelled)
+		}
+	}
+}
+
+func TestCandlesticksTickerLog(t *testing.T) {
+	ticks := (&CandlesticksTicker{WantLables: 5, Log: true}).Ticks(3, 700)
+	for _, tick := range ticks {
+		if tick.Label == "" {
+			continue
+		}
+		if m := tick.Value / math.Pow10(int(math.Floor(math.Log10(tick.Value)))); m != 1 && m != 2 && m != 5 {
+			t.Errorf("wide log range labelled at %v", tick.Value)
+		}
+	}
+
+	// narrow log range is labelled like linear one
+	narrow := (&CandlesticksTicker{WantLables: 5, Log: true}).Ticks(100, 120)
+	linear := (&CandlesticksTicker{WantLables: 5}).Ticks(100, 120)
+	if len(narrow) != len(linear) {
+		t.Errorf("narrow log range has %d ticks, linear %d", len(narrow), len(linear))
+	}
+}
@@ -0,0 +1,113 @@
+package chartgen
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/ohlc"
+)
+
+// defaultCandleStep step of single candle, the finest candle interval
+const defaultCandleStep = 60
+
+// sessionAxis maps timestamps to trading time, so time between sessions takes no space on X axis
+type sessionAxis struct {
+	sessions []calendar.Session
+	// offsets trading seconds before each session
+	offsets []int64
+}
+
+// newSessionAxis returns axis of calendar sessions during candles, nil if there are no sessions
+func newSessionAxis(cal *calendar.Calendar, candles []ohlc.TOHLCV) *sessionAxis {
+	if len(candles) == 0 {
+		return nil
+	}
+	sessions := cal.Sessions(time.Unix(candles[0].Timestamp, 0), time.Unix(candles[len(candles)-1].Timestamp, 0))
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	offsets := make([]int64, len(sessions))
+	for i := 1; i < len(sessions); i++ {
+		offsets[i] = offsets[i-1] + int64(sessions[i-1].Duration()/time.Second)
+	}
+	return &sessionAxis{sessions: sessions, offsets: offsets}
+}
+
+// X returns trading seconds from first session open to ts,
+// timestamps after session close are moved to the close
+func (a *sessionAxis) X(ts int64) float64 {
+	i := sort.Search(len(a.sessions), func(i int) bool {
+		return a.sessions[i].Open.Unix() > ts
+	}) - 1
+	if i < 0 {
+		return float64(ts - a.sessions[0].Open.Unix())
+	}
+
+	offset := ts - a.sessions[i].Open.Unix()
+	if length := int64(a.sessions[i].Duration() / time.Second); offset > length {
+		offset = length
+	}
+	return float64(a.offsets[i] + offset)
+}
+
+// Time returns time of trading seconds x
+func (a *sessionAxis) Time(x float64) time.Time {
+	i := sort.Search(len(a.offsets), func(i int) bool {
+		return float64(a.offsets[i]) > x
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+	return a.sessions[i].Open.Add(time.Duration((x - float64(a.offsets[i])) * float64(time.Second)))
+}
+
+// session returns index of session overlapping candle [ts, ts+step), ok is false if there is none
+func (a *sessionAxis) session(ts, step int64) (int, bool) {
+	i := sort.Search(len(a.sessions), func(i int) bool {
+		return a.sessions[i].Close.Unix() > ts
+	})
+	if i == len(a.sessions) || a.sessions[i].Open.Unix() >= ts+step {
+		return 0, false
+	}
+	return i, true
+}
+
+// apply returns candles overlapping sessions with timestamps replaced by trading seconds,
+// candles started before open are moved to the open, pre-market and after-hours candles are dropped,
+// nil axis returns nil
+func (a *sessionAxis) apply(candles []ohlc.TOHLCV) []ohlc.TOHLCV {
+	if a == nil {
+		return nil
+	}
+	step := candleStep(candles)
+	result := make([]ohlc.TOHLCV, 0, len(candles))
+	for _, candle := range candles {
+		i, ok := a.session(candle.Timestamp, step)
+		if !ok {
+			continue
+		}
+		offset := candle.Timestamp - a.sessions[i].Open.Unix()
+		if offset < 0 {
+			offset = 0
+		}
+		candle.Timestamp = a.offsets[i] + offset
+		result = append(result, candle)
+	}
+	return result
+}
+
+// candleStep returns the least time between candles sorted by timestamp
+func candleStep(candles []ohlc.TOHLCV) int64 {
+	var step int64
+	for i := 1; i < len(candles); i++ {
+		if diff := candles[i].Timestamp - candles[i-1].Timestamp; diff > 0 && (step == 0 || diff < step) {
+			step = diff
+		}
+	}
+	if step == 0 {
+		return defaultCandleStep
+	}
+	return step
+}
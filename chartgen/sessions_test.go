@@ -0,0 +1,59 @@
+package chartgen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/ohlc"
+)
+
+func TestSessionAxisApply(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	cal := calendar.New("TEST", loc, 9*time.Hour+30*time.Minute, 16*time.Hour, nil)
+	// wednesday and thursday, hourly candles with pre-market and after-hours ones
+	var candles []ohlc.TOHLCV
+	for _, day := range []int{1, 2} {
+		for hour := 8; hour <= 17; hour++ {
+			ts := time.Date(2021, 12, day, hour, 0, 0, 0, loc).Unix()
+			candles = append(candles, ohlc.TOHLCV{Timestamp: ts})
+		}
+	}
+
+	axis := newSessionAxis(cal, candles)
+	if axis == nil {
+		t.Fatal("no axis")
+	}
+	got := axis.apply(candles)
+	// 9:00 candle is moved to open, 16:00 and 17:00 ones are after close
+	session := int64(6*60*60 + 30*60)
+	var want []int64
+	for _, open := range []int64{0, session} {
+		want = append(want, open, open+1800, open+5400, open+9000, open+12600, open+16200, open+19800)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d candles, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Timestamp != want[i] {
+			t.Errorf("candle %d at %d, want %d", i, got[i].Timestamp, want[i])
+		}
+		if i > 0 && got[i].Timestamp <= got[i-1].Timestamp {
+			t.Errorf("candle %d is not after previous one", i)
+		}
+	}
+}
+
+func TestSessionAxisNoSessions(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	cal := calendar.New("TEST", loc, 9*time.Hour+30*time.Minute, 16*time.Hour, nil)
+	saturday := time.Date(2021, 12, 4, 12, 0, 0, 0, loc).Unix()
+	axis := newSessionAxis(cal, []ohlc.TOHLCV{{Timestamp: saturday}})
+	if axis != nil {
+		t.Fatal("axis of weekend candles is not nil")
+	}
+	// chart keeps real time then
+	if candles := axis.apply([]ohlc.TOHLCV{{Timestamp: saturday}}); candles != nil {
+		t.Errorf("nil axis applied candles %v", candles)
+	}
+}
@@ -0,0 +1,372 @@
+package chartgen
+
+import (
+	"image"
+	"image/color"
+	// png logos
+	_ "image/png"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/font"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// Built-in theme names
+const (
+	ThemeLight    = "light"
+	ThemeDark     = "dark"
+	ThemeContrast = "contrast"
+)
+
+// Title layouts
+const (
+	TitleCenter = "center"
+	TitleLeft   = "left"
+)
+
+const (
+	volumeAlpha    = 128
+	watermarkAlpha = 40
+	titlePadding   = 8
+	// logoHeight height of logo in the corner of chart, width keeps image proportions
+	logoHeight = 32
+)
+
+// ErrBadTheme error for unknown theme
+var ErrBadTheme = errors.New("unknown theme")
+
+// Theme colours, fonts and branding of charts
+type Theme struct {
+	Name       string
+	Background color.Color
+	// Foreground colour of title, axes, labels and legend
+	Foreground color.Color
+
+	GrowColor    color.Color
+	FallColor    color.Color
+	DefaultColor color.Color
+	LineColor    color.Color
+	SMAColor     color.Color
+	EMAColor     color.Color
+	Grid         draw.LineStyle
+
+	// FontVariant variant of Liberation font: Sans, Serif or Mono
+	FontVariant font.Variant
+	FontSize    vg.Length
+	TitleSize   vg.Length
+	// TitleLayout TitleCenter or TitleLeft
+	TitleLayout string
+
+	// Watermark text drawn faintly across data area, empty means none
+	Watermark string
+	// Logo image drawn in the top right corner of data area, nil means none
+	Logo image.Image
+}
+
+var (
+	// LightTheme default theme
+	LightTheme = &Theme{
+		Name:         ThemeLight,
+		Background:   color.White,
+		Foreground:   color.Black,
+		GrowColor:    color.RGBA{R: 0, G: 198, B: 107, A: 255},
+		FallColor:    color.RGBA{R: 255, G: 98, B: 103, A: 255},
+		DefaultColor: color.Black,
+		LineColor:    color.RGBA{R: 33, G: 110, B: 214, A: 255},
+		SMAColor:     color.RGBA{R: 142, G: 68, B: 173, A: 255},
+		EMAColor:     color.RGBA{R: 243, G: 156, B: 18, A: 255},
+		Grid:         DefaultGridLineStyle,
+		FontVariant:  "Serif",
+		FontSize:     10,
+		TitleSize:    12,
+		TitleLayout:  TitleCenter,
+	}
+
+	// DarkTheme theme with dark background
+	DarkTheme = &Theme{
+		Name:         ThemeDark,
+		Background:   color.RGBA{R: 19, G: 23, B: 34, A: 255},
+		Foreground:   color.RGBA{R: 209, G: 212, B: 220, A: 255},
+		GrowColor:    color.RGBA{R: 38, G: 166, B: 154, A: 255},
+		FallColor:    color.RGBA{R: 239, G: 83, B: 80, A: 255},
+		DefaultColor: color.RGBA{R: 209, G: 212, B: 220, A: 255},
+		LineColor:    color.RGBA{R: 41, G: 98, B: 255, A: 255},
+		SMAColor:     color.RGBA{R: 186, G: 104, B: 200, A: 255},
+		EMAColor:     color.RGBA{R: 255, G: 183, B: 77, A: 255},
+		Grid: draw.LineStyle{
+			Color:    color.RGBA{R: 54, G: 58, B: 69, A: 255},
+			Width:    vg.Points(0.5),
+			Dashes:   []vg.Length{vg.Length(2)},
+			DashOffs: vg.Length(4),
+		},
+		FontVariant: "Sans",
+		FontSize:    10,
+		TitleSize:   12,
+		TitleLayout: TitleLeft,
+	}
+
+	// ContrastTheme high contrast theme with colour-blind friendly blue and orange candles
+	ContrastTheme = &Theme{
+		Name:         ThemeContrast,
+		Background:   color.White,
+		Foreground:   color.Black,
+		GrowColor:    color.RGBA{R: 0, G: 114, B: 178, A: 255},
+		FallColor:    color.RGBA{R: 230, G: 159, B: 0, A: 255},
+		DefaultColor: color.Black,
+		LineColor:    color.Black,
+		SMAColor:     color.RGBA{R: 204, G: 121, B: 167, A: 255},
+		EMAColor:     color.RGBA{R: 0, G: 158, B: 115, A: 255},
+		Grid: draw.LineStyle{
+			Color: color.Gray{96},
+			Width: vg.Points(0.75),
+		},
+		FontVariant: "Sans",
+		FontSize:    12,
+		TitleSize:   14,
+		TitleLayout: TitleCenter,
+	}
+)
+
+// Themes themes by name
+type Themes map[string]*Theme
+
+// DefaultThemes returns built-in themes
+func DefaultThemes() Themes {
+	return Themes{
+		ThemeLight:    LightTheme,
+		ThemeDark:     DarkTheme,
+		ThemeContrast: ContrastTheme,
+	}
+}
+
+// Get returns theme by name, empty name means light theme
+func (t Themes) Get(name string) (*Theme, error) {
+	if name == "" {
+		name = ThemeLight
+	}
+	theme, ok := t[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrBadTheme, "%q", name)
+	}
+	return theme, nil
+}
+
+// Names returns sorted theme names
+func (t Themes) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ThemeConfig custom theme, empty fields are taken from Base theme,
+// colours are "#rrggbb" or "#rrggbbaa"
+type ThemeConfig struct {
+	Name string `json:"Name" validate:"required"`
+	// Base built-in or previously defined theme, light by default
+	Base       string `json:"Base"`
+	Background string `json:"Background"`
+	Foreground string `json:"Foreground"`
+	Grow       string `json:"Grow"`
+	Fall       string `json:"Fall"`
+	Line       string `json:"Line"`
+	Grid       string `json:"Grid"`
+	// Font Sans, Serif or Mono
+	Font        string  `json:"Font"`
+	FontSize    float64 `json:"FontSize"`
+	TitleSize   float64 `json:"TitleSize"`
+	TitleLayout string  `json:"TitleLayout"`
+	Watermark   string  `json:"Watermark"`
+	// LogoFile path to png logo
+	LogoFile string `json:"LogoFile"`
+}
+
+// ThemesConfig file with custom themes
+type ThemesConfig struct {
+	Themes []ThemeConfig `json:"Themes"`
+}
+
+// NewThemes returns built-in themes and custom themes of conf
+func NewThemes(conf ThemesConfig) (Themes, error) {
+	themes := DefaultThemes()
+	for _, themeConfig := range conf.Themes {
+		base, err := themes.Get(themeConfig.Base)
+		if err != nil {
+			return nil, errors.Wrapf(err, "base of theme %q", themeConfig.Name)
+		}
+		theme, err := NewTheme(base, themeConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "theme %q", themeConfig.Name)
+		}
+		themes[theme.Name] = theme
+	}
+	return themes, nil
+}
+
+// NewTheme creates theme from base theme and config overrides
+func NewTheme(base *Theme, cfg ThemeConfig) (*Theme, error) {
+	theme := *base
+	theme.Name = cfg.Name
+
+	colors := []struct {
+		value string
+		dst   *color.Color
+	}{
+		{cfg.Background, &theme.Background},
+		{cfg.Foreground, &theme.Foreground},
+		{cfg.Grow, &theme.GrowColor},
+		{cfg.Fall, &theme.FallColor},
+		{cfg.Line, &theme.LineColor},
+		{cfg.Grid, &theme.Grid.Color},
+	}
+	for _, c := range colors {
+		if c.value == "" {
+			continue
+		}
+		parsed, err := parseColor(c.value)
+		if err != nil {
+			return nil, err
+		}
+		*c.dst = parsed
+	}
+
+	switch cfg.Font {
+	case "":
+	case "Sans", "Serif", "Mono":
+		theme.FontVariant = font.Variant(cfg.Font)
+	default:
+		return nil, errors.Errorf("unknown font %q", cfg.Font)
+	}
+	if cfg.FontSize > 0 {
+		theme.FontSize = vg.Length(cfg.FontSize)
+	}
+	if cfg.TitleSize > 0 {
+		theme.TitleSize = vg.Length(cfg.TitleSize)
+	}
+	switch cfg.TitleLayout {
+	case "":
+	case TitleCenter, TitleLeft:
+		theme.TitleLayout = cfg.TitleLayout
+	default:
+		return nil, errors.Errorf("unknown title layout %q", cfg.TitleLayout)
+	}
+	if cfg.Watermark != "" {
+		theme.Watermark = cfg.Watermark
+	}
+	if cfg.LogoFile != "" {
+		logo, err := loadImage(cfg.LogoFile)
+		if err != nil {
+			return nil, err
+		}
+		theme.Logo = logo
+	}
+	return &theme, nil
+}
+
+// parseColor parses "#rrggbb" or "#rrggbbaa" colour
+func parseColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return nil, errors.Errorf("bad colour %q", s)
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, errors.Errorf("bad colour %q", s)
+	}
+	return color.NRGBA{R: uint8(value >> 24), G: uint8(value >> 16), B: uint8(value >> 8), A: uint8(value)}, nil
+}
+
+func loadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not open logo")
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not decode logo %s", path)
+	}
+	return img, nil
+}
+
+// withAlpha returns colour c with opacity alpha
+func withAlpha(c color.Color, alpha uint8) color.Color {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	nrgba.A = alpha
+	return nrgba
+}
+
+// font returns theme font of size
+func (t *Theme) font(size vg.Length) font.Font {
+	f := plot.DefaultFont
+	f.Variant = t.FontVariant
+	return font.From(f, size)
+}
+
+// apply sets background, colours and fonts of plot, title is laid out for canvas of width
+func (t *Theme) apply(p *plot.Plot, width vg.Length) {
+	p.BackgroundColor = t.Background
+
+	p.Title.TextStyle.Color = t.Foreground
+	p.Title.TextStyle.Font = t.font(t.TitleSize)
+	p.Title.Padding = titlePadding
+	if t.TitleLayout == TitleLeft && p.Title.Text != "" {
+		// plot draws title at canvas center, alignment is relative to title width
+		if titleWidth := p.Title.TextStyle.Width(p.Title.Text); titleWidth > 0 {
+			p.Title.TextStyle.XAlign = draw.XAlignment((titlePadding - width/2) / titleWidth)
+		}
+	}
+
+	for _, axis := range []*plot.Axis{&p.X, &p.Y} {
+		axis.Color = t.Foreground
+		axis.Label.TextStyle.Color = t.Foreground
+		axis.Label.TextStyle.Font = t.font(t.FontSize + 2)
+		axis.Tick.Color = t.Foreground
+		axis.Tick.Label.Color = t.Foreground
+		axis.Tick.Label.Font = t.font(t.FontSize)
+	}
+
+	p.Legend.TextStyle.Color = t.Foreground
+	p.Legend.TextStyle.Font = t.font(t.FontSize + 2)
+}
+
+// brandingPlotter draws watermark and logo of theme over data area
+type brandingPlotter struct {
+	theme *Theme
+}
+
+// Plot implements the Plot method of the plot.Plotter interface.
+func (b *brandingPlotter) Plot(c draw.Canvas, plt *plot.Plot) {
+	if b.theme.Watermark != "" {
+		style := plt.Title.TextStyle
+		style.Color = withAlpha(b.theme.Foreground, watermarkAlpha)
+		style.Font = b.theme.font(c.Size().Y / 6)
+		style.XAlign = draw.XCenter
+		style.YAlign = draw.YCenter
+		c.FillText(style, c.Center(), b.theme.Watermark)
+	}
+
+	if b.theme.Logo != nil {
+		bounds := b.theme.Logo.Bounds()
+		if bounds.Dy() == 0 {
+			return
+		}
+		width := logoHeight * vg.Length(bounds.Dx()) / vg.Length(bounds.Dy())
+		c.DrawImage(vg.Rectangle{
+			Min: vg.Point{X: c.Max.X - width - titlePadding, Y: c.Max.Y - logoHeight - titlePadding},
+			Max: vg.Point{X: c.Max.X - titlePadding, Y: c.Max.Y - titlePadding},
+		}, b.theme.Logo)
+	}
+}
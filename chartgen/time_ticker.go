@@ -1,11 +1,157 @@
 package chartgen
 
-import "gonum.org/v1/plot"
+import (
+	"time"
 
-// TimeTicker helps draw time ticks
+	"gonum.org/v1/plot"
+)
+
+const (
+	defaultTimeLabels = 8
+
+	minuteLabelFormat = "15:04"
+	dateLabelFormat   = "02 Jan"
+	yearLabelFormat   = "2006"
+)
+
+// timeUnit unit of time ticker step
+type timeUnit int
+
+const (
+	unitMinute timeUnit = iota
+	unitDay
+	unitWeek
+	unitMonth
+	unitYear
+)
+
+// timeStep step between time ticks
+type timeStep struct {
+	Unit  timeUnit
+	Count int
+	// Approx approximate step length used to choose step
+	Approx time.Duration
+}
+
+const (
+	approxDay   = 24 * time.Hour
+	approxMonth = 30 * approxDay
+	approxYear  = 365 * approxDay
+)
+
+// timeSteps nice steps from finest to coarsest
+var timeSteps = []timeStep{
+	{unitMinute, 1, time.Minute},
+	{unitMinute, 2, 2 * time.Minute},
+	{unitMinute, 5, 5 * time.Minute},
+	{unitMinute, 10, 10 * time.Minute},
+	{unitMinute, 15, 15 * time.Minute},
+	{unitMinute, 30, 30 * time.Minute},
+	{unitMinute, 60, time.Hour},
+	{unitMinute, 2 * 60, 2 * time.Hour},
+	{unitMinute, 3 * 60, 3 * time.Hour},
+	{unitMinute, 6 * 60, 6 * time.Hour},
+	{unitMinute, 12 * 60, 12 * time.Hour},
+	{unitDay, 1, approxDay},
+	{unitDay, 2, 2 * approxDay},
+	{unitWeek, 1, 7 * approxDay},
+	{unitWeek, 2, 14 * approxDay},
+	{unitMonth, 1, approxMonth},
+	{unitMonth, 3, 3 * approxMonth},
+	{unitMonth, 6, 6 * approxMonth},
+	{unitYear, 1, approxYear},
+	{unitYear, 2, 2 * approxYear},
+	{unitYear, 5, 5 * approxYear},
+	{unitYear, 10, 10 * approxYear},
+}
+
+// chooseTimeStep returns the finest step giving at most want labels over span
+func chooseTimeStep(span time.Duration, want int) timeStep {
+	for _, step := range timeSteps {
+		if span/step.Approx < time.Duration(want) {
+			return step
+		}
+	}
+	return timeSteps[len(timeSteps)-1]
+}
+
+// floor returns the latest step boundary at or before t
+func (s timeStep) floor(t time.Time) time.Time {
+	year, month, day := t.Date()
+	loc := t.Location()
+	switch s.Unit {
+	case unitMinute:
+		midnight := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		minutes := int(t.Sub(midnight) / time.Minute)
+		return midnight.Add(time.Duration(minutes-minutes%s.Count) * time.Minute)
+	case unitDay:
+		return time.Date(year, month, day-(day-1)%s.Count, 0, 0, 0, 0, loc)
+	case unitWeek:
+		// weeks start on monday
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case unitMonth:
+		return time.Date(year, month-(month-1)%time.Month(s.Count), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(year-year%s.Count, time.January, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// next returns the step boundary after boundary t
+func (s timeStep) next(t time.Time) time.Time {
+	switch s.Unit {
+	case unitMinute:
+		return t.Add(time.Duration(s.Count) * time.Minute)
+	case unitDay:
+		next := t.AddDate(0, 0, s.Count)
+		if next.Month() != t.Month() {
+			// day steps restart from the first day of month
+			return time.Date(next.Year(), next.Month(), 1, 0, 0, 0, 0, t.Location())
+		}
+		return next
+	case unitWeek:
+		return t.AddDate(0, 0, 7*s.Count)
+	case unitMonth:
+		return t.AddDate(0, s.Count, 0)
+	default:
+		return t.AddDate(s.Count, 0, 0)
+	}
+}
+
+// label returns tick label of t, prev is time of previous label,
+// higher parts of time (date, year) are added when they change
+func (s timeStep) label(t, prev time.Time, first bool) string {
+	newDay := first || t.YearDay() != prev.YearDay() || t.Year() != prev.Year()
+	newYear := first || t.Year() != prev.Year()
+	switch s.Unit {
+	case unitMinute:
+		if newDay {
+			return t.Format(minuteLabelFormat + "\n" + dateLabelFormat)
+		}
+		return t.Format(minuteLabelFormat)
+	case unitDay, unitWeek:
+		if newYear {
+			return t.Format(dateLabelFormat + "\n" + yearLabelFormat)
+		}
+		return t.Format(dateLabelFormat)
+	case unitMonth:
+		if newYear {
+			return t.Format(monthLabelFormat)
+		}
+		return t.Format("Jan")
+	default:
+		return t.Format(yearLabelFormat)
+	}
+}
+
+// TimeTicker ticks of time X axis at nice steps chosen by range,
+// labels show date when day changes and year when year changes
 type TimeTicker struct {
-	Delta        int64
-	BetweenCount int
+	WantLabels int
+	// Location timezone of labels, nil means Europe/Moscow
+	Location *time.Location
+	// Time and X convert axis values to time and back, nil means unix seconds
+	Time func(x float64) time.Time
+	X    func(t time.Time) float64
 }
 
 // Ticks returns Ticks in the specified range.
@@ -14,17 +160,37 @@ func (t *TimeTicker) Ticks(min, max float64) []plot.Tick {
 		panic("illegal range")
 	}
 
-	minTS, maxTS := int64(min), int64(max)
-	nearestLeftStart := minTS - (minTS % t.Delta)
+	want := t.WantLabels
+	if want <= 0 {
+		want = defaultTimeLabels
+	}
+	loc := t.Location
+	if loc == nil {
+		loc = defaultTimezone
+	}
+	timeOf, xOf := t.Time, t.X
+	if timeOf == nil || xOf == nil {
+		timeOf = func(x float64) time.Time { return time.Unix(int64(x), 0) }
+		xOf = func(t time.Time) float64 { return float64(t.Unix()) }
+	}
 
-	ticks := make([]plot.Tick, 0)
-	for i := nearestLeftStart; i < maxTS; i += t.Delta {
-		ticks = append(ticks, plot.Tick{Value: float64(i), Label: "fill_me"})
+	// axis values are seconds, compressed axis has less time than real range
+	step := chooseTimeStep(time.Duration(max-min)*time.Second, want)
+	end := timeOf(max)
 
-		miniDelta := float64(t.Delta) / float64(t.BetweenCount+1)
-		for j := 1; j <= t.BetweenCount; j++ {
-			ticks = append(ticks, plot.Tick{Value: float64(i) + float64(j)*miniDelta, Label: ""})
+	var ticks []plot.Tick
+	var prev time.Time
+	lastX := min
+	for ts := step.floor(timeOf(min).In(loc)); !ts.After(end); ts = step.next(ts) {
+		x := xOf(ts)
+		// ticks out of range or moved to the same place by compressed axis are skipped,
+		// intraday ticks are kept only at times present on axis
+		if x < min || x > max || (len(ticks) > 0 && x <= lastX) ||
+			(step.Unit == unitMinute && !timeOf(x).Equal(ts)) {
+			continue
 		}
+		ticks = append(ticks, plot.Tick{Value: x, Label: step.label(ts, prev, len(ticks) == 0)})
+		prev, lastX = ts, x
 	}
 	return ticks
 }
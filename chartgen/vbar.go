@@ -13,21 +13,27 @@ import (
 
 // volumePlotterOptions options for VbarPlotter
 type volumePlotterOptions struct {
+	// HeightRatio part of canvas height used by volume bars
+	HeightRatio float64
+
 	GrowColor    color.Color
 	FallColor    color.Color
 	DefaultColor color.Color
 }
 
 // NewVolumePlotterOptions returns VolumePlotterOptions
-// with default config
-func newVolumePlotterOptions() *volumePlotterOptions {
+// with default config and translucent theme colours
+func newVolumePlotterOptions(theme *Theme) *volumePlotterOptions {
 	return &volumePlotterOptions{
-		GrowColor:    color.RGBA{R: 0, G: 198, B: 107, A: 255},
-		FallColor:    color.RGBA{R: 255, G: 98, B: 103, A: 255},
-		DefaultColor: color.Black,
+		HeightRatio:  0.2,
+		GrowColor:    withAlpha(theme.GrowColor, volumeAlpha),
+		FallColor:    withAlpha(theme.FallColor, volumeAlpha),
+		DefaultColor: withAlpha(theme.DefaultColor, volumeAlpha),
 	}
 }
 
+// volumePlotter draws volume bars at the bottom of the canvas
+// with own scale, so it does not affect plot Y axis
 type volumePlotter struct {
 	tohlcvs []ohlc.TOHLCV
 	options *volumePlotterOptions
@@ -64,8 +70,16 @@ func newVolumePlotter(data []ohlc.TOHLCV, opt *volumePlotterOptions) *volumePlot
 
 // Plot implements the Plot method of the plot.Plotter interface.
 func (p *volumePlotter) Plot(c draw.Canvas, plt *plot.Plot) {
-	trX, trY := plt.Transforms(&c)
+	if p.maxY <= 0 {
+		return
+	}
+	trX, _ := plt.Transforms(&c)
+	height := float64(c.Max.Y-c.Min.Y) * p.options.HeightRatio
+	trY := func(volume float64) vg.Length {
+		return c.Min.Y + vg.Length(volume/p.maxY*height)
+	}
 
+	barWidth := candleWidth(c, trX, p.tohlcvs)
 	for _, tohlcv := range p.tohlcvs {
 		tsX := trX(float64(tohlcv.Timestamp))
 		barStartY := trY(0)
@@ -79,15 +93,10 @@ func (p *volumePlotter) Plot(c draw.Canvas, plt *plot.Plot) {
 			c.SetColor(p.options.DefaultColor)
 		}
 
-		var bar vg.Path
-		bar.Move(vg.Point{X: tsX, Y: barStartY})
-		bar.Line(vg.Point{X: tsX, Y: barEndY})
-		bar.Close()
-		c.Stroke(bar)
+		bar := vg.Rectangle{
+			Min: vg.Point{X: tsX - barWidth/2., Y: barStartY},
+			Max: vg.Point{X: tsX + barWidth/2., Y: barEndY},
+		}
+		c.Fill(bar.Path())
 	}
 }
-
-// DataRange implements the DataRange method of the plot.DataRanger interface.
-func (p *volumePlotter) DataRange() (xmin, xmax, ymin, ymax float64) {
-	return p.minX, p.maxX, p.minY, p.maxY
-}
@@ -1,27 +1,146 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
 )
 
-func GetConfig(args []string, conf interface{}) {
-	if len(args) < 2 {
-		fmt.Println("no config provided")
-		os.Exit(1)
+// Loader loads config from optional file, environment variables
+// and validates the result.
+//
+// Every exported field can be overridden by environment variable
+// EnvPrefix_FIELD_NAME (nested structs add their own name part),
+// or by EnvPrefix_FIELD_NAME_FILE which points to file with value,
+// e.g. docker secret.
+type Loader struct {
+	Path      string
+	EnvPrefix string
+}
+
+// NewLoader creates loader with config path from args[1]
+// or from EnvPrefix_CONFIG environment variable.
+func NewLoader(args []string, envPrefix string) *Loader {
+	path := os.Getenv(envName(envPrefix, "CONFIG"))
+	if len(args) >= 2 {
+		path = args[1]
 	}
 
-	confBytes, err := ioutil.ReadFile(os.Args[1])
-	if err != nil {
-		fmt.Println("cant read config file", err)
-		os.Exit(1)
+	return &Loader{
+		Path:      path,
+		EnvPrefix: envPrefix,
+	}
+}
+
+// GetConfig loads config using path from args[1]
+func GetConfig(args []string, envPrefix string, conf interface{}) error {
+	return NewLoader(args, envPrefix).Load(conf)
+}
+
+// Load fills conf from file and environment and validates it
+func (l *Loader) Load(conf interface{}) error {
+	if l.Path != "" {
+		if err := loadFile(l.Path, conf); err != nil {
+			return err
+		}
+	}
+
+	if err := applyEnv(conf, l.EnvPrefix); err != nil {
+		return errors.Wrap(err, "can not apply environment")
+	}
+
+	return Validate(conf)
+}
+
+// OnReload calls reload on every SIGHUP until ctx is done
+func (l *Loader) OnReload(ctx context.Context, reload func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+				reload()
+			}
+		}
+	}()
+}
+
+// ChangedFields returns json names of top-level fields which differ in old and new config,
+// fields named in ignore are skipped
+func ChangedFields(old, new interface{}, ignore ...string) []string {
+	oldValue, newValue := reflect.Indirect(reflect.ValueOf(old)), reflect.Indirect(reflect.ValueOf(new))
+	var changed []string
+fields:
+	for i := 0; i < oldValue.NumField(); i++ {
+		name, ok := fieldName(oldValue.Type().Field(i))
+		if !ok {
+			continue
+		}
+		for _, ignored := range ignore {
+			if name == ignored {
+				continue fields
+			}
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
 	}
+	return changed
+}
 
-	err = json.Unmarshal(confBytes, &conf)
+func loadFile(path string, conf interface{}) error {
+	confBytes, err := ioutil.ReadFile(path)
 	if err != nil {
-		fmt.Println("cant unmarshal config file", err)
-		os.Exit(1)
+		return errors.Wrap(err, "can not read config file")
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		value, err := parseYAML(confBytes)
+		if err != nil {
+			return errors.Wrapf(err, "can not parse yaml config %s", path)
+		}
+		// yaml is converted to json so both formats share
+		// the same field names and decoding rules
+		confBytes, err = json.Marshal(value)
+		if err != nil {
+			return errors.Wrapf(err, "can not convert yaml config %s", path)
+		}
+	}
+
+	if err := decodeJSON(confBytes, conf); err != nil {
+		return errors.Wrapf(err, "can not decode config %s", path)
+	}
+	return nil
+}
+
+func decodeJSON(data []byte, conf interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(conf)
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line := 1 + bytes.Count(data[:syntaxErr.Offset], []byte("\n"))
+		return errors.Errorf("line %d: %s", line, syntaxErr.Error())
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return errors.Errorf("field %s: can not use %s as %s", typeErr.Field, typeErr.Value, typeErr.Type)
 	}
+	return err
 }
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const fileEnvSuffix = "_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func applyEnv(conf interface{}, prefix string) error {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config must be pointer to struct")
+	}
+	return applyEnvStruct(v.Elem(), prefix)
+}
+
+func applyEnvStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		fieldEnv := envName(prefix, name)
+		fieldValue := v.Field(i)
+
+		if fieldValue.Kind() == reflect.Struct {
+			if err := applyEnvStruct(fieldValue, fieldEnv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok, err := lookupEnv(fieldEnv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := setValue(fieldValue, value); err != nil {
+			return errors.Wrapf(err, "%s", fieldEnv)
+		}
+	}
+
+	return nil
+}
+
+// lookupEnv returns value of NAME or contents of file from NAME_FILE
+func lookupEnv(name string) (string, bool, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, nil
+	}
+
+	path, ok := os.LookupEnv(name + fileEnvSuffix)
+	if !ok {
+		return "", false, nil
+	}
+	valueBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "can not read %s%s", name, fileEnvSuffix)
+	}
+	return strings.TrimRight(string(valueBytes), "\r\n"), true, nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct || v.Type().Elem().Kind() == reflect.Ptr {
+			return errors.Errorf("can not set %s from environment", v.Type())
+		}
+		parts := strings.Split(value, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return errors.Errorf("can not set %s from environment", v.Type())
+	}
+
+	return nil
+}
+
+// fieldName returns json name of exported field
+func fieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	name := field.Name
+	if tag, ok := field.Tag.Lookup("json"); ok {
+		tagName := strings.Split(tag, ",")[0]
+		if tagName == "-" {
+			return "", false
+		}
+		if tagName != "" {
+			name = tagName
+		}
+	}
+	return name, true
+}
+
+// envName builds PREFIX_FIELD_NAME from PREFIX and FieldName
+func envName(prefix, name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	if prefix == "" {
+		return b.String()
+	}
+	return prefix + "_" + b.String()
+}
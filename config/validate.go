@@ -0,0 +1,85 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Validator is implemented by config parts with own checks
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError lists all config problems
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks `validate:"required"` fields and calls Validate
+// of every struct implementing Validator.
+func Validate(conf interface{}) error {
+	validationErr := &ValidationError{}
+	validateValue(reflect.ValueOf(conf), "", validationErr)
+	if len(validationErr.Problems) > 0 {
+		return validationErr
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, path string, validationErr *ValidationError) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	value := v.Interface()
+	if v.CanAddr() {
+		value = v.Addr().Interface()
+	}
+	if validator, ok := value.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			validationErr.Problems = append(validationErr.Problems, joinPath(path, err.Error()))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+
+			fieldValue := v.Field(i)
+			if field.Tag.Get("validate") == "required" && fieldValue.IsZero() {
+				validationErr.Problems = append(validationErr.Problems, fieldPath+" is required")
+				continue
+			}
+			validateValue(fieldValue, fieldPath, validationErr)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), path+"["+strconv.Itoa(i)+"]", validationErr)
+		}
+	}
+}
+
+func joinPath(path, problem string) string {
+	if path == "" {
+		return problem
+	}
+	return path + ": " + problem
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// yamlLine is a meaningful line of yaml document
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// yamlParser parses subset of yaml used by configs: block mappings,
+// block sequences, flow sequences of scalars, quoted and plain scalars.
+// Flow mappings, block scalars, anchors and tags are rejected.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, errors.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(raw) - len(text), text: text})
+	}
+
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, err := p.parseNode(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, errors.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, errors.Errorf("line %d: unexpected indentation", line.num)
+		}
+		if isYAMLSeqItem(line.text) {
+			return nil, errors.Errorf("line %d: unexpected sequence item", line.num)
+		}
+
+		key, rest, ok := splitYAMLKeyValue(line.text)
+		if !ok {
+			return nil, errors.Errorf("line %d: expected 'key: value'", line.num)
+		}
+		if _, exists := m[key]; exists {
+			return nil, errors.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", line.num)
+			}
+			m[key] = value
+			continue
+		}
+
+		m[key] = nil
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			// sequences are allowed on the same indentation as parent key
+			if next.indent > indent || (next.indent == indent && isYAMLSeqItem(next.text)) {
+				value, err := p.parseNode(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = value
+			}
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseSeq(indent int) (interface{}, error) {
+	seq := make([]interface{}, 0)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLSeqItem(line.text) {
+			if line.indent > indent {
+				return nil, errors.Errorf("line %d: unexpected indentation", line.num)
+			}
+			break
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			var value interface{}
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				var err error
+				value, err = p.parseNode(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+			}
+			seq = append(seq, value)
+			continue
+		}
+
+		if _, _, ok := splitYAMLKeyValue(rest); ok || isYAMLSeqItem(rest) {
+			// "- key: value" starts nested node, which continues
+			// on the following lines with the same indentation as key
+			p.lines[p.pos] = yamlLine{
+				num:    line.num,
+				indent: indent + len(line.text) - len(rest),
+				text:   rest,
+			}
+			value, err := p.parseNode(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, value)
+			continue
+		}
+
+		value, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", line.num)
+		}
+		seq = append(seq, value)
+		p.pos++
+	}
+	return seq, nil
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKeyValue splits "key: value" outside of quotes
+func splitYAMLKeyValue(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return "", "", false
+	}
+
+	quote := byte(0)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			key := strings.TrimSpace(text[:i])
+			if unquoted, err := unquoteYAML(key); err == nil {
+				key = unquoted
+			}
+			return key, strings.TrimSpace(text[i+1:]), key != ""
+		}
+	}
+	return "", "", false
+}
+
+func parseYAMLScalar(text string) (interface{}, error) {
+	if strings.HasPrefix(text, "[") {
+		if !strings.HasSuffix(text, "]") {
+			return nil, errors.New("unterminated flow sequence")
+		}
+		seq := make([]interface{}, 0)
+		body := strings.TrimSpace(text[1 : len(text)-1])
+		if body == "" {
+			return seq, nil
+		}
+		for _, part := range splitYAMLFlow(body) {
+			value, err := parseYAMLScalar(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, value)
+		}
+		return seq, nil
+	}
+	if text == "{}" {
+		return map[string]interface{}{}, nil
+	}
+
+	switch {
+	case text == "":
+		return nil, errors.New("empty flow sequence item")
+	case strings.HasPrefix(text, "{"):
+		return nil, errors.New("flow mappings are not supported")
+	case strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		return nil, errors.New("block scalars are not supported")
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") || strings.HasPrefix(text, "!"):
+		return nil, errors.New("anchors, aliases and tags are not supported")
+	case strings.Contains(text, ": ") && !strings.HasPrefix(text, "\"") && !strings.HasPrefix(text, "'"):
+		return nil, errors.Errorf("plain value %q contains ': ', quote it", text)
+	}
+
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		return unquoteYAML(text)
+	}
+
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
+
+func unquoteYAML(text string) (string, error) {
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		return strconv.Unquote(text)
+	}
+	return "", errors.Errorf("bad quoted string %s", text)
+}
+
+// splitYAMLFlow splits flow sequence body by commas outside of quotes
+func splitYAMLFlow(body string) []string {
+	var parts []string
+	quote := byte(0)
+	start := 0
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, body[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, body[start:])
+}
+
+// stripYAMLComment removes " # comment" outside of quotes
+func stripYAMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '[' || line[i-1] == ',' {
+				quote = c
+			}
+		case c == '#':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
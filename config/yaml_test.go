@@ -0,0 +1,140 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type m = map[string]interface{}
+type s = []interface{}
+
+func TestParseYAML(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		yaml string
+		want interface{}
+	}{
+		{"empty", "", m{}},
+		{"comments only", "# comment\n\n---\n", m{}},
+		{"scalars", "a: 1\nb: text\nc: 1.5\nd: true\ne: null\nf: ~\ng: -2\nh: FALSE",
+			m{"a": int64(1), "b": "text", "c": 1.5, "d": true, "e": nil, "f": nil, "g": int64(-2), "h": false}},
+		{"empty value", "a:\nb: 1", m{"a": nil, "b": int64(1)}},
+		{"comments", "# head\na: 1 # tail\nb: 'x # y'\nc: a#b\nd: \"#\"", m{"a": int64(1), "b": "x # y", "c": "a#b", "d": "#"}},
+		{"quoted", "a: \"x: y\"\nb: 'it''s'\n\"c d\": e\nf: \"tab\\t\"\ng: '1'",
+			m{"a": "x: y", "b": "it's", "c d": "e", "f": "tab\t", "g": "1"}},
+		{"url", "a: http://host:8080/path", m{"a": "http://host:8080/path"}},
+		{"document start", "---\na: 1", m{"a": int64(1)}},
+		{"crlf", "a: 1\r\nb: 2\r\n", m{"a": int64(1), "b": int64(2)}},
+		{"indented document", "  a: 1\n  b: 2", m{"a": int64(1), "b": int64(2)}},
+		{"nested maps", "a:\n  b:\n    c: 1\n  d: 2\ne: 3", m{"a": m{"b": m{"c": int64(1)}, "d": int64(2)}, "e": int64(3)}},
+		{"sequence on key indent", "a:\n- 1\n- x\nb: 2", m{"a": s{int64(1), "x"}, "b": int64(2)}},
+		{"indented sequence", "a:\n  - 1\n  - x", m{"a": s{int64(1), "x"}}},
+		{"sequence of maps", "a:\n  - name: x\n    v: 1\n  - name: y", m{"a": s{m{"name": "x", "v": int64(1)}, m{"name": "y"}}}},
+		{"map on next line of item", "- \n  a: 1\n-\n- b", s{m{"a": int64(1)}, nil, "b"}},
+		{"nested sequences", "- - 1\n  - 2\n- 3", s{s{int64(1), int64(2)}, int64(3)}},
+		{"top level sequence", "- a\n- b", s{"a", "b"}},
+		{"flow sequences", "a: [1, \"b, c\", 'd', x]\nb: []\nc: {}", m{"a": s{int64(1), "b, c", "d", "x"}, "b": s{}, "c": m{}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tc.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseYAML = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		yaml string
+	}{
+		{"tab indentation", "a:\n\tb: 1"},
+		{"unexpected indentation", "a: 1\n  b: 2"},
+		{"dedent to unknown level", "a:\n  b: 1\n c: 2"},
+		{"indented sequence item", "a:\n  - 1\n    - 2"},
+		{"duplicate key", "a: 1\na: 2"},
+		{"sequence item in map", "a: 1\n- b"},
+		{"map key in sequence", "- a\nb: 1"},
+		{"no key", "just text"},
+		{"unterminated flow sequence", "a: [1, 2"},
+		{"empty flow item", "a: [1, , 2]"},
+		{"unterminated quote", "a: \"text"},
+		{"bad escape", "a: \"\\q\""},
+		{"flow mapping", "a: {b: 1}"},
+		{"literal block scalar", "a: |\n  text"},
+		{"folded block scalar", "a: >"},
+		{"anchor", "a: &x 1"},
+		{"alias", "a: *x"},
+		{"tag", "a: !!str 1"},
+		{"nested mapping value", "a: b: c"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, err := parseYAML([]byte(tc.yaml)); err == nil {
+				t.Errorf("no error, parsed %#v", got)
+			}
+		})
+	}
+}
+
+func TestLoadYAMLFile(t *testing.T) {
+	type nested struct {
+		Rate  float64  `json:"Rate"`
+		Names []string `json:"Names"`
+	}
+	var conf struct {
+		Host   string `json:"Host"`
+		Port   int    `json:"Port"`
+		Nested nested `json:"Nested"`
+	}
+	path := filepath.Join(t.TempDir(), "conf.yml")
+	yaml := "Host: localhost\nPort: 8080\nNested:\n  Rate: 0.5\n  Names: [a, b]\n"
+	if err := ioutil.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadFile(path, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Host != "localhost" || conf.Port != 8080 || conf.Nested.Rate != 0.5 || !reflect.DeepEqual(conf.Nested.Names, []string{"a", "b"}) {
+		t.Errorf("loaded %+v", conf)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("Unknown: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadFile(path, &conf); err == nil {
+		t.Error("no error for unknown field")
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	type conf struct {
+		Token    string   `json:"Token"`
+		Featured string   `json:"FeaturedFile"`
+		Names    []string `json:"Names"`
+		hidden   int
+	}
+	old := conf{Token: "a", Featured: "f.json", Names: []string{"x"}, hidden: 1}
+	if changed := ChangedFields(old, old); len(changed) != 0 {
+		t.Errorf("changed fields of equal configs %v", changed)
+	}
+	new := conf{Token: "b", Featured: "g.json", Names: []string{"x"}, hidden: 2}
+	if changed := ChangedFields(old, &new, "FeaturedFile"); !reflect.DeepEqual(changed, []string{"Token"}) {
+		t.Errorf("changed fields %v, want [Token]", changed)
+	}
+}
+
+func TestParseExampleYAML(t *testing.T) {
+	data, err := ioutil.ReadFile("../configs_example/bot.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseYAML(data); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,281 @@
+package i18n
+
+// Message keys
+const (
+	KeyCaptionVerdict   Key = "caption.verdict"
+	KeyCaptionNegative  Key = "caption.negative"
+	KeyGradeNeutral     Key = "grade.neutral"
+	KeyGradeGood        Key = "grade.good"
+	KeyGradeGreat       Key = "grade.great"
+	KeyGradeOutstanding Key = "grade.outstanding"
+	KeyGradeFantastic   Key = "grade.fantastic"
+
+	KeyHelpHeader  Key = "help.header"
+	KeyHelpCommand Key = "help.command"
+	KeyHelpFooter  Key = "help.footer"
+
+	KeyLangCurrent Key = "lang.current"
+	KeyLangChanged Key = "lang.changed"
+	KeyLangUnknown Key = "lang.unknown"
+
+	KeyThemeCurrent Key = "theme.current"
+	KeyThemeChanged Key = "theme.changed"
+	KeyThemeUnknown Key = "theme.unknown"
+
+	KeyChartError      Key = "error.chart"
+	KeyUnknownAction   Key = "error.unknown_action"
+	KeyUnknownCommand  Key = "error.unknown_command"
+	KeyTooManyRequests Key = "error.too_many_requests"
+
+	KeyButtonCandles        Key = "button.candles"
+	KeyButtonLine           Key = "button.line"
+	KeyButtonSMA            Key = "button.sma"
+	KeyButtonEMA            Key = "button.ema"
+	KeyButtonVolume         Key = "button.volume"
+	KeyButtonNativeCurrency Key = "button.native_currency"
+
+	KeyQuoteTitle         Key = "quote.title"
+	KeyQuoteChange        Key = "quote.change"
+	KeyQuoteNoPrevClose   Key = "quote.no_prev_close"
+	KeyQuoteDayRange      Key = "quote.day_range"
+	KeyQuoteVolume        Key = "quote.volume"
+	KeyQuoteVolumeAverage Key = "quote.volume_average"
+	KeyQuoteYearRange     Key = "quote.year_range"
+	KeyQuoteMarketClosed  Key = "quote.market_closed"
+	KeyQuoteMarketOpens   Key = "quote.market_opens"
+	KeyQuoteNoData        Key = "quote.no_data"
+	KeyQuoteUsage         Key = "quote.usage"
+	KeyQuoteUnknownTicker Key = "quote.unknown_ticker"
+	KeyQuoteError         Key = "quote.error"
+
+	KeyBookTitle      Key = "book.title"
+	KeyBookNotTrading Key = "book.not_trading"
+	KeyBookSpread     Key = "book.spread"
+	KeyBookOneSided   Key = "book.one_sided"
+	KeyBookLots       Key = "book.lots"
+	KeyBookEmpty      Key = "book.empty"
+	KeyBookUsage      Key = "book.usage"
+	KeyBookError      Key = "book.error"
+
+	KeySearchFound    Key = "search.found"
+	KeySearchResult   Key = "search.result"
+	KeySearchNotFound Key = "search.not_found"
+	KeySearchUsage    Key = "search.usage"
+
+	KeyTypeStock    Key = "type.stock"
+	KeyTypeETF      Key = "type.etf"
+	KeyTypeBond     Key = "type.bond"
+	KeyTypeCurrency Key = "type.currency"
+
+	KeyTradeUsage     Key = "trade.usage"
+	KeyTradeBought    Key = "trade.bought"
+	KeyTradeSold      Key = "trade.sold"
+	KeyTradePosition  Key = "trade.position"
+	KeyTradeClosed    Key = "trade.closed"
+	KeyTradeNotEnough Key = "trade.not_enough"
+	KeyTradeError     Key = "trade.error"
+
+	KeyPortfolioEmpty    Key = "portfolio.empty"
+	KeyPortfolioTitle    Key = "portfolio.title"
+	KeyPortfolioPosition Key = "portfolio.position"
+	KeyPortfolioNoPrice  Key = "portfolio.no_price"
+	KeyPortfolioTotal    Key = "portfolio.total"
+	KeyPortfolioRealized Key = "portfolio.realized"
+	KeyPortfolioError    Key = "portfolio.error"
+	KeyEquityTitle       Key = "equity.title"
+	KeyEquityValue       Key = "equity.value"
+	KeyEquityCost        Key = "equity.cost"
+)
+
+var ruMessages = map[Key]string{
+	KeyCaptionVerdict:   "Какой%s %s результат!",
+	KeyCaptionNegative:  " отрицательно",
+	KeyGradeNeutral:     "нейтральный",
+	KeyGradeGood:        "хороший",
+	KeyGradeGreat:       "прекрасный",
+	KeyGradeOutstanding: "выдающийся",
+	KeyGradeFantastic:   "фантастический",
+
+	KeyHelpCommand: "/%s — %s (%s)\n",
+	KeyHelpFooter:  "\n/quote ТИКЕР показывает котировку\n/book ТИКЕР показывает стакан\n/search НАЗВАНИЕ ищет инструмент\n/buy, /sell и /portfolio ведут учебный портфель\n/start или /help выводит это сообщение\n/lang меняет язык\n/theme меняет оформление графиков\n",
+
+	KeyLangCurrent: "Текущий язык: %s. Доступные языки: %s, например /lang en",
+	KeyLangChanged: "Язык переключен: %s",
+	KeyLangUnknown: "Неизвестный язык %q. Доступные языки: %s",
+
+	KeyThemeCurrent: "Текущая тема графиков: %s. Доступные темы: %s, например /theme dark",
+	KeyThemeChanged: "Тема графиков переключена: %s",
+	KeyThemeUnknown: "Неизвестная тема %q. Доступные темы: %s",
+
+	KeyChartError:      "Не удалось построить график, попробуйте позже",
+	KeyUnknownAction:   "Неизвестное действие",
+	KeyUnknownCommand:  "Неизвестная команда %q, попробуйте help",
+	KeyTooManyRequests: "Слишком много запросов, попробуйте через несколько секунд",
+
+	KeyButtonCandles:        "Свечи",
+	KeyButtonLine:           "Линия",
+	KeyButtonSMA:            "SMA",
+	KeyButtonEMA:            "EMA",
+	KeyButtonVolume:         "Объем",
+	KeyButtonNativeCurrency: "Исходная",
+
+	KeyQuoteTitle:         "%s (%s): %s",
+	KeyQuoteChange:        "Изменение: %s (%s) к закрытию %s",
+	KeyQuoteNoPrevClose:   "Предыдущее закрытие неизвестно",
+	KeyQuoteDayRange:      "День: %s – %s",
+	KeyQuoteVolume:        "Объем: %s",
+	KeyQuoteYearRange:     "52 недели: %s – %s",
+	KeyQuoteMarketClosed:  "Торги не идут, данные на %s",
+	KeyQuoteMarketOpens:   "%s откроется %s в %s",
+	KeyQuoteNoData:        "Нет данных о торгах %s за последний год",
+	KeyQuoteUsage:         "Использование: /quote ТИКЕР, например /quote sber",
+	KeyQuoteUnknownTicker: "Неизвестный тикер %q",
+	KeyQuoteError:         "Не удалось получить котировку, попробуйте позже",
+
+	KeyBookTitle:      "Стакан %s (%s)",
+	KeyBookNotTrading: "Торги не идут",
+	KeyBookSpread:     "Покупка %s / продажа %s, спред %s (%s%%)",
+	KeyBookOneSided:   "Заявки только с одной стороны",
+	KeyBookLots:       "Лотов на покупку: %s, на продажу: %s",
+	KeyBookEmpty:      "Стакан %s пуст, возможно, торги не идут",
+	KeyBookUsage:      "Использование: /book ТИКЕР [ГЛУБИНА], глубина от 1 до %d, например /book sber 10",
+	KeyBookError:      "Не удалось получить стакан, попробуйте позже",
+
+	KeySearchFound:    "Найдено по запросу «%s»:\n",
+	KeySearchResult:   "%s — %s, %s\n",
+	KeySearchNotFound: "По запросу «%s» ничего не найдено",
+	KeySearchUsage:    "Использование: /search НАЗВАНИЕ или ТИКЕР, например /search сбер",
+
+	KeyTypeStock:    "акция",
+	KeyTypeETF:      "фонд",
+	KeyTypeBond:     "облигация",
+	KeyTypeCurrency: "валюта",
+
+	KeyTradeUsage:     "Использование: /%[1]s ТИКЕР КОЛИЧЕСТВО [@ ЦЕНА], например /%[1]s sber 10 @ 250, без цены используется последняя",
+	KeyTradeBought:    "Куплено %s %s по %s",
+	KeyTradeSold:      "Продано %s %s по %s",
+	KeyTradePosition:  "В портфеле %s %s, средняя цена %s",
+	KeyTradeClosed:    "Позиция %s закрыта, зафиксировано %s",
+	KeyTradeNotEnough: "Нельзя продать %s %s, в портфеле %s",
+	KeyTradeError:     "Не удалось сохранить сделку, попробуйте позже",
+
+	KeyPortfolioEmpty:    "Портфель пуст, добавьте сделку: /buy ТИКЕР КОЛИЧЕСТВО @ ЦЕНА",
+	KeyPortfolioTitle:    "Портфель, оценка в %s:",
+	KeyPortfolioPosition: "%s: %s × %s, средняя %s, стоимость %s, P&L %s (%s)",
+	KeyPortfolioNoPrice:  "%s: %s, нет цены",
+	KeyPortfolioTotal:    "Итого: %s, вложено %s, P&L %s (%s)",
+	KeyPortfolioRealized: "Зафиксировано: %s",
+	KeyPortfolioError:    "Не удалось оценить портфель, попробуйте позже",
+	KeyEquityTitle:       "Портфель",
+	KeyEquityValue:       "Стоимость",
+	KeyEquityCost:        "Вложено",
+}
+
+var ruPlurals = map[Key]Plural{
+	KeyQuoteVolumeAverage: {
+		One:  "Объем: %[2]s (×%[3]s к среднему за %[1]d день)",
+		Few:  "Объем: %[2]s (×%[3]s к среднему за %[1]d дня)",
+		Many: "Объем: %[2]s (×%[3]s к среднему за %[1]d дней)",
+	},
+	KeyHelpHeader: {
+		One:  "Доступна %d команда:\n",
+		Few:  "Доступно %d команды:\n",
+		Many: "Доступно %d команд:\n",
+	},
+}
+
+var enMessages = map[Key]string{
+	// grades start with consonant to fit "a"
+	KeyCaptionVerdict:   "What a%s %s result!",
+	KeyCaptionNegative:  " negatively",
+	KeyGradeNeutral:     "neutral",
+	KeyGradeGood:        "good",
+	KeyGradeGreat:       "great",
+	KeyGradeOutstanding: "stellar",
+	KeyGradeFantastic:   "fantastic",
+
+	KeyHelpCommand: "/%s for %s (%s)\n",
+	KeyHelpFooter:  "\n/quote TICKER shows price summary\n/book TICKER shows order book\n/search NAME finds instrument\n/buy, /sell and /portfolio track paper portfolio\n/start or /help prints this message\n/lang switches language\n/theme switches chart theme\n",
+
+	KeyLangCurrent: "Current language: %s. Available languages: %s, e.g. /lang ru",
+	KeyLangChanged: "Language switched: %s",
+	KeyLangUnknown: "Unknown language %q. Available languages: %s",
+
+	KeyThemeCurrent: "Current chart theme: %s. Available themes: %s, e.g. /theme dark",
+	KeyThemeChanged: "Chart theme switched: %s",
+	KeyThemeUnknown: "Unknown theme %q. Available themes: %s",
+
+	KeyChartError:      "Can not draw chart, try later",
+	KeyUnknownAction:   "Unknown action",
+	KeyUnknownCommand:  "Unknown command %q, try help",
+	KeyTooManyRequests: "Too many requests, try again in a few seconds",
+
+	KeyButtonCandles:        "Candles",
+	KeyButtonLine:           "Line",
+	KeyButtonSMA:            "SMA",
+	KeyButtonEMA:            "EMA",
+	KeyButtonVolume:         "Volume",
+	KeyButtonNativeCurrency: "Native",
+
+	KeyQuoteTitle:         "%s (%s): %s",
+	KeyQuoteChange:        "Change: %s (%s) vs previous close %s",
+	KeyQuoteNoPrevClose:   "Previous close is unknown",
+	KeyQuoteDayRange:      "Day: %s – %s",
+	KeyQuoteVolume:        "Volume: %s",
+	KeyQuoteYearRange:     "52 weeks: %s – %s",
+	KeyQuoteMarketClosed:  "Market is closed, data as of %s",
+	KeyQuoteMarketOpens:   "%s opens %s at %s",
+	KeyQuoteNoData:        "No trading data for %s over the last year",
+	KeyQuoteUsage:         "Usage: /quote TICKER, e.g. /quote sber",
+	KeyQuoteUnknownTicker: "Unknown ticker %q",
+	KeyQuoteError:         "Can not get quote, try later",
+
+	KeyBookTitle:      "%s (%s) order book",
+	KeyBookNotTrading: "Instrument is not traded now",
+	KeyBookSpread:     "Bid %s / ask %s, spread %s (%s%%)",
+	KeyBookOneSided:   "Orders on one side only",
+	KeyBookLots:       "Lots to buy: %s, to sell: %s",
+	KeyBookEmpty:      "%s order book is empty, market may be closed",
+	KeyBookUsage:      "Usage: /book TICKER [DEPTH], depth from 1 to %d, e.g. /book sber 10",
+	KeyBookError:      "Can not get order book, try later",
+
+	KeySearchFound:    "Found for «%s»:\n",
+	KeySearchResult:   "%s — %s, %s\n",
+	KeySearchNotFound: "Nothing found for «%s»",
+	KeySearchUsage:    "Usage: /search NAME or TICKER, e.g. /search sber",
+
+	KeyTypeStock:    "stock",
+	KeyTypeETF:      "ETF",
+	KeyTypeBond:     "bond",
+	KeyTypeCurrency: "currency",
+
+	KeyTradeUsage:     "Usage: /%[1]s TICKER QUANTITY [@ PRICE], e.g. /%[1]s sber 10 @ 250, last price is used if price is omitted",
+	KeyTradeBought:    "Bought %s %s at %s",
+	KeyTradeSold:      "Sold %s %s at %s",
+	KeyTradePosition:  "Holding %s %s, average price %s",
+	KeyTradeClosed:    "%s position closed, realized %s",
+	KeyTradeNotEnough: "Can not sell %s %s, holding %s",
+	KeyTradeError:     "Can not save trade, try later",
+
+	KeyPortfolioEmpty:    "Portfolio is empty, add trade: /buy TICKER QUANTITY @ PRICE",
+	KeyPortfolioTitle:    "Portfolio valued in %s:",
+	KeyPortfolioPosition: "%s: %s × %s, average %s, value %s, P&L %s (%s)",
+	KeyPortfolioNoPrice:  "%s: %s, no price",
+	KeyPortfolioTotal:    "Total: %s, invested %s, P&L %s (%s)",
+	KeyPortfolioRealized: "Realized: %s",
+	KeyPortfolioError:    "Can not value portfolio, try later",
+	KeyEquityTitle:       "Portfolio",
+	KeyEquityValue:       "Value",
+	KeyEquityCost:        "Invested",
+}
+
+var enPlurals = map[Key]Plural{
+	KeyQuoteVolumeAverage: {
+		One:  "Volume: %[2]s (×%[3]s vs %[1]d-day average)",
+		Many: "Volume: %[2]s (×%[3]s vs %[1]d-day average)",
+	},
+	KeyHelpHeader: {
+		One:  "%d command is available:\n",
+		Many: "%d commands are available:\n",
+	},
+}
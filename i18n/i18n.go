@@ -0,0 +1,164 @@
+// Package i18n contains bot message catalogs and locale aware formatting
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lang language of messages
+type Lang string
+
+const (
+	// Russian language
+	Russian Lang = "ru"
+	// English language
+	English Lang = "en"
+
+	// DefaultLang is used when chat language is unknown
+	DefaultLang = Russian
+)
+
+// Key message identifier in catalogs
+type Key string
+
+// PluralForm plural category of number
+type PluralForm int
+
+const (
+	// One singular form, e.g. "1 command", "21 команда"
+	One PluralForm = iota
+	// Few russian paucal form, e.g. "3 команды"
+	Few
+	// Many form for other numbers, e.g. "5 commands", "5 команд"
+	Many
+)
+
+// Plural message forms, missing forms fall back to Many
+type Plural map[PluralForm]string
+
+// locale language rules
+type locale struct {
+	name        string
+	dateLayout  string
+	decimalSep  string
+	groupSep    string
+	pluralForm  func(n int) PluralForm
+	messages    map[Key]string
+	pluralForms map[Key]Plural
+}
+
+var locales = map[Lang]*locale{
+	Russian: {
+		name:        "Русский",
+		dateLayout:  "02.01.2006",
+		decimalSep:  ",",
+		groupSep:    "\u00a0",
+		pluralForm:  russianPluralForm,
+		messages:    ruMessages,
+		pluralForms: ruPlurals,
+	},
+	English: {
+		name:        "English",
+		dateLayout:  "Jan 2, 2006",
+		decimalSep:  ".",
+		groupSep:    ",",
+		pluralForm:  englishPluralForm,
+		messages:    enMessages,
+		pluralForms: enPlurals,
+	},
+}
+
+func russianPluralForm(n int) PluralForm {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return One
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+func englishPluralForm(n int) PluralForm {
+	if n == 1 || n == -1 {
+		return One
+	}
+	return Many
+}
+
+// ParseLang returns supported language of IETF tag, e.g. "en-US" is English
+func ParseLang(tag string) (Lang, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	_, ok := locales[Lang(tag)]
+	return Lang(tag), ok
+}
+
+// Langs returns supported languages
+func Langs() []Lang {
+	langs := make([]Lang, 0, len(locales))
+	for lang := range locales {
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool { return langs[i] < langs[j] })
+	return langs
+}
+
+// Printer formats messages and numbers in one language
+type Printer struct {
+	lang   Lang
+	locale *locale
+}
+
+// NewPrinter returns printer of lang, unsupported languages use DefaultLang
+func NewPrinter(lang Lang) *Printer {
+	l, ok := locales[lang]
+	if !ok {
+		lang, l = DefaultLang, locales[DefaultLang]
+	}
+	return &Printer{lang: lang, locale: l}
+}
+
+// Lang returns printer language
+func (p *Printer) Lang() Lang {
+	return p.lang
+}
+
+// LangName returns language name in that language
+func (p *Printer) LangName() string {
+	return p.locale.name
+}
+
+// Sprintf formats message of key, messages missing in catalog
+// are taken from English catalog
+func (p *Printer) Sprintf(key Key, args ...interface{}) string {
+	format, ok := p.locale.messages[key]
+	if !ok {
+		format, ok = enMessages[key]
+	}
+	if !ok {
+		format = string(key)
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Plural formats plural message of key for n, n is passed as first argument
+func (p *Printer) Plural(key Key, n int, args ...interface{}) string {
+	forms, ok := p.locale.pluralForms[key]
+	pluralForm := p.locale.pluralForm
+	if !ok {
+		forms, pluralForm = enPlurals[key], englishPluralForm
+	}
+	format, ok := forms[pluralForm(n)]
+	if !ok {
+		format = forms[Many]
+	}
+	return fmt.Sprintf(format, append([]interface{}{n}, args...)...)
+}
@@ -0,0 +1,65 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Number formats number with precision digits after decimal separator
+// and groups thousands, e.g. "1 234,50" in russian and "1,234.50" in english
+func (p *Printer) Number(value float64, precision int) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	formatted := strconv.FormatFloat(math.Abs(value), 'f', precision, 64)
+	intPart, fracPart := formatted, ""
+	if i := strings.IndexByte(formatted, '.'); i >= 0 {
+		intPart, fracPart = formatted[:i], formatted[i+1:]
+	}
+
+	var b strings.Builder
+	if value < 0 && strings.Trim(formatted, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(p.locale.groupSep)
+		}
+		b.WriteRune(digit)
+	}
+	if fracPart != "" {
+		b.WriteString(p.locale.decimalSep)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// SignedNumber formats number with explicit sign, e.g. "+1,50"
+func (p *Printer) SignedNumber(value float64, precision int) string {
+	formatted := p.Number(value, precision)
+	if strings.HasPrefix(formatted, "-") {
+		return formatted
+	}
+	return "+" + formatted
+}
+
+// Percent formats signed percent change, e.g. "+1,50%"
+func (p *Printer) Percent(value float64, precision int) string {
+	return p.SignedNumber(value, precision) + "%"
+}
+
+// Money formats amount with currency code, e.g. "250,34 RUB"
+func (p *Printer) Money(value float64, precision int, currency string) string {
+	if currency == "" {
+		return p.Number(value, precision)
+	}
+	return p.Number(value, precision) + " " + currency
+}
+
+// Date formats date, e.g. "02.01.2006" in russian and "Jan 2, 2006" in english
+func (p *Printer) Date(t time.Time) string {
+	return t.Format(p.locale.dateLayout)
+}
@@ -0,0 +1,90 @@
+package instruments
+
+import (
+	"sort"
+
+	"github.com/Apakhov/stocks-bot/config"
+
+	"github.com/pkg/errors"
+)
+
+const featuredEnvPrefix = "FEATURED"
+
+// Featured instrument available as bot command and in web list
+type Featured struct {
+	Command string `json:"Command" validate:"required"`
+	Ticker  string `json:"Ticker" validate:"required"`
+	Name    string `json:"Name"`
+	Group   string `json:"Group" validate:"required"`
+}
+
+// DisplayName returns name or ticker if name is not set
+func (f *Featured) DisplayName() string {
+	if f.Name == "" {
+		return f.Ticker
+	}
+	return f.Name
+}
+
+// FeaturedConfig file with featured instruments
+type FeaturedConfig struct {
+	Featured []*Featured `json:"Featured" validate:"required"`
+}
+
+// Validate checks that commands are unique
+func (c *FeaturedConfig) Validate() error {
+	commands := make(map[string]struct{}, len(c.Featured))
+	for _, featured := range c.Featured {
+		if featured == nil {
+			return errors.New("empty featured instrument")
+		}
+		if _, ok := commands[featured.Command]; ok {
+			return errors.Errorf("duplicate command %q", featured.Command)
+		}
+		commands[featured.Command] = struct{}{}
+	}
+	return nil
+}
+
+// LoadFeatured loads featured instruments from file
+func LoadFeatured(path string) ([]*Featured, error) {
+	var conf FeaturedConfig
+	loader := &config.Loader{Path: path, EnvPrefix: featuredEnvPrefix}
+	if err := loader.Load(&conf); err != nil {
+		return nil, errors.Wrap(err, "can not load featured instruments")
+	}
+	return conf.Featured, nil
+}
+
+// Group featured instruments of one group
+type Group struct {
+	Name        string      `json:"Name"`
+	Instruments []*Featured `json:"Instruments"`
+}
+
+// GroupFeatured groups instruments, groups and instruments
+// inside them are sorted by name and command
+func GroupFeatured(featured []*Featured) []*Group {
+	groupsByName := make(map[string]*Group)
+	groups := make([]*Group, 0)
+	for _, f := range featured {
+		group, ok := groupsByName[f.Group]
+		if !ok {
+			group = &Group{Name: f.Group}
+			groupsByName[f.Group] = group
+			groups = append(groups, group)
+		}
+		group.Instruments = append(group.Instruments, f)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	for _, group := range groups {
+		instruments := group.Instruments
+		sort.Slice(instruments, func(i, j int) bool {
+			return instruments[i].Command < instruments[j].Command
+		})
+	}
+	return groups
+}
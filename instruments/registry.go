@@ -0,0 +1,238 @@
+package instruments
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Type instrument class
+type Type string
+
+// Instrument classes in order of preference on ticker collision
+const (
+	TypeStock    Type = "stock"
+	TypeETF      Type = "etf"
+	TypeCurrency Type = "currency"
+	TypeBond     Type = "bond"
+)
+
+// Types returns instrument classes in order of preference
+func Types() []Type {
+	return []Type{TypeStock, TypeETF, TypeCurrency, TypeBond}
+}
+
+func (t Type) priority() int {
+	for i, typ := range Types() {
+		if typ == t {
+			return i
+		}
+	}
+	return len(Types())
+}
+
+// ParseType parses instrument class name
+func ParseType(s string) (Type, error) {
+	t := Type(strings.ToLower(s))
+	if t.priority() == len(Types()) {
+		return "", errors.Errorf("unknown instrument type %q", s)
+	}
+	return t, nil
+}
+
+// typeSeparator separates ticker and class in qualified ticker: "SU26238RMFS4:bond"
+const typeSeparator = ":"
+
+// Instrument tradable instrument description
+type Instrument struct {
+	FIGI              string
+	Ticker            string
+	ISIN              string
+	Name              string
+	Type              Type
+	Lot               int
+	MinPriceIncrement float64
+	Currency          string
+}
+
+// QualifiedTicker returns ticker with class which is unique across classes
+func (i *Instrument) QualifiedTicker() string {
+	return i.Ticker + typeSeparator + string(i.Type)
+}
+
+// LoadFunc loads all instruments of provider
+type LoadFunc func(ctx context.Context) ([]*Instrument, error)
+
+// Registry instruments keyed by ticker and FIGI, refreshed with Refresh or Run
+type Registry struct {
+	load    LoadFunc
+	aliases map[string]string
+	logger  *zap.Logger
+
+	mu       sync.RWMutex
+	byFIGI   map[string]*Instrument
+	byTicker map[string][]*Instrument
+	entries  []*searchEntry
+	updated  time.Time
+}
+
+// NewRegistry creates empty registry, aliases map extra names to tickers
+func NewRegistry(load LoadFunc, aliases map[string]string, logger *zap.Logger) *Registry {
+	return &Registry{
+		load:     load,
+		aliases:  aliases,
+		logger:   logger,
+		byFIGI:   map[string]*Instrument{},
+		byTicker: map[string][]*Instrument{},
+	}
+}
+
+// Refresh reloads instruments, previous list is kept on error
+func (r *Registry) Refresh(ctx context.Context) error {
+	instruments, err := r.load(ctx)
+	if err != nil {
+		return errors.Wrap(err, "can not load instruments")
+	}
+	if len(instruments) == 0 {
+		return errors.New("provider returned no instruments")
+	}
+
+	byFIGI := make(map[string]*Instrument, len(instruments))
+	byTicker := make(map[string][]*Instrument, len(instruments))
+	entries := make([]*searchEntry, 0, len(instruments))
+	for _, instrument := range instruments {
+		if _, ok := byFIGI[instrument.FIGI]; ok {
+			r.logger.Warn("duplicate instrument figi", zap.String("figi", instrument.FIGI), zap.String("ticker", instrument.Ticker))
+			continue
+		}
+		byFIGI[instrument.FIGI] = instrument
+		byTicker[instrument.Ticker] = append(byTicker[instrument.Ticker], instrument)
+		entries = append(entries, newSearchEntry(instrument))
+	}
+
+	collisions := 0
+	for _, candidates := range byTicker {
+		if len(candidates) < 2 {
+			continue
+		}
+		collisions++
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Type.priority() < candidates[j].Type.priority()
+		})
+	}
+
+	r.mu.Lock()
+	r.byFIGI = byFIGI
+	r.byTicker = byTicker
+	r.entries = entries
+	r.updated = time.Now()
+	r.mu.Unlock()
+
+	r.logger.Info("instruments loaded", zap.Int("instruments", len(byFIGI)), zap.Int("tickers", len(byTicker)),
+		zap.Int("ticker_collisions", collisions))
+	return nil
+}
+
+// Run refreshes registry every interval until ctx is done
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Warn("instruments refresh failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Updated returns time of last successful refresh
+func (r *Registry) Updated() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.updated
+}
+
+// ByFIGI returns instrument by FIGI
+func (r *Registry) ByFIGI(figi string) (*Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	instrument, ok := r.byFIGI[figi]
+	return instrument, ok
+}
+
+// ByTicker returns all instruments with ticker in order of preference
+func (r *Registry) ByTicker(ticker string) []*Instrument {
+	if alias, ok := r.aliases[ticker]; ok {
+		ticker = alias
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.byTicker[ticker]
+}
+
+// Lookup resolves ticker, qualified ticker ("TICKER:bond"), alias or FIGI,
+// plain ticker present in several classes resolves to the preferred class
+func (r *Registry) Lookup(key string) (*Instrument, bool) {
+	if idx := strings.LastIndex(key, typeSeparator); idx >= 0 {
+		typ, err := ParseType(key[idx+1:])
+		if err != nil {
+			return nil, false
+		}
+		for _, instrument := range r.ByTicker(key[:idx]) {
+			if instrument.Type == typ {
+				return instrument, true
+			}
+		}
+		return nil, false
+	}
+
+	if candidates := r.ByTicker(key); len(candidates) > 0 {
+		return candidates[0], true
+	}
+	return r.ByFIGI(key)
+}
+
+// Key returns shortest key resolving to instrument with Lookup:
+// ticker or qualified ticker if ticker is present in several classes
+func (r *Registry) Key(instrument *Instrument) string {
+	if len(r.ByTicker(instrument.Ticker)) > 1 {
+		return instrument.QualifiedTicker()
+	}
+	return instrument.Ticker
+}
+
+// Search returns up to limit instruments matching query by ticker, FIGI, ISIN or name,
+// cyrillic and latin spellings are matched with each other, typos are tolerated
+func (r *Registry) Search(query string, limit int) []*Instrument {
+	r.mu.RLock()
+	entries := r.entries
+	r.mu.RUnlock()
+	return searchEntries(entries, query, limit)
+}
+
+// All returns all instruments sorted by ticker and class
+func (r *Registry) All() []*Instrument {
+	r.mu.RLock()
+	all := make([]*Instrument, 0, len(r.byFIGI))
+	for _, instrument := range r.byFIGI {
+		all = append(all, instrument)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Ticker != all[j].Ticker {
+			return all[i].Ticker < all[j].Ticker
+		}
+		return all[i].Type.priority() < all[j].Type.priority()
+	})
+	return all
+}
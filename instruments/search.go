@@ -0,0 +1,230 @@
+package instruments
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultSearchLimit count of search results used when limit is not set
+const DefaultSearchLimit = 10
+
+// Search scores, greater is better
+const (
+	scoreExactTicker  = 1000
+	scoreExactID      = 900
+	scoreTickerPrefix = 700
+	scoreNamePrefix   = 600
+	scoreWordPrefix   = 500
+	scoreSubstring    = 300
+	scoreFuzzy        = 100
+)
+
+var cyrillicTranslit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// latinSimplifier merges latin spellings which transliteration can not tell apart:
+// "yandex" and "яндекс" both become "yandeks"
+var latinSimplifier = strings.NewReplacer(
+	"ph", "f",
+	"x", "ks",
+	"w", "v",
+	"q", "k",
+	"c", "k",
+	"j", "y",
+)
+
+// normalize lowercases s, transliterates cyrillic to latin
+// and replaces punctuation with single spaces
+func normalize(s string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(s) {
+		switch translit, ok := cyrillicTranslit[r]; {
+		case ok:
+			b.WriteString(translit)
+			space = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			space = false
+		case !space:
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return latinSimplifier.Replace(strings.TrimSpace(b.String()))
+}
+
+// searchEntry instrument with normalized search keys
+type searchEntry struct {
+	instrument *Instrument
+	ticker     string
+	name       string
+	words      []string
+}
+
+func newSearchEntry(instrument *Instrument) *searchEntry {
+	name := normalize(instrument.Name)
+	return &searchEntry{
+		instrument: instrument,
+		ticker:     strings.ReplaceAll(normalize(instrument.Ticker), " ", ""),
+		name:       name,
+		words:      strings.Fields(name),
+	}
+}
+
+// searchQuery query with precomputed forms
+type searchQuery struct {
+	raw     string
+	text    string
+	compact string
+}
+
+func newSearchQuery(query string) *searchQuery {
+	text := normalize(query)
+	return &searchQuery{
+		raw:     strings.ToUpper(strings.TrimSpace(query)),
+		text:    text,
+		compact: strings.ReplaceAll(text, " ", ""),
+	}
+}
+
+// maxDistance returns edit distance tolerated for query,
+// short queries are matched exactly
+func (q *searchQuery) maxDistance() int {
+	switch n := len(q.compact); {
+	case n < 3:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// score returns relevance of entry to query, zero if entry does not match
+func (e *searchEntry) score(q *searchQuery) int {
+	switch {
+	case e.instrument.Ticker == q.raw:
+		return scoreExactTicker
+	case e.ticker == q.compact, e.instrument.FIGI == q.raw, e.instrument.ISIN == q.raw:
+		return scoreExactID
+	case strings.HasPrefix(e.ticker, q.compact):
+		return scoreTickerPrefix - (len(e.ticker) - len(q.compact))
+	case strings.HasPrefix(e.name, q.text):
+		return scoreNamePrefix
+	}
+	for _, word := range e.words {
+		if strings.HasPrefix(word, q.text) {
+			return scoreWordPrefix
+		}
+	}
+	if strings.Contains(e.ticker, q.compact) || strings.Contains(e.name, q.text) {
+		return scoreSubstring
+	}
+
+	maxDistance := q.maxDistance()
+	if maxDistance == 0 {
+		return 0
+	}
+	best := maxDistance + 1
+	for _, key := range append([]string{e.ticker}, e.words...) {
+		if d := prefixDistance(q.compact, key); d < best {
+			best = d
+		}
+	}
+	if best > maxDistance {
+		return 0
+	}
+	return scoreFuzzy - 10*best
+}
+
+// prefixDistance returns edit distance between query and
+// the closest prefix of key
+func prefixDistance(query, key string) int {
+	a, b := []rune(query), []rune(key)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	best := prev[0]
+	for _, d := range prev {
+		if d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// searchEntries finds up to limit best matching entries
+func searchEntries(entries []*searchEntry, query string, limit int) []*Instrument {
+	q := newSearchQuery(query)
+	if q.compact == "" {
+		return nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	type match struct {
+		entry *searchEntry
+		score int
+	}
+	var matches []match
+	for _, entry := range entries {
+		if score := entry.score(q); score > 0 {
+			matches = append(matches, match{entry: entry, score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i].entry.instrument, matches[j].entry.instrument
+		switch {
+		case matches[i].score != matches[j].score:
+			return matches[i].score > matches[j].score
+		case a.Type != b.Type:
+			return a.Type.priority() < b.Type.priority()
+		case len(a.Ticker) != len(b.Ticker):
+			return len(a.Ticker) < len(b.Ticker)
+		default:
+			return a.Ticker < b.Ticker
+		}
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]*Instrument, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.entry.instrument)
+	}
+	return result
+}
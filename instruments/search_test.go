@@ -0,0 +1,78 @@
+package instruments
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Яндекс", "yandeks"},
+		{"yandex", "yandeks"},
+		{"YANDEX", "yandeks"},
+		{"Сбер-Банк", "sber bank"},
+		{"  Газпром — нефть!! ", "gazprom neft"},
+		{"A.B...C", "a b k"},
+		{"X5 Retail Group", "ks5 retail group"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalize(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if normalize("Яндекс") != normalize("yandex") {
+		t.Error("cyrillic and latin spellings of Yandex differ")
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	load := func(ctx context.Context) ([]*Instrument, error) {
+		return []*Instrument{
+			{FIGI: "1", Ticker: "SBEP", Name: "Fuzzy Match", Type: TypeStock},
+			{FIGI: "2", Ticker: "SBERP", Name: "Sberbank pref", Type: TypeStock},
+			{FIGI: "3", Ticker: "GAZP", Name: "Gazprom", Type: TypeStock},
+			{FIGI: "4", Ticker: "SBER", Name: "Sberbank", Type: TypeStock},
+			{FIGI: "5", Ticker: "YNDX", Name: "Yandex", Type: TypeStock},
+		}, nil
+	}
+	registry := NewRegistry(load, nil, zap.NewNop())
+	if err := registry.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// exact ticker, then ticker prefix, then fuzzy match
+		{"sber", []string{"SBER", "SBERP", "SBEP"}},
+		{"Яндекс", []string{"YNDX"}},
+		{"yandex", []string{"YNDX"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		found := registry.Search(tt.query, 0)
+		got := make([]string, 0, len(found))
+		for _, instrument := range found {
+			got = append(got, instrument.Ticker)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// FormatJSON json encoded log lines
+	FormatJSON = "json"
+	// FormatConsole human readable log lines
+	FormatConsole = "console"
+
+	// RequestIDKey log field name for request id
+	RequestIDKey = "request_id"
+)
+
+type requestIDCtxKey struct{}
+
+// Config logger config
+type Config struct {
+	Level  string `json:"Level"`
+	Format string `json:"Format"`
+}
+
+// Validate checks log level and format
+func (c Config) Validate() error {
+	if c.Level != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+			return errors.Errorf("unknown log level %q", c.Level)
+		}
+	}
+	switch c.Format {
+	case "", FormatJSON, FormatConsole:
+		return nil
+	default:
+		return errors.Errorf("unknown log format %q", c.Format)
+	}
+}
+
+// New creates new zap logger from config
+func New(cfg Config) (*zap.Logger, error) {
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, errors.Wrapf(err, "can not parse log level %q", cfg.Level)
+		}
+	}
+
+	zapConfig := zap.NewProductionConfig()
+	switch cfg.Format {
+	case "", FormatJSON:
+	case FormatConsole:
+		zapConfig.Encoding = FormatConsole
+		zapConfig.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+		zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	default:
+		return nil, errors.Errorf("unknown log format %q", cfg.Format)
+	}
+	zapConfig.Level = level
+
+	return zapConfig.Build()
+}
+
+// NewRequestID generates random request id
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+// WithRequestID returns context with request id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, requestID)
+}
+
+// RequestID returns request id stored in context or empty string
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return requestID
+}
+
+// FromContext returns logger annotated with request id from context
+func FromContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	requestID := RequestID(ctx)
+	if requestID == "" {
+		return logger
+	}
+	return logger.With(zap.String(RequestIDKey, requestID))
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// bufferLogger returns logger writing json lines to buf
+func bufferLogger(buf *bytes.Buffer) *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(zapcore.NewCore(encoder, zapcore.AddSync(buf), zap.DebugLevel))
+}
+
+func TestFromContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		wantID string
+	}{
+		{"with request id", WithRequestID(context.Background(), "0123456789abcdef"), "0123456789abcdef"},
+		{"without request id", context.Background(), ""},
+		{"empty request id", WithRequestID(context.Background(), ""), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := bufferLogger(&buf)
+
+			if got := RequestID(tt.ctx); got != tt.wantID {
+				t.Errorf("RequestID() = %q, want %q", got, tt.wantID)
+			}
+			FromContext(tt.ctx, logger).Info("message")
+
+			var line map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("can not decode log line %q: %v", buf.String(), err)
+			}
+			got, ok := line[RequestIDKey]
+			if tt.wantID == "" {
+				if ok {
+					t.Errorf("log line has %s %v, want none", RequestIDKey, got)
+				}
+				return
+			}
+			if got != tt.wantID {
+				t.Errorf("log line %s = %v, want %q", RequestIDKey, got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	first, second := NewRequestID(), NewRequestID()
+	if len(first) != 16 || first == second {
+		t.Errorf("NewRequestID() = %q, %q, want distinct 16 hex chars", first, second)
+	}
+}
@@ -0,0 +1,395 @@
+// Package discord implements messenger.Messenger with discord
+// application commands received by interactions endpoint
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/messenger/slash"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAPIEndpoint = "https://discord.com/api/v10/"
+	defaultPath        = "/discord/interactions"
+	defaultCommand     = "stock"
+
+	signatureHeader = "X-Signature-Ed25519"
+	timestampHeader = "X-Signature-Timestamp"
+
+	// interaction token is valid for 15 minutes
+	interactionTTL = 15 * time.Minute
+
+	interactionTypePing               = 1
+	interactionTypeApplicationCommand = 2
+
+	responseTypePong                   = 1
+	responseTypeDeferredChannelMessage = 5
+
+	commandOptionTypeString = 3
+	tickerOption            = "ticker"
+
+	photoFileName     = "chart.jpg"
+	httpClientTimeout = 30 * time.Second
+)
+
+// Config discord messenger config
+type Config struct {
+	// PublicKey hex encoded application public key verifying interactions
+	PublicKey     string `json:"PublicKey"`
+	ApplicationID string `json:"ApplicationID"`
+	// BotToken is used only to register command
+	BotToken string `json:"BotToken"`
+	Listen   string `json:"Listen"`
+	Path     string `json:"Path"`
+	// Command name of application command, "/stock sber" is handled as "/sber"
+	Command string `json:"Command"`
+	// RegisterCommand creates or updates global application command at startup
+	RegisterCommand bool `json:"RegisterCommand"`
+	// APIEndpoint overrides discord api endpoint
+	APIEndpoint string `json:"APIEndpoint"`
+}
+
+// Validate checks public key
+func (c Config) Validate() error {
+	if c.PublicKey == "" {
+		return nil
+	}
+	key, err := hex.DecodeString(c.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("PublicKey must be hex encoded ed25519 public key")
+	}
+	return nil
+}
+
+// Messenger discord implementation of messenger.Messenger
+type Messenger struct {
+	cfg          Config
+	publicKey    ed25519.PublicKey
+	client       *http.Client
+	interactions *slash.Interactions
+	logger       *zap.Logger
+}
+
+var _ messenger.Messenger = (*Messenger)(nil)
+
+// reply target of interaction, original response can be edited
+// only after deferred response is sent, ready is closed then
+type reply struct {
+	token string
+	ready chan struct{}
+}
+
+type interaction struct {
+	Type   int    `json:"type"`
+	Token  string `json:"token"`
+	Locale string `json:"locale"`
+	Member *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+type discordUser struct {
+	ID string `json:"id"`
+}
+
+// New creates discord messenger
+func New(cfg Config, logger *zap.Logger) (*Messenger, error) {
+	if cfg.APIEndpoint == "" {
+		cfg.APIEndpoint = defaultAPIEndpoint
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	if cfg.Command == "" {
+		cfg.Command = defaultCommand
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	publicKey, _ := hex.DecodeString(cfg.PublicKey)
+
+	return &Messenger{
+		cfg:          cfg,
+		publicKey:    publicKey,
+		client:       &http.Client{Timeout: httpClientTimeout},
+		interactions: slash.NewInteractions(interactionTTL),
+		logger:       logger,
+	}, nil
+}
+
+// Run implements messenger.Messenger
+func (m *Messenger) Run(ctx context.Context, dispatch messenger.DispatchFunc) error {
+	if m.cfg.RegisterCommand {
+		if err := m.registerCommand(ctx); err != nil {
+			return errors.Wrap(err, "can not register command")
+		}
+		m.logger.Info("discord command registered", zap.String("command", m.cfg.Command))
+	}
+
+	return slash.Serve(ctx, m.cfg.Listen, m.cfg.Path, &interactionHandler{
+		messenger: m,
+		dispatch:  dispatch,
+	}, m.logger)
+}
+
+// interactionHandler receives interactions and passes commands to dispatcher
+type interactionHandler struct {
+	messenger *Messenger
+	dispatch  messenger.DispatchFunc
+}
+
+// ServeHTTP implements http.Handler
+func (h *interactionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.messenger.logger
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, slash.MaxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !verifySignature(h.messenger.publicKey, r.Header, body) {
+		// discord periodically sends requests with bad signature
+		// and disables endpoint if they are accepted
+		logger.Warn("interaction with bad signature", zap.String("remote_addr", r.RemoteAddr))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var in interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		logger.Warn("can not decode interaction", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch in.Type {
+	case interactionTypePing:
+		writeResponse(w, responseTypePong)
+		return
+	case interactionTypeApplicationCommand:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	text := in.text()
+	command, args := messenger.ParseCommand(text)
+	if command == "" {
+		command = "help"
+	}
+	// discord user ids are global, so conversation is user in any guild or direct messages
+	chatID := slash.ChatID(in.userID())
+	reply := &reply{token: in.Token, ready: make(chan struct{})}
+	h.messenger.interactions.Add(chatID, reply)
+
+	err = h.dispatch(r.Context(), messenger.Update{Message: &messenger.Message{
+		ChatID:      chatID,
+		UserID:      slash.UserID(in.userID()),
+		Text:        text,
+		Command:     command,
+		Args:        args,
+		Lang:        in.Locale,
+		Interaction: true,
+	}})
+	if err != nil {
+		h.messenger.interactions.Remove(chatID, reply)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	writeResponse(w, responseTypeDeferredChannelMessage)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	close(reply.ready)
+}
+
+// text returns string options of command joined with spaces
+func (in *interaction) text() string {
+	parts := make([]string, 0, len(in.Data.Options))
+	for _, option := range in.Data.Options {
+		var value string
+		if err := json.Unmarshal(option.Value, &value); err == nil {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// userID returns author id, member is set in guilds and user in direct messages
+func (in *interaction) userID() string {
+	if in.Member != nil {
+		return in.Member.User.ID
+	}
+	if in.User != nil {
+		return in.User.ID
+	}
+	return ""
+}
+
+func writeResponse(w http.ResponseWriter, responseType int) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"type": responseType})
+}
+
+// verifySignature checks ed25519 signature of timestamp and body
+func verifySignature(publicKey ed25519.PublicKey, header http.Header, body []byte) bool {
+	signature, err := hex.DecodeString(header.Get(signatureHeader))
+	if err != nil || len(signature) != ed25519.SignatureSize || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	message := append([]byte(header.Get(timestampHeader)), body...)
+	return ed25519.Verify(publicKey, message, signature)
+}
+
+func (m *Messenger) takeReply(ctx context.Context, chatID int64) (*reply, error) {
+	target, ok := m.interactions.Take(chatID)
+	if !ok {
+		return nil, errors.Errorf("no pending interaction in chat %d", chatID)
+	}
+	reply := target.(*reply)
+
+	select {
+	case <-reply.ready:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SendText implements messenger.Messenger
+func (m *Messenger) SendText(ctx context.Context, chatID int64, text string) error {
+	reply, err := m.takeReply(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]string{"content": text})
+	if err != nil {
+		return errors.Wrap(err, "can not encode response")
+	}
+	return m.editOriginal(ctx, reply, "application/json", payload)
+}
+
+// SendButtons implements messenger.Messenger, keyboard is not supported and ignored
+func (m *Messenger) SendButtons(ctx context.Context, chatID int64, text string, _ messenger.Keyboard) error {
+	return m.SendText(ctx, chatID, text)
+}
+
+// SendPhoto implements messenger.Messenger, keyboard is not supported and ignored
+func (m *Messenger) SendPhoto(ctx context.Context, chatID int64, photo []byte, caption string, _ messenger.Keyboard) error {
+	reply, err := m.takeReply(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"content":     caption,
+		"attachments": []map[string]interface{}{{"id": 0, "filename": photoFileName}},
+	})
+	if err != nil {
+		return errors.Wrap(err, "can not encode response")
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("payload_json", string(payload)); err != nil {
+		return errors.Wrap(err, "can not write response form")
+	}
+	part, err := writer.CreateFormFile("files[0]", photoFileName)
+	if err != nil {
+		return errors.Wrap(err, "can not create response form")
+	}
+	if _, err := part.Write(photo); err != nil {
+		return errors.Wrap(err, "can not write response form")
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "can not close response form")
+	}
+	return m.editOriginal(ctx, reply, writer.FormDataContentType(), body.Bytes())
+}
+
+// EditPhoto implements messenger.Messenger
+func (m *Messenger) EditPhoto(context.Context, int64, int, []byte, string, messenger.Keyboard) error {
+	return messenger.ErrNotSupported
+}
+
+// AnswerCallback implements messenger.Messenger
+func (m *Messenger) AnswerCallback(context.Context, *messenger.Callback, string) error {
+	return messenger.ErrNotSupported
+}
+
+// editOriginal replaces deferred "thinking" response with reply
+func (m *Messenger) editOriginal(ctx context.Context, reply *reply, contentType string, body []byte) error {
+	url := m.cfg.APIEndpoint + "webhooks/" + m.cfg.ApplicationID + "/" + reply.token + "/messages/@original"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "can not create response request")
+	}
+	req.Header.Set("Content-Type", contentType)
+	return errors.Wrap(m.do(req), "can not edit interaction response")
+}
+
+// registerCommand creates or updates global command with optional ticker option
+func (m *Messenger) registerCommand(ctx context.Context) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"name":        m.cfg.Command,
+		"description": "Draw stock chart",
+		"options": []map[string]interface{}{{
+			"type":        commandOptionTypeString,
+			"name":        tickerOption,
+			"description": "Ticker command, e.g. sber, empty prints help",
+			"required":    false,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	url := m.cfg.APIEndpoint + "applications/" + m.cfg.ApplicationID + "/commands"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+m.cfg.BotToken)
+	return m.do(req)
+}
+
+func (m *Messenger) do(req *http.Request) error {
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package messenger
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotSupported returned for operations platform can not do
+var ErrNotSupported = errors.New("operation is not supported by messenger")
+
+// Button inline button, Data is returned in Callback when pressed
+type Button struct {
+	Text string
+	Data string
+}
+
+// Keyboard rows of inline buttons attached to message
+type Keyboard [][]Button
+
+// Message incoming text message
+type Message struct {
+	ChatID  int64
+	UserID  int64
+	Text    string
+	Command string
+	Args    string
+	// Lang IETF language tag of user, empty if platform does not tell
+	Lang string
+	// Interaction is set for slash command invocations,
+	// which always expect reply, even for unknown commands
+	Interaction bool
+}
+
+// Callback inline button press
+type Callback struct {
+	ID        string
+	ChatID    int64
+	UserID    int64
+	MessageID int
+	Data      string
+	Lang      string
+}
+
+// Update incoming event, exactly one field is set
+type Update struct {
+	Message  *Message
+	Callback *Callback
+}
+
+// ChatID returns chat of update
+func (u *Update) ChatID() int64 {
+	switch {
+	case u.Message != nil:
+		return u.Message.ChatID
+	case u.Callback != nil:
+		return u.Callback.ChatID
+	default:
+		return 0
+	}
+}
+
+// UserID returns author of update
+func (u *Update) UserID() int64 {
+	switch {
+	case u.Message != nil:
+		return u.Message.UserID
+	case u.Callback != nil:
+		return u.Callback.UserID
+	default:
+		return 0
+	}
+}
+
+// DispatchFunc accepts received update, error means update
+// was not accepted and should be redelivered if possible
+type DispatchFunc func(ctx context.Context, update Update) error
+
+// Messenger chat platform used by bot
+type Messenger interface {
+	// Run receives updates and passes them to dispatch until ctx is done
+	Run(ctx context.Context, dispatch DispatchFunc) error
+	// SendText sends text message
+	SendText(ctx context.Context, chatID int64, text string) error
+	// SendButtons sends text message with keyboard
+	SendButtons(ctx context.Context, chatID int64, text string, keyboard Keyboard) error
+	// SendPhoto sends photo with caption and optional keyboard
+	SendPhoto(ctx context.Context, chatID int64, photo []byte, caption string, keyboard Keyboard) error
+	// EditPhoto replaces photo, caption and keyboard of sent message
+	EditPhoto(ctx context.Context, chatID int64, messageID int, photo []byte, caption string, keyboard Keyboard) error
+	// AnswerCallback confirms button press with optional notification text
+	AnswerCallback(ctx context.Context, callback *Callback, text string) error
+}
+
+// ParseCommand splits "/command@bot args" into command and args,
+// leading slash is optional
+func ParseCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	command, args := text, ""
+	if i := strings.IndexAny(text, " \n\t"); i >= 0 {
+		command, args = text[:i], strings.TrimSpace(text[i+1:])
+	}
+	command = strings.TrimPrefix(command, "/")
+	if i := strings.Index(command, "@"); i >= 0 {
+		command = command[:i]
+	}
+	return strings.ToLower(command), args
+}
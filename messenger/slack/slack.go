@@ -0,0 +1,314 @@
+// Package slack implements messenger.Messenger with slack slash commands
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/messenger/slash"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAPIEndpoint = "https://slack.com/api/"
+	defaultPath        = "/slack/commands"
+
+	signatureHeader  = "X-Slack-Signature"
+	timestampHeader  = "X-Slack-Request-Timestamp"
+	signatureVersion = "v0"
+	// maxClockSkew protects from replayed requests
+	maxClockSkew = 5 * time.Minute
+
+	// response_url accepts replies for 30 minutes
+	interactionTTL = 30 * time.Minute
+
+	photoFileName     = "chart.jpg"
+	httpClientTimeout = 30 * time.Second
+)
+
+// Config slack messenger config
+type Config struct {
+	// SigningSecret verifies that requests are sent by slack
+	SigningSecret string `json:"SigningSecret"`
+	// BotToken is used to upload charts, bot must be member of channel
+	BotToken string `json:"BotToken"`
+	Listen   string `json:"Listen"`
+	Path     string `json:"Path"`
+	// APIEndpoint overrides slack web api endpoint
+	APIEndpoint string `json:"APIEndpoint"`
+}
+
+// Messenger slack slash command implementation of messenger.Messenger,
+// "/stock sber" is handled as "/sber" command
+type Messenger struct {
+	cfg          Config
+	client       *http.Client
+	interactions *slash.Interactions
+	logger       *zap.Logger
+}
+
+var _ messenger.Messenger = (*Messenger)(nil)
+
+// reply target of slash command
+type reply struct {
+	channelID   string
+	responseURL string
+}
+
+// New creates slack messenger
+func New(cfg Config, logger *zap.Logger) *Messenger {
+	if cfg.APIEndpoint == "" {
+		cfg.APIEndpoint = defaultAPIEndpoint
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+
+	return &Messenger{
+		cfg:          cfg,
+		client:       &http.Client{Timeout: httpClientTimeout},
+		interactions: slash.NewInteractions(interactionTTL),
+		logger:       logger,
+	}
+}
+
+// Run implements messenger.Messenger
+func (m *Messenger) Run(ctx context.Context, dispatch messenger.DispatchFunc) error {
+	return slash.Serve(ctx, m.cfg.Listen, m.cfg.Path, &commandHandler{
+		messenger: m,
+		dispatch:  dispatch,
+	}, m.logger)
+}
+
+// commandHandler receives slash commands and passes them to dispatcher
+type commandHandler struct {
+	messenger *Messenger
+	dispatch  messenger.DispatchFunc
+}
+
+// ServeHTTP implements http.Handler
+func (h *commandHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.messenger.logger
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, slash.MaxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := verifySignature(h.messenger.cfg.SigningSecret, r.Header, body, time.Now()); err != nil {
+		logger.Warn("slash command with bad signature", zap.String("remote_addr", r.RemoteAddr), zap.Error(err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	text := form.Get("text")
+	command, args := messenger.ParseCommand(text)
+	if command == "" {
+		command = "help"
+	}
+	// replies are visible only to command author, so conversation is user in workspace
+	chatID := slash.ChatID(form.Get("team_id"), form.Get("user_id"))
+	reply := &reply{
+		channelID:   form.Get("channel_id"),
+		responseURL: form.Get("response_url"),
+	}
+	h.messenger.interactions.Add(chatID, reply)
+
+	err = h.dispatch(r.Context(), messenger.Update{Message: &messenger.Message{
+		ChatID:      chatID,
+		UserID:      slash.UserID(form.Get("team_id"), form.Get("user_id")),
+		Text:        text,
+		Command:     command,
+		Args:        args,
+		Interaction: true,
+	}})
+	if err != nil {
+		h.messenger.interactions.Remove(chatID, reply)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifySignature checks v0 signature of request body
+func verifySignature(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(timestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.Errorf("bad %s", timestampHeader)
+	}
+	if math.Abs(now.Sub(time.Unix(seconds, 0)).Seconds()) > maxClockSkew.Seconds() {
+		return errors.New("request is too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signatureVersion + ":" + timestamp + ":"))
+	mac.Write(body)
+	expected := signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get(signatureHeader))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (m *Messenger) takeReply(chatID int64) (*reply, error) {
+	target, ok := m.interactions.Take(chatID)
+	if !ok {
+		return nil, errors.Errorf("no pending slash command in chat %d", chatID)
+	}
+	return target.(*reply), nil
+}
+
+// SendText implements messenger.Messenger, text is visible only to command author
+func (m *Messenger) SendText(ctx context.Context, chatID int64, text string) error {
+	reply, err := m.takeReply(chatID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"response_type": "ephemeral",
+		"text":          text,
+	})
+	if err != nil {
+		return errors.Wrap(err, "can not encode response")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reply.responseURL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "can not create response request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return errors.Wrap(m.do(req, nil), "can not send response")
+}
+
+// SendButtons implements messenger.Messenger, keyboard is not supported and ignored
+func (m *Messenger) SendButtons(ctx context.Context, chatID int64, text string, _ messenger.Keyboard) error {
+	return m.SendText(ctx, chatID, text)
+}
+
+// SendPhoto implements messenger.Messenger, uploads photo to command channel,
+// keyboard is not supported and ignored
+func (m *Messenger) SendPhoto(ctx context.Context, chatID int64, photo []byte, caption string, _ messenger.Keyboard) error {
+	reply, err := m.takeReply(chatID)
+	if err != nil {
+		return err
+	}
+
+	var upload struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	params := url.Values{}
+	params.Set("filename", photoFileName)
+	params.Set("length", strconv.Itoa(len(photo)))
+	req, err := m.newAPIRequest(ctx, "files.getUploadURLExternal", "application/x-www-form-urlencoded", []byte(params.Encode()))
+	if err != nil {
+		return err
+	}
+	if err := m.do(req, &upload); err != nil {
+		return errors.Wrap(err, "can not call files.getUploadURLExternal")
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadURL, bytes.NewReader(photo))
+	if err != nil {
+		return errors.Wrap(err, "can not create upload request")
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if err := m.do(req, nil); err != nil {
+		return errors.Wrap(err, "can not upload photo")
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"files":           []map[string]string{{"id": upload.FileID, "title": photoFileName}},
+		"channel_id":      reply.channelID,
+		"initial_comment": caption,
+	})
+	if err != nil {
+		return errors.Wrap(err, "can not encode upload completion")
+	}
+	req, err = m.newAPIRequest(ctx, "files.completeUploadExternal", "application/json; charset=utf-8", payload)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(m.do(req, nil), "can not call files.completeUploadExternal")
+}
+
+// EditPhoto implements messenger.Messenger
+func (m *Messenger) EditPhoto(context.Context, int64, int, []byte, string, messenger.Keyboard) error {
+	return messenger.ErrNotSupported
+}
+
+// AnswerCallback implements messenger.Messenger
+func (m *Messenger) AnswerCallback(context.Context, *messenger.Callback, string) error {
+	return messenger.ErrNotSupported
+}
+
+func (m *Messenger) newAPIRequest(ctx context.Context, method, contentType string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.APIEndpoint+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not create %s request", method)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+m.cfg.BotToken)
+	return req, nil
+}
+
+// do sends request, if result is set checks web api "ok" field
+// and decodes response into result
+func (m *Messenger) do(req *http.Request, result interface{}) error {
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return errors.Wrap(err, "can not decode response")
+	}
+	if !status.OK {
+		return errors.Errorf("slack api error: %s", status.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
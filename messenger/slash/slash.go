@@ -0,0 +1,155 @@
+// Package slash contains helpers shared by slash command messengers,
+// where every command invocation is answered with exactly one reply.
+package slash
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxBodySize limit of slash command request body
+	MaxBodySize = 1 << 20
+
+	shutdownTimeout = 5 * time.Second
+)
+
+type interaction struct {
+	target  interface{}
+	expires time.Time
+}
+
+// Interactions pending slash command invocations by chat. Chat id is stable
+// conversation key, so chat settings survive between commands and restarts,
+// every invocation is queued in its chat and bot replies to the oldest one.
+// Updates of one chat are handled in order, so replies match invocations.
+type Interactions struct {
+	mu      sync.Mutex
+	pending map[int64][]*interaction
+	ttl     time.Duration
+}
+
+// NewInteractions creates registry, invocations not answered
+// for ttl are dropped
+func NewInteractions(ttl time.Duration) *Interactions {
+	return &Interactions{
+		pending: make(map[int64][]*interaction),
+		ttl:     ttl,
+	}
+}
+
+// Add queues reply target of invocation in chat
+func (i *Interactions) Add(chatID int64, target interface{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	for id, pending := range i.pending {
+		if alive := dropExpired(pending, now); len(alive) > 0 {
+			i.pending[id] = alive
+		} else {
+			delete(i.pending, id)
+		}
+	}
+
+	i.pending[chatID] = append(i.pending[chatID], &interaction{
+		target:  target,
+		expires: now.Add(i.ttl),
+	})
+}
+
+// Take returns and forgets the oldest not expired reply target of chat
+func (i *Interactions) Take(chatID int64) (interface{}, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pending := dropExpired(i.pending[chatID], time.Now())
+	if len(pending) == 0 {
+		delete(i.pending, chatID)
+		return nil, false
+	}
+	if len(pending) == 1 {
+		delete(i.pending, chatID)
+	} else {
+		i.pending[chatID] = pending[1:]
+	}
+	return pending[0].target, true
+}
+
+// Remove forgets reply target of invocation which was not accepted by bot
+func (i *Interactions) Remove(chatID int64, target interface{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pending := i.pending[chatID]
+	for n, p := range pending {
+		if p.target == target {
+			pending = append(pending[:n:n], pending[n+1:]...)
+			break
+		}
+	}
+	if len(pending) == 0 {
+		delete(i.pending, chatID)
+		return
+	}
+	i.pending[chatID] = pending
+}
+
+// dropExpired returns invocations which are not expired, queue is ordered by expiration
+func dropExpired(pending []*interaction, now time.Time) []*interaction {
+	for len(pending) > 0 && now.After(pending[0].expires) {
+		pending = pending[1:]
+	}
+	return pending
+}
+
+// UserID maps platform user identifiers to numeric user id
+func UserID(parts ...string) int64 {
+	return hashID(parts)
+}
+
+// ChatID maps platform identifiers of conversation to numeric chat id,
+// ids differ from user ids of the same identifiers
+func ChatID(parts ...string) int64 {
+	return hashID(append([]string{"chat"}, parts...))
+}
+
+func hashID(parts []string) int64 {
+	h := fnv.New64a()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return int64(h.Sum64() >> 1)
+}
+
+// Serve serves handler on path until ctx is done
+func Serve(ctx context.Context, listen, path string, handler http.Handler, logger *zap.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	server := &http.Server{
+		Addr:    listen,
+		Handler: mux,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		logger.Info("slash commands listening", zap.String("addr", listen), zap.String("path", path))
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,60 @@
+package slash
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInteractions(t *testing.T) {
+	i := NewInteractions(time.Minute)
+	i.Add(1, "first")
+	i.Add(2, "other chat")
+	i.Add(1, "second")
+	i.Add(1, "third")
+
+	i.Remove(1, "second")
+	for _, want := range []string{"first", "third"} {
+		target, ok := i.Take(1)
+		if !ok || target != want {
+			t.Errorf("Take = %v, %v, want %s", target, ok, want)
+		}
+	}
+	if target, ok := i.Take(1); ok {
+		t.Errorf("Take of answered chat = %v", target)
+	}
+	if target, ok := i.Take(2); !ok || target != "other chat" {
+		t.Errorf("Take of other chat = %v, %v", target, ok)
+	}
+}
+
+func TestInteractionsExpire(t *testing.T) {
+	i := NewInteractions(time.Millisecond)
+	i.Add(1, "expired")
+	time.Sleep(5 * time.Millisecond)
+	if target, ok := i.Take(1); ok {
+		t.Errorf("Take of expired invocation = %v", target)
+	}
+
+	i.Add(1, "expired")
+	time.Sleep(5 * time.Millisecond)
+	i.ttl = time.Minute
+	i.Add(1, "alive")
+	if target, ok := i.Take(1); !ok || target != "alive" {
+		t.Errorf("Take = %v, %v, want alive", target, ok)
+	}
+}
+
+func TestChatID(t *testing.T) {
+	if ChatID("T1", "U1") != ChatID("T1", "U1") {
+		t.Error("chat id is not stable")
+	}
+	if ChatID("T1", "U1") == ChatID("T1", "U2") || ChatID("T1U", "1") == ChatID("T1", "U1") {
+		t.Error("different conversations have the same chat id")
+	}
+	if ChatID("T1", "U1") == UserID("T1", "U1") {
+		t.Error("chat id equals user id")
+	}
+	if ChatID("T1", "U1") <= 0 {
+		t.Error("chat id is not positive")
+	}
+}
@@ -0,0 +1,99 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ratelimit"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// telegram allows about 30 messages per second for bot
+	// and 1 message per second for one chat
+	defaultGlobalSendRate = 30
+	defaultChatSendRate   = 1
+)
+
+// SenderConfig config of telegram send limits
+type SenderConfig struct {
+	// GlobalRate messages per second for all chats
+	GlobalRate float64 `json:"GlobalRate"`
+	// ChatRate messages per second for one chat
+	ChatRate float64 `json:"ChatRate"`
+}
+
+func (c SenderConfig) withDefaults() SenderConfig {
+	if c.GlobalRate == 0 {
+		c.GlobalRate = defaultGlobalSendRate
+	}
+	if c.ChatRate <= 0 {
+		c.ChatRate = defaultChatSendRate
+	}
+	return c
+}
+
+// sender sends messages respecting telegram limits,
+// callers wait in queue until their message may be sent
+type sender struct {
+	botAPI *tgbotapi.BotAPI
+	global *ratelimit.Bucket
+
+	chatInterval time.Duration
+	chatNextMu   sync.Mutex
+	chatNext     map[int64]time.Time
+}
+
+func newSender(botAPI *tgbotapi.BotAPI, cfg SenderConfig) *sender {
+	cfg = cfg.withDefaults()
+	return &sender{
+		botAPI:       botAPI,
+		global:       ratelimit.NewBucket(cfg.GlobalRate, int(cfg.GlobalRate)),
+		chatInterval: time.Duration(float64(time.Second) / cfg.ChatRate),
+		chatNext:     make(map[int64]time.Time),
+	}
+}
+
+// Send waits for chat and global limits and sends message
+func (s *sender) Send(ctx context.Context, chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if err := s.waitChat(ctx, chatID); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	if err := s.global.Wait(ctx); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	return s.botAPI.Send(c)
+}
+
+// waitChat reserves next send slot of chat and waits for it
+func (s *sender) waitChat(ctx context.Context, chatID int64) error {
+	now := time.Now()
+
+	s.chatNextMu.Lock()
+	for id, next := range s.chatNext {
+		if next.Before(now) {
+			delete(s.chatNext, id)
+		}
+	}
+	slot := now
+	if next, ok := s.chatNext[chatID]; ok && next.After(now) {
+		slot = next
+	}
+	s.chatNext[chatID] = slot.Add(s.chatInterval)
+	s.chatNextMu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,201 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const photoFileName = "chart.jpg"
+
+// Config telegram messenger config
+type Config struct {
+	Token string
+	// APIEndpoint overrides telegram api endpoint,
+	// e.g. "http://127.0.0.1:8081/bot%s/%s" for local fake server
+	APIEndpoint string
+	UpdatesMode string
+	Webhook     WebhookConfig
+	Sender      SenderConfig
+}
+
+// Validate checks webhook config in webhook mode,
+// secret token is required so only telegram can deliver updates
+func (c Config) Validate() error {
+	if c.UpdatesMode != UpdatesModeWebhook {
+		return nil
+	}
+	if c.Webhook.SecretToken == "" {
+		return errors.New("Webhook.SecretToken is required in webhook mode")
+	}
+	return c.Webhook.Validate()
+}
+
+// Messenger telegram implementation of messenger.Messenger
+type Messenger struct {
+	botAPI *tgbotapi.BotAPI
+	sender *sender
+	cfg    Config
+	logger *zap.Logger
+}
+
+var _ messenger.Messenger = (*Messenger)(nil)
+
+// New creates telegram messenger
+func New(cfg Config, logger *zap.Logger) (*Messenger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	apiEndpoint := tgbotapi.APIEndpoint
+	if cfg.APIEndpoint != "" {
+		apiEndpoint = cfg.APIEndpoint
+	}
+	botAPI, err := tgbotapi.NewBotAPIWithAPIEndpoint(cfg.Token, apiEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not initialize telegram api")
+	}
+
+	return &Messenger{
+		botAPI: botAPI,
+		sender: newSender(botAPI, cfg.Sender),
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+// Run implements messenger.Messenger
+func (m *Messenger) Run(ctx context.Context, dispatch messenger.DispatchFunc) error {
+	if m.cfg.UpdatesMode == UpdatesModeWebhook {
+		return m.runWebhook(ctx, m.cfg.Webhook, dispatch)
+	}
+	m.runPolling(ctx, dispatch)
+	return nil
+}
+
+func (m *Messenger) runPolling(ctx context.Context, dispatch messenger.DispatchFunc) {
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+
+	updates := m.botAPI.GetUpdatesChan(u)
+	for {
+		select {
+		case <-ctx.Done():
+			m.botAPI.StopReceivingUpdates()
+			return
+		case update := <-updates:
+			converted, ok := convertUpdate(update)
+			if !ok {
+				continue
+			}
+			if err := dispatch(ctx, converted); err != nil {
+				m.botAPI.StopReceivingUpdates()
+				return
+			}
+		}
+	}
+}
+
+// convertUpdate converts supported telegram updates
+func convertUpdate(update tgbotapi.Update) (messenger.Update, bool) {
+	if query := update.CallbackQuery; query != nil {
+		callback := &messenger.Callback{
+			ID:   query.ID,
+			Data: query.Data,
+		}
+		if query.From != nil {
+			callback.UserID = query.From.ID
+			callback.Lang = query.From.LanguageCode
+		}
+		if query.Message != nil {
+			callback.ChatID = query.Message.Chat.ID
+			callback.MessageID = query.Message.MessageID
+		}
+		return messenger.Update{Callback: callback}, true
+	}
+
+	if msg := update.Message; msg != nil {
+		message := &messenger.Message{
+			ChatID:  msg.Chat.ID,
+			Text:    msg.Text,
+			Command: msg.Command(),
+			Args:    msg.CommandArguments(),
+		}
+		if msg.From != nil {
+			message.UserID = msg.From.ID
+			message.Lang = msg.From.LanguageCode
+		}
+		return messenger.Update{Message: message}, true
+	}
+
+	return messenger.Update{}, false
+}
+
+// SendText implements messenger.Messenger
+func (m *Messenger) SendText(ctx context.Context, chatID int64, text string) error {
+	_, err := m.sender.Send(ctx, chatID, tgbotapi.NewMessage(chatID, text))
+	return err
+}
+
+// SendButtons implements messenger.Messenger
+func (m *Messenger) SendButtons(ctx context.Context, chatID int64, text string, keyboard messenger.Keyboard) error {
+	resp := tgbotapi.NewMessage(chatID, text)
+	if markup := keyboardMarkup(keyboard); markup != nil {
+		resp.ReplyMarkup = *markup
+	}
+	_, err := m.sender.Send(ctx, chatID, resp)
+	return err
+}
+
+// SendPhoto implements messenger.Messenger
+func (m *Messenger) SendPhoto(ctx context.Context, chatID int64, photo []byte, caption string, keyboard messenger.Keyboard) error {
+	resp := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: photoFileName, Bytes: photo})
+	resp.Caption = caption
+	if markup := keyboardMarkup(keyboard); markup != nil {
+		resp.ReplyMarkup = *markup
+	}
+	_, err := m.sender.Send(ctx, chatID, resp)
+	return err
+}
+
+// EditPhoto implements messenger.Messenger
+func (m *Messenger) EditPhoto(ctx context.Context, chatID int64, messageID int, photo []byte, caption string, keyboard messenger.Keyboard) error {
+	media := tgbotapi.NewInputMediaPhoto(tgbotapi.FileBytes{Name: photoFileName, Bytes: photo})
+	media.Caption = caption
+	edit := tgbotapi.EditMessageMediaConfig{
+		BaseEdit: tgbotapi.BaseEdit{
+			ChatID:      chatID,
+			MessageID:   messageID,
+			ReplyMarkup: keyboardMarkup(keyboard),
+		},
+		Media: media,
+	}
+	_, err := m.sender.Send(ctx, chatID, edit)
+	return err
+}
+
+// AnswerCallback implements messenger.Messenger
+func (m *Messenger) AnswerCallback(ctx context.Context, callback *messenger.Callback, text string) error {
+	_, err := m.botAPI.Request(tgbotapi.NewCallback(callback.ID, text))
+	return err
+}
+
+func keyboardMarkup(keyboard messenger.Keyboard) *tgbotapi.InlineKeyboardMarkup {
+	if len(keyboard) == 0 {
+		return nil
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(keyboard))
+	for _, row := range keyboard {
+		buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(row))
+		for _, button := range row {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(button.Text, button.Data))
+		}
+		rows = append(rows, buttons)
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &markup
+}
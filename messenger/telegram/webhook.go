@@ -0,0 +1,160 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// UpdatesModePolling receive updates with getUpdates long polling
+	UpdatesModePolling = "polling"
+	// UpdatesModeWebhook receive updates on http listener
+	UpdatesModeWebhook = "webhook"
+
+	secretTokenHeader      = "X-Telegram-Bot-Api-Secret-Token"
+	maxWebhookBodySize     = 1 << 20
+	webhookShutdownTimeout = 10 * time.Second
+)
+
+// WebhookConfig config of webhook mode
+type WebhookConfig struct {
+	// Listen address of http listener
+	Listen string `json:"Listen"`
+	// Path of http handler receiving updates
+	Path string `json:"Path"`
+	// SecretToken expected in X-Telegram-Bot-Api-Secret-Token header, required in webhook mode
+	SecretToken string `json:"SecretToken"`
+	// CertFile and KeyFile enable TLS on listener
+	CertFile string `json:"CertFile"`
+	KeyFile  string `json:"KeyFile"`
+	// URL public url registered with setWebhook when Register is set,
+	// with several replicas it is enough for one of them to register
+	URL      string `json:"URL"`
+	Register bool   `json:"Register"`
+}
+
+// Validate checks webhook config consistency
+func (c WebhookConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("CertFile and KeyFile must be set together")
+	}
+	if c.Register && c.URL == "" {
+		return errors.New("URL is required to register webhook")
+	}
+	return nil
+}
+
+func (c WebhookConfig) path() string {
+	if c.Path == "" {
+		return "/"
+	}
+	return c.Path
+}
+
+// webhookHandler receives telegram updates and passes them to dispatcher
+type webhookHandler struct {
+	secretToken string
+	dispatch    messenger.DispatchFunc
+	logger      *zap.Logger
+}
+
+// ServeHTTP implements http.Handler
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(secretTokenHeader)
+	if h.secretToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.secretToken)) != 1 {
+		h.logger.Warn("webhook request with bad secret token", zap.String("remote_addr", r.RemoteAddr))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodySize)).Decode(&update); err != nil {
+		h.logger.Warn("can not decode webhook update", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	converted, ok := convertUpdate(update)
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err := h.dispatch(r.Context(), converted); err != nil {
+		// telegram retries delivery on non 2xx status
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// registerWebhook calls setWebhook with url, secret token and self-signed certificate
+func (m *Messenger) registerWebhook(cfg WebhookConfig) error {
+	params := make(tgbotapi.Params)
+	params["url"] = cfg.URL
+	params["secret_token"] = cfg.SecretToken
+
+	var err error
+	if cfg.CertFile != "" {
+		_, err = m.botAPI.UploadFiles("setWebhook", params, []tgbotapi.RequestFile{
+			{Name: "certificate", Data: tgbotapi.FilePath(cfg.CertFile)},
+		})
+	} else {
+		_, err = m.botAPI.MakeRequest("setWebhook", params)
+	}
+	return err
+}
+
+// runWebhook serves webhook until ctx is done
+func (m *Messenger) runWebhook(ctx context.Context, cfg WebhookConfig, dispatch messenger.DispatchFunc) error {
+	if cfg.Register {
+		if err := m.registerWebhook(cfg); err != nil {
+			return errors.Wrap(err, "can not register webhook")
+		}
+		m.logger.Info("webhook registered", zap.String("url", cfg.URL))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.path(), &webhookHandler{
+		secretToken: cfg.SecretToken,
+		dispatch:    dispatch,
+		logger:      m.logger,
+	})
+	server := &http.Server{
+		Addr:    cfg.Listen,
+		Handler: mux,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		m.logger.Info("webhook listening", zap.String("addr", cfg.Listen), zap.String("path", cfg.path()))
+		if cfg.CertFile != "" {
+			errs <- server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+		} else {
+			errs <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,200 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	testToken  = "123:token"
+	testSecret = "secret"
+	testUpdate = `{"update_id": 1, "message": {"message_id": 2, "date": 0,
+		"chat": {"id": 42, "type": "private"}, "from": {"id": 7, "first_name": "u", "language_code": "ru"},
+		"text": "/sber 1d", "entities": [{"type": "bot_command", "offset": 0, "length": 5}]}}`
+)
+
+// fakeTelegram api server answering getMe and setWebhook
+type fakeTelegram struct {
+	mu      sync.Mutex
+	webhook map[string]string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/bot" + testToken + "/getMe":
+		w.Write([]byte(`{"ok": true, "result": {"id": 1, "is_bot": true, "first_name": "bot", "username": "bot"}}`))
+	case "/bot" + testToken + "/setWebhook":
+		r.ParseForm()
+		f.mu.Lock()
+		f.webhook = map[string]string{"url": r.FormValue("url"), "secret_token": r.FormValue("secret_token")}
+		f.mu.Unlock()
+		w.Write([]byte(`{"ok": true, "result": true}`))
+	default:
+		w.Write([]byte(`{"ok": false, "error_code": 404, "description": "Not Found"}`))
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestWebhook(t *testing.T) {
+	api := &fakeTelegram{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	addr := freeAddr(t)
+	m, err := New(Config{
+		Token:       testToken,
+		APIEndpoint: server.URL + "/bot%s/%s",
+		UpdatesMode: UpdatesModeWebhook,
+		Webhook: WebhookConfig{
+			Listen:      addr,
+			Path:        "/updates",
+			SecretToken: testSecret,
+			URL:         "https://bot.example.com/updates",
+			Register:    true,
+		},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make(chan messenger.Update, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(ctx, func(ctx context.Context, update messenger.Update) error {
+			updates <- update
+			return nil
+		})
+	}()
+
+	var resp *http.Response
+	for attempt := 0; attempt < 50; attempt++ {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/updates", strings.NewReader(testUpdate))
+		req.Header.Set(secretTokenHeader, testSecret)
+		if resp, err = http.DefaultClient.Do(req); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+
+	select {
+	case update := <-updates:
+		msg := update.Message
+		if msg == nil || msg.ChatID != 42 || msg.UserID != 7 || msg.Command != "sber" || msg.Args != "1d" || msg.Lang != "ru" {
+			t.Errorf("dispatched %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("update is not dispatched")
+	}
+
+	api.mu.Lock()
+	if api.webhook["url"] != "https://bot.example.com/updates" || api.webhook["secret_token"] != testSecret {
+		t.Errorf("setWebhook params %v", api.webhook)
+	}
+	api.mu.Unlock()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v after shutdown", err)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var dispatchErr error
+	dispatched := 0
+	handler := &webhookHandler{
+		secretToken: testSecret,
+		dispatch: func(ctx context.Context, update messenger.Update) error {
+			dispatched++
+			return dispatchErr
+		},
+		logger: zap.NewNop(),
+	}
+
+	for _, tc := range []struct {
+		name       string
+		method     string
+		token      string
+		body       string
+		err        error
+		status     int
+		dispatched bool
+	}{
+		{"no token", http.MethodPost, "", testUpdate, nil, http.StatusUnauthorized, false},
+		{"bad token", http.MethodPost, "secreT", testUpdate, nil, http.StatusUnauthorized, false},
+		{"get", http.MethodGet, testSecret, "", nil, http.StatusMethodNotAllowed, false},
+		{"bad body", http.MethodPost, testSecret, "{", nil, http.StatusBadRequest, false},
+		{"unsupported update", http.MethodPost, testSecret, `{"update_id": 1}`, nil, http.StatusOK, false},
+		{"dispatched", http.MethodPost, testSecret, testUpdate, nil, http.StatusOK, true},
+		{"not accepted", http.MethodPost, testSecret, testUpdate, errors.New("stopped"), http.StatusServiceUnavailable, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dispatched, dispatchErr = 0, tc.err
+			req := httptest.NewRequest(tc.method, "/updates", strings.NewReader(tc.body))
+			if tc.token != "" {
+				req.Header.Set(secretTokenHeader, tc.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if (dispatched == 1) != tc.dispatched {
+				t.Errorf("dispatched %d times", dispatched)
+			}
+		})
+	}
+
+	// handler without configured token accepts nothing
+	handler.secretToken = ""
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(testUpdate)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status without configured token = %d, want 401", rec.Code)
+	}
+}
+
+func TestWebhookConfigValidate(t *testing.T) {
+	cfg := Config{UpdatesMode: UpdatesModeWebhook, Webhook: WebhookConfig{Listen: ":8443"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("no error for webhook mode without secret token")
+	}
+	cfg.Webhook.SecretToken = testSecret
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	cfg.Webhook.CertFile = "cert.pem"
+	if err := cfg.Validate(); err == nil {
+		t.Error("no error for CertFile without KeyFile")
+	}
+	if err := (Config{UpdatesMode: UpdatesModePolling}).Validate(); err != nil {
+		t.Errorf("polling mode: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package vk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAPIEndpoint = "https://api.vk.com/method/"
+	defaultAPIVersion  = "5.131"
+)
+
+// APIError error returned by vk api
+type APIError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"error_msg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("vk api error %d: %s", e.Code, e.Message)
+}
+
+type apiResponse struct {
+	Response json.RawMessage `json:"response"`
+	Error    *APIError       `json:"error"`
+}
+
+// flexString decodes both json strings and numbers,
+// vk long poll returns ts in both forms
+type flexString string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *flexString) UnmarshalJSON(data []byte) error {
+	*s = flexString(strings.Trim(string(data), `"`))
+	return nil
+}
+
+// call calls vk api method and decodes response into result
+func (m *Messenger) call(ctx context.Context, method string, params url.Values, result interface{}) error {
+	params.Set("access_token", m.cfg.Token)
+	params.Set("v", m.cfg.APIVersion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.APIEndpoint+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return errors.Wrapf(err, "can not create %s request", method)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp apiResponse
+	if err := m.doJSON(req, &resp); err != nil {
+		return errors.Wrapf(err, "can not call %s", method)
+	}
+	if resp.Error != nil {
+		return errors.Wrapf(resp.Error, "can not call %s", method)
+	}
+	if result == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(resp.Response, result), "can not decode %s response", method)
+}
+
+func (m *Messenger) doJSON(req *http.Request, result interface{}) error {
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// uploadPhoto uploads photo for peer and returns attachment string
+func (m *Messenger) uploadPhoto(ctx context.Context, peerID int64, photo []byte) (string, error) {
+	var server struct {
+		UploadURL string `json:"upload_url"`
+	}
+	params := url.Values{}
+	params.Set("peer_id", strconv.FormatInt(peerID, 10))
+	if err := m.call(ctx, "photos.getMessagesUploadServer", params, &server); err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photo", photoFileName)
+	if err != nil {
+		return "", errors.Wrap(err, "can not create upload form")
+	}
+	if _, err := io.Copy(part, bytes.NewReader(photo)); err != nil {
+		return "", errors.Wrap(err, "can not write upload form")
+	}
+	if err := writer.Close(); err != nil {
+		return "", errors.Wrap(err, "can not close upload form")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.UploadURL, &body)
+	if err != nil {
+		return "", errors.Wrap(err, "can not create upload request")
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var uploaded struct {
+		Server int    `json:"server"`
+		Photo  string `json:"photo"`
+		Hash   string `json:"hash"`
+	}
+	if err := m.doJSON(req, &uploaded); err != nil {
+		return "", errors.Wrap(err, "can not upload photo")
+	}
+
+	var saved []struct {
+		ID        int64  `json:"id"`
+		OwnerID   int64  `json:"owner_id"`
+		AccessKey string `json:"access_key"`
+	}
+	params = url.Values{}
+	params.Set("server", strconv.Itoa(uploaded.Server))
+	params.Set("photo", uploaded.Photo)
+	params.Set("hash", uploaded.Hash)
+	if err := m.call(ctx, "photos.saveMessagesPhoto", params, &saved); err != nil {
+		return "", err
+	}
+	if len(saved) == 0 {
+		return "", errors.New("photos.saveMessagesPhoto returned no photos")
+	}
+
+	attachment := fmt.Sprintf("photo%d_%d", saved[0].OwnerID, saved[0].ID)
+	if saved[0].AccessKey != "" {
+		attachment += "_" + saved[0].AccessKey
+	}
+	return attachment, nil
+}
@@ -0,0 +1,384 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+	"github.com/Apakhov/stocks-bot/ratelimit"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	photoFileName = "chart.jpg"
+
+	longPollWait       = 25
+	longPollRetryDelay = 3 * time.Second
+	httpClientTimeout  = longPollWait*time.Second + 10*time.Second
+
+	// vk allows community to send 20 messages per second
+	defaultSendRate = 20
+
+	eventMessageNew   = "message_new"
+	eventMessageEvent = "message_event"
+
+	// vk inline keyboard limits
+	maxKeyboardRows    = 6
+	maxKeyboardButtons = 10
+	maxRowButtons      = 5
+)
+
+// Config vk messenger config
+type Config struct {
+	Token   string `json:"Token"`
+	GroupID int64  `json:"GroupID"`
+	// APIEndpoint overrides vk api endpoint, e.g. for local fake server
+	APIEndpoint string  `json:"APIEndpoint"`
+	APIVersion  string  `json:"APIVersion"`
+	SendRate    float64 `json:"SendRate"`
+}
+
+// Messenger vk community bot implementation of messenger.Messenger,
+// receives updates with bots long poll api
+type Messenger struct {
+	cfg    Config
+	client *http.Client
+	sends  *ratelimit.Bucket
+	logger *zap.Logger
+}
+
+var _ messenger.Messenger = (*Messenger)(nil)
+
+// New creates vk messenger
+func New(cfg Config, logger *zap.Logger) *Messenger {
+	if cfg.APIEndpoint == "" {
+		cfg.APIEndpoint = defaultAPIEndpoint
+	}
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = defaultAPIVersion
+	}
+	if cfg.SendRate == 0 {
+		cfg.SendRate = defaultSendRate
+	}
+
+	return &Messenger{
+		cfg:    cfg,
+		client: &http.Client{Timeout: httpClientTimeout},
+		sends:  ratelimit.NewBucket(cfg.SendRate, int(cfg.SendRate)),
+		logger: logger,
+	}
+}
+
+type longPollServer struct {
+	Key    string     `json:"key"`
+	Server string     `json:"server"`
+	TS     flexString `json:"ts"`
+}
+
+type longPollResponse struct {
+	TS      flexString `json:"ts"`
+	Failed  int        `json:"failed"`
+	Updates []struct {
+		Type   string          `json:"type"`
+		Object json.RawMessage `json:"object"`
+	} `json:"updates"`
+}
+
+type messageNew struct {
+	Message struct {
+		PeerID int64  `json:"peer_id"`
+		FromID int64  `json:"from_id"`
+		Text   string `json:"text"`
+	} `json:"message"`
+}
+
+type messageEvent struct {
+	UserID                int64  `json:"user_id"`
+	PeerID                int64  `json:"peer_id"`
+	EventID               string `json:"event_id"`
+	ConversationMessageID int    `json:"conversation_message_id"`
+	Payload               struct {
+		Data string `json:"d"`
+	} `json:"payload"`
+}
+
+// Run implements messenger.Messenger
+func (m *Messenger) Run(ctx context.Context, dispatch messenger.DispatchFunc) error {
+	var server *longPollServer
+	// keepTS is ts to continue from after key renewal
+	var keepTS flexString
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if server == nil {
+			var err error
+			server, err = m.getLongPollServer(ctx)
+			if err != nil {
+				m.logger.Warn("can not get long poll server", zap.Error(err))
+				sleep(ctx, longPollRetryDelay)
+				continue
+			}
+			if keepTS != "" {
+				server.TS, keepTS = keepTS, ""
+			}
+		}
+
+		resp, err := m.poll(ctx, server)
+		if err != nil {
+			if ctx.Err() == nil {
+				m.logger.Warn("long poll request failed", zap.Error(err))
+				sleep(ctx, longPollRetryDelay)
+			}
+			continue
+		}
+
+		switch resp.Failed {
+		case 0:
+		case 1:
+			// history is outdated, continue from new ts
+			server.TS = resp.TS
+			continue
+		case 2:
+			// key expired, request new key and continue from the same ts
+			server, keepTS = nil, server.TS
+			continue
+		default:
+			// information lost, request new key and ts
+			server = nil
+			continue
+		}
+
+		for _, event := range resp.Updates {
+			update, ok := m.convertUpdate(event.Type, event.Object)
+			if !ok {
+				continue
+			}
+			if err := dispatch(ctx, update); err != nil {
+				return nil
+			}
+		}
+		server.TS = resp.TS
+	}
+}
+
+func (m *Messenger) getLongPollServer(ctx context.Context) (*longPollServer, error) {
+	params := url.Values{}
+	params.Set("group_id", strconv.FormatInt(m.cfg.GroupID, 10))
+
+	var server longPollServer
+	if err := m.call(ctx, "groups.getLongPollServer", params, &server); err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+func (m *Messenger) poll(ctx context.Context, server *longPollServer) (*longPollResponse, error) {
+	params := url.Values{}
+	params.Set("act", "a_check")
+	params.Set("key", server.Key)
+	params.Set("ts", string(server.TS))
+	params.Set("wait", strconv.Itoa(longPollWait))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.Server+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not create long poll request")
+	}
+
+	var resp longPollResponse
+	if err := m.doJSON(req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (m *Messenger) convertUpdate(eventType string, object json.RawMessage) (messenger.Update, bool) {
+	switch eventType {
+	case eventMessageNew:
+		var event messageNew
+		if err := json.Unmarshal(object, &event); err != nil {
+			m.logger.Warn("can not decode message_new", zap.Error(err))
+			return messenger.Update{}, false
+		}
+		command, args := messenger.ParseCommand(event.Message.Text)
+		return messenger.Update{Message: &messenger.Message{
+			ChatID:  event.Message.PeerID,
+			UserID:  event.Message.FromID,
+			Text:    event.Message.Text,
+			Command: command,
+			Args:    args,
+		}}, true
+	case eventMessageEvent:
+		var event messageEvent
+		if err := json.Unmarshal(object, &event); err != nil {
+			m.logger.Warn("can not decode message_event", zap.Error(err))
+			return messenger.Update{}, false
+		}
+		return messenger.Update{Callback: &messenger.Callback{
+			ID:        event.EventID,
+			ChatID:    event.PeerID,
+			UserID:    event.UserID,
+			MessageID: event.ConversationMessageID,
+			Data:      event.Payload.Data,
+		}}, true
+	default:
+		return messenger.Update{}, false
+	}
+}
+
+// SendText implements messenger.Messenger
+func (m *Messenger) SendText(ctx context.Context, chatID int64, text string) error {
+	return m.send(ctx, chatID, text, "", nil)
+}
+
+// SendButtons implements messenger.Messenger
+func (m *Messenger) SendButtons(ctx context.Context, chatID int64, text string, keyboard messenger.Keyboard) error {
+	return m.send(ctx, chatID, text, "", keyboard)
+}
+
+// SendPhoto implements messenger.Messenger
+func (m *Messenger) SendPhoto(ctx context.Context, chatID int64, photo []byte, caption string, keyboard messenger.Keyboard) error {
+	attachment, err := m.uploadPhoto(ctx, chatID, photo)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, chatID, caption, attachment, keyboard)
+}
+
+func (m *Messenger) send(ctx context.Context, chatID int64, text, attachment string, keyboard messenger.Keyboard) error {
+	params := url.Values{}
+	params.Set("peer_id", strconv.FormatInt(chatID, 10))
+	params.Set("random_id", strconv.FormatInt(int64(rand.Int31()), 10))
+	params.Set("message", text)
+	if attachment != "" {
+		params.Set("attachment", attachment)
+	}
+	if err := setKeyboard(params, keyboard); err != nil {
+		return err
+	}
+
+	if err := m.sends.Wait(ctx); err != nil {
+		return err
+	}
+	return m.call(ctx, "messages.send", params, nil)
+}
+
+// EditPhoto implements messenger.Messenger, messageID is conversation message id
+func (m *Messenger) EditPhoto(ctx context.Context, chatID int64, messageID int, photo []byte, caption string, keyboard messenger.Keyboard) error {
+	attachment, err := m.uploadPhoto(ctx, chatID, photo)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("peer_id", strconv.FormatInt(chatID, 10))
+	params.Set("conversation_message_id", strconv.Itoa(messageID))
+	params.Set("message", caption)
+	params.Set("attachment", attachment)
+	if err := setKeyboard(params, keyboard); err != nil {
+		return err
+	}
+
+	if err := m.sends.Wait(ctx); err != nil {
+		return err
+	}
+	return m.call(ctx, "messages.edit", params, nil)
+}
+
+// AnswerCallback implements messenger.Messenger
+func (m *Messenger) AnswerCallback(ctx context.Context, callback *messenger.Callback, text string) error {
+	params := url.Values{}
+	params.Set("event_id", callback.ID)
+	params.Set("user_id", strconv.FormatInt(callback.UserID, 10))
+	params.Set("peer_id", strconv.FormatInt(callback.ChatID, 10))
+	if text != "" {
+		eventData, err := json.Marshal(map[string]string{"type": "show_snackbar", "text": text})
+		if err != nil {
+			return errors.Wrap(err, "can not encode event data")
+		}
+		params.Set("event_data", string(eventData))
+	}
+	return m.call(ctx, "messages.sendMessageEventAnswer", params, nil)
+}
+
+type keyboardButton struct {
+	Action struct {
+		Type    string `json:"type"`
+		Label   string `json:"label"`
+		Payload string `json:"payload"`
+	} `json:"action"`
+}
+
+// setKeyboard adds inline keyboard with callback buttons to params
+func setKeyboard(params url.Values, keyboard messenger.Keyboard) error {
+	if len(keyboard) == 0 {
+		return nil
+	}
+
+	keyboard = fitKeyboard(keyboard)
+	rows := make([][]keyboardButton, 0, len(keyboard))
+	for _, row := range keyboard {
+		buttons := make([]keyboardButton, 0, len(row))
+		for _, button := range row {
+			payload, err := json.Marshal(map[string]string{"d": button.Data})
+			if err != nil {
+				return errors.Wrap(err, "can not encode button payload")
+			}
+			var b keyboardButton
+			b.Action.Type = "callback"
+			b.Action.Label = button.Text
+			b.Action.Payload = string(payload)
+			buttons = append(buttons, b)
+		}
+		rows = append(rows, buttons)
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"inline":  true,
+		"buttons": rows,
+	})
+	if err != nil {
+		return errors.Wrap(err, "can not encode keyboard")
+	}
+	params.Set("keyboard", string(encoded))
+	return nil
+}
+
+// fitKeyboard splits rows wider than vk allows and drops trailing rows
+// that do not fit into inline keyboard limits
+func fitKeyboard(keyboard messenger.Keyboard) messenger.Keyboard {
+	fitted := make(messenger.Keyboard, 0, maxKeyboardRows)
+	buttons := 0
+	for _, row := range keyboard {
+		for len(row) > 0 {
+			n := len(row)
+			if n > maxRowButtons {
+				n = maxRowButtons
+			}
+			if len(fitted) == maxKeyboardRows || buttons+n > maxKeyboardButtons {
+				return fitted
+			}
+			fitted = append(fitted, row[:n])
+			buttons += n
+			row = row[n:]
+		}
+	}
+	return fitted
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
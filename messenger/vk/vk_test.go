@@ -0,0 +1,244 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Apakhov/stocks-bot/messenger"
+
+	"go.uber.org/zap"
+)
+
+// fakeVK is vk api, long poll and upload server in one
+type fakeVK struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	servers  []string // longPollServer responses, one per getLongPollServer call
+	polls    []string // long poll responses, one per poll
+	polled   []string // key and ts of each poll
+	photo    []byte
+	sent     map[string]url.Values
+	uploaded bool
+}
+
+func newFakeVK(t *testing.T) *fakeVK {
+	f := &fakeVK{t: t, sent: map[string]url.Values{}}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeVK) messenger() *Messenger {
+	return New(Config{Token: "token", GroupID: 1, APIEndpoint: f.srv.URL + "/method/"}, zap.NewNop())
+}
+
+func (f *fakeVK) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/poll":
+		query := r.URL.Query()
+		f.polled = append(f.polled, query.Get("key")+"@"+query.Get("ts"))
+		if len(f.polls) == 0 {
+			fmt.Fprint(w, `{"failed":3}`)
+			return
+		}
+		fmt.Fprint(w, f.polls[0])
+		f.polls = f.polls[1:]
+	case r.URL.Path == "/upload":
+		file, _, err := r.FormFile("photo")
+		if err != nil {
+			f.t.Errorf("upload without photo: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.photo, _ = io.ReadAll(file)
+		fmt.Fprint(w, `{"server":7,"photo":"[{}]","hash":"abc"}`)
+	case strings.HasPrefix(r.URL.Path, "/method/"):
+		method := strings.TrimPrefix(r.URL.Path, "/method/")
+		if err := r.ParseForm(); err != nil {
+			f.t.Errorf("can not parse %s form: %v", method, err)
+		}
+		f.sent[method] = r.PostForm
+		if r.PostForm.Get("access_token") != "token" {
+			f.t.Errorf("%s called without access token", method)
+		}
+		f.respond(w, method, r.PostForm)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeVK) respond(w http.ResponseWriter, method string, params url.Values) {
+	switch method {
+	case "groups.getLongPollServer":
+		if len(f.servers) == 0 {
+			fmt.Fprint(w, `{"error":{"error_code":5,"error_msg":"no more servers"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"response":`+f.servers[0]+`}`, f.srv.URL+"/poll")
+		f.servers = f.servers[1:]
+	case "photos.getMessagesUploadServer":
+		fmt.Fprintf(w, `{"response":{"upload_url":%q}}`, f.srv.URL+"/upload")
+	case "photos.saveMessagesPhoto":
+		f.uploaded = params.Get("server") == "7" && params.Get("photo") == "[{}]" && params.Get("hash") == "abc"
+		fmt.Fprint(w, `{"response":[{"id":2,"owner_id":-1,"access_key":"key"}]}`)
+	default:
+		fmt.Fprint(w, `{"response":1}`)
+	}
+}
+
+func TestRunLongPollFailed(t *testing.T) {
+	f := newFakeVK(t)
+	f.servers = []string{
+		`{"key":"k1","server":%q,"ts":"10"}`,
+		`{"key":"k2","server":%q,"ts":"99"}`,
+		`{"key":"k3","server":%q,"ts":30}`,
+	}
+	f.polls = []string{
+		// history outdated: continue with new ts and the same key
+		`{"failed":1,"ts":20}`,
+		// key expired: new key, keep ts
+		`{"failed":2}`,
+		// information lost: new key and ts
+		`{"failed":3}`,
+		`{"ts":"31","updates":[{"type":"message_new","object":{"message":{"peer_id":5,"from_id":6,"text":"/sber 1d"}}}]}`,
+	}
+
+	var got []messenger.Update
+	stop := errors.New("stop")
+	err := f.messenger().Run(context.Background(), func(ctx context.Context, update messenger.Update) error {
+		got = append(got, update)
+		return stop
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	wantPolled := []string{"k1@10", "k1@20", "k2@20", "k3@30"}
+	if !reflect.DeepEqual(f.polled, wantPolled) {
+		t.Errorf("polled = %v, want %v", f.polled, wantPolled)
+	}
+	if len(got) != 1 || got[0].Message == nil {
+		t.Fatalf("dispatched %+v, want one message", got)
+	}
+	message := got[0].Message
+	if message.ChatID != 5 || message.UserID != 6 || message.Command != "sber" || message.Args != "1d" {
+		t.Errorf("message = %+v", message)
+	}
+}
+
+func TestSendPhoto(t *testing.T) {
+	f := newFakeVK(t)
+	photo := []byte("jpeg bytes")
+	keyboard := messenger.Keyboard{{{Text: "1D", Data: "p:1d"}}}
+
+	if err := f.messenger().SendPhoto(context.Background(), 5, photo, "caption", keyboard); err != nil {
+		t.Fatalf("SendPhoto() error = %v", err)
+	}
+
+	if string(f.photo) != string(photo) {
+		t.Errorf("uploaded photo = %q, want %q", f.photo, photo)
+	}
+	if !f.uploaded {
+		t.Errorf("photos.saveMessagesPhoto params = %v, want upload response", f.sent["photos.saveMessagesPhoto"])
+	}
+	send := f.sent["messages.send"]
+	if send == nil {
+		t.Fatal("messages.send not called")
+	}
+	if got := send.Get("attachment"); got != "photo-1_2_key" {
+		t.Errorf("attachment = %q, want photo-1_2_key", got)
+	}
+	if got := send.Get("peer_id"); got != "5" {
+		t.Errorf("peer_id = %q, want 5", got)
+	}
+	if got := send.Get("message"); got != "caption" {
+		t.Errorf("message = %q, want caption", got)
+	}
+	if send.Get("keyboard") == "" {
+		t.Error("keyboard not sent")
+	}
+}
+
+func TestSetKeyboard(t *testing.T) {
+	params := url.Values{}
+	keyboard := messenger.Keyboard{{{Text: "1D", Data: "p:1d"}, {Text: "1W", Data: "p:1w"}}}
+	if err := setKeyboard(params, keyboard); err != nil {
+		t.Fatalf("setKeyboard() error = %v", err)
+	}
+
+	var decoded struct {
+		Inline  bool               `json:"inline"`
+		Buttons [][]keyboardButton `json:"buttons"`
+	}
+	if err := json.Unmarshal([]byte(params.Get("keyboard")), &decoded); err != nil {
+		t.Fatalf("can not decode keyboard %q: %v", params.Get("keyboard"), err)
+	}
+	if !decoded.Inline || len(decoded.Buttons) != 1 || len(decoded.Buttons[0]) != 2 {
+		t.Fatalf("keyboard = %+v", decoded)
+	}
+	button := decoded.Buttons[0][1].Action
+	if button.Type != "callback" || button.Label != "1W" || button.Payload != `{"d":"p:1w"}` {
+		t.Errorf("button = %+v", button)
+	}
+
+	empty := url.Values{}
+	if err := setKeyboard(empty, nil); err != nil || empty.Get("keyboard") != "" {
+		t.Errorf("setKeyboard(nil) = %v, keyboard %q, want no keyboard", err, empty.Get("keyboard"))
+	}
+}
+
+func buttonRow(n int) []messenger.Button {
+	row := make([]messenger.Button, n)
+	for i := range row {
+		row[i] = messenger.Button{Text: fmt.Sprint(i), Data: fmt.Sprint(i)}
+	}
+	return row
+}
+
+func TestFitKeyboard(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     []int
+		wantRows []int
+	}{
+		{"fits", []int{3, 2}, []int{3, 2}},
+		{"wide row split", []int{7}, []int{5, 2}},
+		{"too many rows", []int{1, 1, 1, 1, 1, 1, 1, 1}, []int{1, 1, 1, 1, 1, 1}},
+		{"too many buttons", []int{4, 5, 4, 3}, []int{4, 5}},
+		{"chart keyboard", []int{4, 4, 2, 3, 3}, []int{4, 4, 2}},
+		{"split tail trimmed", []int{4, 7}, []int{4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyboard := make(messenger.Keyboard, 0, len(tt.rows))
+			for _, n := range tt.rows {
+				keyboard = append(keyboard, buttonRow(n))
+			}
+
+			fitted := fitKeyboard(keyboard)
+			gotRows := make([]int, 0, len(fitted))
+			for _, row := range fitted {
+				gotRows = append(gotRows, len(row))
+			}
+			if !reflect.DeepEqual(gotRows, tt.wantRows) {
+				t.Errorf("fitKeyboard() rows = %v, want %v", gotRows, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package ohlc
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrNoRates error for conversion without exchange rates
+var ErrNoRates = errors.New("no exchange rates")
+
+// rateAt returns rate candle aligned to ts: candle with the same timestamp,
+// otherwise close of last earlier candle or open of first candle as flat candle
+func rateAt(rates []TOHLCV, ts int64) OHLCV {
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].Timestamp >= ts
+	})
+	switch {
+	case i < len(rates) && rates[i].Timestamp == ts:
+		return rates[i].OHLCV
+	case i > 0:
+		c := rates[i-1].Close
+		return OHLCV{Open: c, High: c, Low: c, Close: c}
+	default:
+		o := rates[0].Open
+		return OHLCV{Open: o, High: o, Low: o, Close: o}
+	}
+}
+
+// multiply returns candles with prices multiplied by aligned rates,
+// high and low are widened to contain open and close
+func multiply(candles, rates []TOHLCV) []TOHLCV {
+	result := make([]TOHLCV, 0, len(candles))
+	for _, candle := range candles {
+		rate := rateAt(rates, candle.Timestamp)
+		converted := OHLCV{
+			Open:   candle.Open * rate.Open,
+			High:   candle.High * rate.High,
+			Low:    candle.Low * rate.Low,
+			Close:  candle.Close * rate.Close,
+			Volume: candle.Volume,
+		}
+		converted.High = math.Max(converted.High, math.Max(converted.Open, converted.Close))
+		converted.Low = math.Min(converted.Low, math.Min(converted.Open, converted.Close))
+		result = append(result, TOHLCV{Timestamp: candle.Timestamp, OHLCV: converted})
+	}
+	return result
+}
+
+// InvertRates returns rates of reverse currency pair, e.g. RUBUSD from USDRUB
+func InvertRates(rates []TOHLCV) []TOHLCV {
+	result := make([]TOHLCV, 0, len(rates))
+	for _, rate := range rates {
+		result = append(result, TOHLCV{
+			Timestamp: rate.Timestamp,
+			OHLCV: OHLCV{
+				Open:   1 / rate.Open,
+				High:   1 / rate.Low,
+				Low:    1 / rate.High,
+				Close:  1 / rate.Close,
+				Volume: rate.Volume,
+			},
+		})
+	}
+	return result
+}
+
+// CrossRates returns rates of a base in b base aligned by timestamps of a,
+// e.g. USDEUR from USDRUB and EURRUB
+func CrossRates(a, b []TOHLCV) ([]TOHLCV, error) {
+	if len(b) == 0 {
+		return nil, ErrNoRates
+	}
+	return multiply(a, InvertRates(b)), nil
+}
+
+// Convert returns copy of data with prices multiplied by rates
+// aligned per timestamp, rates are prices of data currency in currency
+func Convert(data *CandlesticksData, rates []TOHLCV, currency string) (*CandlesticksData, error) {
+	if len(rates) == 0 && len(data.TOHLCs) > 0 {
+		return nil, ErrNoRates
+	}
+	return &CandlesticksData{
+		Ticker:   data.Ticker,
+		Name:     data.Name,
+		Currency: currency,
+		Interval: data.Interval,
+		TOHLCs:   multiply(data.TOHLCs, rates),
+	}, nil
+}
@@ -0,0 +1,13 @@
+package ohlc
+
+// EquityPoint value and cost basis of portfolio at timestamp
+type EquityPoint struct {
+	Timestamp int64
+	Value     float64
+	Cost      float64
+}
+
+// PnL returns unrealized profit at point
+func (p EquityPoint) PnL() float64 {
+	return p.Value - p.Cost
+}
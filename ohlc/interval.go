@@ -0,0 +1,196 @@
+package ohlc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBadInterval error for interval which can not be parsed
+var ErrBadInterval = errors.New("can not parse interval")
+
+// IntervalUnit unit of candle interval
+type IntervalUnit int
+
+// Interval units
+const (
+	UnitMinute IntervalUnit = iota + 1
+	UnitHour
+	UnitDay
+	UnitWeek
+	UnitMonth
+)
+
+var intervalUnits = []struct {
+	Unit  IntervalUnit
+	Code  string
+	Title string
+}{
+	{Unit: UnitMinute, Code: "min", Title: "Min"},
+	{Unit: UnitHour, Code: "hour", Title: "Hour"},
+	{Unit: UnitDay, Code: "day", Title: "Day"},
+	{Unit: UnitWeek, Code: "week", Title: "Week"},
+	{Unit: UnitMonth, Code: "mon", Title: "Month"},
+}
+
+// quarterCode alias of 3 month interval
+const quarterCode = "quarter"
+
+// Interval candle interval of Count units, zero value is invalid interval
+type Interval struct {
+	Count int
+	Unit  IntervalUnit
+}
+
+// ParseInterval parses "Nmin", "Nhour", "Nday", "Nweek", "Nmon" or "quarter"
+func ParseInterval(s string) (Interval, error) {
+	if s == quarterCode {
+		return Interval{Count: 3, Unit: UnitMonth}, nil
+	}
+	for _, unit := range intervalUnits {
+		if !strings.HasSuffix(s, unit.Code) {
+			continue
+		}
+		count, err := strconv.Atoi(strings.TrimSuffix(s, unit.Code))
+		if err != nil || count < 1 {
+			break
+		}
+		return Interval{Count: count, Unit: unit.Unit}, nil
+	}
+	return Interval{}, fmt.Errorf("%w %q", ErrBadInterval, s)
+}
+
+// Valid reports if interval has positive count and known unit
+func (i Interval) Valid() bool {
+	return i.Count > 0 && i.Unit >= UnitMinute && i.Unit <= UnitMonth
+}
+
+// Code returns interval in ParseInterval format, e.g. "5min"
+func (i Interval) Code() string {
+	for _, unit := range intervalUnits {
+		if unit.Unit == i.Unit {
+			return strconv.Itoa(i.Count) + unit.Code
+		}
+	}
+	return ""
+}
+
+// String returns interval for chart titles, e.g. "5 Min"
+func (i Interval) String() string {
+	for _, unit := range intervalUnits {
+		if unit.Unit == i.Unit {
+			return strconv.Itoa(i.Count) + " " + unit.Title
+		}
+	}
+	return ""
+}
+
+// Duration returns interval length, months are counted as 30 days
+func (i Interval) Duration() time.Duration {
+	day := 24 * time.Hour
+	unit := map[IntervalUnit]time.Duration{
+		UnitMinute: time.Minute,
+		UnitHour:   time.Hour,
+		UnitDay:    day,
+		UnitWeek:   7 * day,
+		UnitMonth:  30 * day,
+	}[i.Unit]
+	return time.Duration(i.Count) * unit
+}
+
+// Divides reports if buckets of i consist of whole buckets of finer interval
+func (i Interval) Divides(finer Interval) bool {
+	switch {
+	case !i.Valid() || !finer.Valid():
+		return false
+	case i.Unit == finer.Unit:
+		return i.Count%finer.Count == 0
+	case i.Unit == UnitDay || i.Unit == UnitWeek || i.Unit == UnitMonth:
+		// days are not split to intraday buckets as sessions differ in length
+		return finer == Interval{Count: 1, Unit: UnitDay}
+	default:
+		return finer.Unit == UnitMinute && i.Duration()%finer.Duration() == 0
+	}
+}
+
+// Session trading session used to align intraday buckets
+type Session struct {
+	Location *time.Location
+	// Open offset of session open from midnight, intraday buckets start at open
+	Open time.Duration
+}
+
+// epochMonday first monday of unix epoch, weeks are counted from it
+var epochMonday = time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+
+// Start returns start of interval bucket containing t,
+// days, weeks and months start at midnight of session location.
+// Multi-day buckets count weekdays, so weekends belong to the bucket of preceding friday,
+// holidays are not known here and are counted as trading days
+func (i Interval) Start(t time.Time, session Session) time.Time {
+	loc := session.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+	switch i.Unit {
+	case UnitMinute, UnitHour:
+		size := i.Duration()
+		offset := t.Sub(midnight) - session.Open
+		buckets := offset / size
+		if offset < 0 && offset%size != 0 {
+			buckets--
+		}
+		return midnight.Add(session.Open + buckets*size)
+	case UnitDay:
+		if i.Count == 1 {
+			return midnight
+		}
+		weekdays := weekdaysSinceEpochMonday(midnight)
+		start := weekdays - floorMod(weekdays, i.Count)
+		days := floorDiv(start, 5)*7 + floorMod(start, 5)
+		return time.Date(epochMonday.Year(), epochMonday.Month(), epochMonday.Day()+days, 0, 0, 0, 0, loc)
+	case UnitWeek:
+		days := daysSinceEpoch(midnight) - daysSinceEpoch(epochMonday)
+		return midnight.AddDate(0, 0, -floorMod(days, 7*i.Count))
+	case UnitMonth:
+		months := t.Year()*12 + int(t.Month()) - 1
+		months -= floorMod(months, i.Count)
+		return time.Date(months/12, time.Month(months%12+1), 1, 0, 0, 0, 0, loc)
+	default:
+		return t
+	}
+}
+
+// daysSinceEpoch returns calendar days from 1970-01-01 to date of t ignoring location offset
+func daysSinceEpoch(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// weekdaysSinceEpochMonday returns index of weekday of t counting from epochMonday,
+// saturday and sunday get index of preceding friday
+func weekdaysSinceEpochMonday(t time.Time) int {
+	days := daysSinceEpoch(t) - daysSinceEpoch(epochMonday)
+	weekday := floorMod(days, 7)
+	if weekday > 4 {
+		weekday = 4
+	}
+	return floorDiv(days, 7)*5 + weekday
+}
+
+func floorDiv(a, b int) int {
+	return (a - floorMod(a, b)) / b
+}
+
+func floorMod(a, b int) int {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
@@ -17,9 +17,10 @@ type TOHLCV struct {
 
 // CandlesticksData TOHLCs data with metainformation
 type CandlesticksData struct {
-	Ticker   string
-	Name     string
-	Currency string
-	Interval string
-	TOHLCs   []TOHLCV
+	Ticker            string
+	Name              string
+	Currency          string
+	MinPriceIncrement float64
+	Interval          string
+	TOHLCs            []TOHLCV
 }
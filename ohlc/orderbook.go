@@ -0,0 +1,37 @@
+package ohlc
+
+// PriceLevel price and quantity in lots of order book level
+type PriceLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// OrderBook bids sorted by price descending, asks sorted by price ascending
+type OrderBook struct {
+	Ticker   string
+	Name     string
+	Currency string
+
+	Bids []PriceLevel
+	Asks []PriceLevel
+
+	LastPrice         float64
+	MinPriceIncrement float64
+	// Trading is false when instrument is not traded now
+	Trading bool
+}
+
+// Spread returns best bid, best ask and difference between them,
+// ok is false if one of sides is empty
+func (b *OrderBook) Spread() (bid, ask, spread float64, ok bool) {
+	if len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return 0, 0, 0, false
+	}
+	bid, ask = b.Bids[0].Price, b.Asks[0].Price
+	return bid, ask, ask - bid, true
+}
+
+// Empty returns true if there are no orders
+func (b *OrderBook) Empty() bool {
+	return len(b.Bids) == 0 && len(b.Asks) == 0
+}
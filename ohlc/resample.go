@@ -0,0 +1,26 @@
+package ohlc
+
+import (
+	"math"
+	"time"
+)
+
+// Resample aggregates candles sorted by timestamp to interval buckets aligned
+// to session: open of first candle, max high, min low, close of last candle
+// and total volume, bucket timestamp is bucket start
+func Resample(candles []TOHLCV, interval Interval, session Session) []TOHLCV {
+	result := make([]TOHLCV, 0, len(candles))
+	for _, candle := range candles {
+		start := interval.Start(time.Unix(candle.Timestamp, 0), session).Unix()
+		if n := len(result); n > 0 && result[n-1].Timestamp == start {
+			bucket := &result[n-1]
+			bucket.High = math.Max(bucket.High, candle.High)
+			bucket.Low = math.Min(bucket.Low, candle.Low)
+			bucket.Close = candle.Close
+			bucket.Volume += candle.Volume
+			continue
+		}
+		result = append(result, TOHLCV{Timestamp: start, OHLCV: candle.OHLCV})
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package ohlc
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	moscow  = time.FixedZone("MSK", 3*60*60)
+	newYork = time.FixedZone("EST", -5*60*60)
+	moex    = Session{Location: moscow, Open: 10 * time.Hour}
+	nyse    = Session{Location: newYork, Open: 9*time.Hour + 30*time.Minute}
+)
+
+func candle(t time.Time, open, high, low, close, volume float64) TOHLCV {
+	return TOHLCV{Timestamp: t.Unix(), OHLCV: OHLCV{Open: open, High: high, Low: low, Close: close, Volume: volume}}
+}
+
+func TestResample(t *testing.T) {
+	day := time.Date(2021, 12, 1, 0, 0, 0, 0, moscow)
+	candles := []TOHLCV{
+		candle(day.Add(10*time.Hour), 100, 103, 99, 102, 10),
+		candle(day.Add(11*time.Hour), 102, 105, 101, 104, 20),
+		candle(day.Add(12*time.Hour), 104, 104, 98, 100, 30),
+		candle(day.Add(14*time.Hour), 100, 101, 97, 99, 40),
+	}
+	got := Resample(candles, Interval{Count: 4, Unit: UnitHour}, moex)
+	want := []TOHLCV{
+		candle(day.Add(10*time.Hour), 100, 105, 98, 100, 60),
+		candle(day.Add(14*time.Hour), 100, 101, 97, 99, 40),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bucket %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIntervalStart(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		interval Interval
+		session  Session
+		t, want  time.Time
+	}{
+		{"4hour moex", Interval{4, UnitHour}, moex,
+			time.Date(2021, 12, 1, 15, 20, 0, 0, moscow), time.Date(2021, 12, 1, 14, 0, 0, 0, moscow)},
+		{"4hour nyse", Interval{4, UnitHour}, nyse,
+			time.Date(2021, 12, 1, 15, 20, 0, 0, newYork), time.Date(2021, 12, 1, 13, 30, 0, 0, newYork)},
+		{"before open", Interval{4, UnitHour}, moex,
+			time.Date(2021, 12, 1, 9, 0, 0, 0, moscow), time.Date(2021, 12, 1, 6, 0, 0, 0, moscow)},
+		{"15min", Interval{15, UnitMinute}, nyse,
+			time.Date(2021, 12, 1, 9, 44, 0, 0, newYork), time.Date(2021, 12, 1, 9, 30, 0, 0, newYork)},
+		{"day", Interval{1, UnitDay}, moex,
+			time.Date(2021, 12, 4, 12, 0, 0, 0, moscow), time.Date(2021, 12, 4, 0, 0, 0, 0, moscow)},
+		// weekdays are counted from 1970-01-05, 2day buckets of 2021-12 are wed-thu, fri-mon and tue-wed
+		{"2day thursday", Interval{2, UnitDay}, moex,
+			time.Date(2021, 12, 2, 12, 0, 0, 0, moscow), time.Date(2021, 12, 1, 0, 0, 0, 0, moscow)},
+		{"2day sunday", Interval{2, UnitDay}, moex,
+			time.Date(2021, 12, 5, 12, 0, 0, 0, moscow), time.Date(2021, 12, 3, 0, 0, 0, 0, moscow)},
+		{"2day monday", Interval{2, UnitDay}, moex,
+			time.Date(2021, 12, 6, 12, 0, 0, 0, moscow), time.Date(2021, 12, 3, 0, 0, 0, 0, moscow)},
+		{"2day tuesday", Interval{2, UnitDay}, moex,
+			time.Date(2021, 12, 7, 12, 0, 0, 0, moscow), time.Date(2021, 12, 7, 0, 0, 0, 0, moscow)},
+		{"3day before epoch", Interval{3, UnitDay}, nyse,
+			time.Date(1969, 12, 30, 12, 0, 0, 0, newYork), time.Date(1969, 12, 26, 0, 0, 0, 0, newYork)},
+		{"week", Interval{1, UnitWeek}, moex,
+			time.Date(2021, 12, 5, 12, 0, 0, 0, moscow), time.Date(2021, 11, 29, 0, 0, 0, 0, moscow)},
+		{"quarter", Interval{3, UnitMonth}, nyse,
+			time.Date(2021, 12, 5, 12, 0, 0, 0, newYork), time.Date(2021, 10, 1, 0, 0, 0, 0, newYork)},
+	} {
+		if got := tc.interval.Start(tc.t, tc.session); !got.Equal(tc.want) {
+			t.Errorf("%s: Start(%v) = %v, want %v", tc.name, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestResampleTwoDaysSkipsWeekend(t *testing.T) {
+	var candles []TOHLCV
+	// daily candles from thursday 2021-12-02 to wednesday 2021-12-08 without weekend
+	for _, day := range []int{2, 3, 6, 7, 8} {
+		candles = append(candles, candle(time.Date(2021, 12, day, 0, 0, 0, 0, moscow), 1, 1, 1, 1, 1))
+	}
+	got := Resample(candles, Interval{2, UnitDay}, moex)
+	want := []struct {
+		day    int
+		volume float64
+	}{{1, 1}, {3, 2}, {7, 2}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Timestamp != time.Date(2021, 12, w.day, 0, 0, 0, 0, moscow).Unix() || got[i].Volume != w.volume {
+			t.Errorf("bucket %d = %+v, want start at 2021-12-%02d and volume %v", i, got[i], w.day, w.volume)
+		}
+	}
+}
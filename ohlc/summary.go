@@ -0,0 +1,83 @@
+package ohlc
+
+import "errors"
+
+// ErrNoCandles error for summary of empty candles
+var ErrNoCandles = errors.New("no candles")
+
+// Summary price summary of last daily candle
+type Summary struct {
+	Last          float64
+	LastTimestamp int64
+
+	// PrevClose close of previous candle, valid if HasPrevClose
+	PrevClose     float64
+	HasPrevClose  bool
+	Change        float64
+	ChangePercent float64
+
+	DayHigh float64
+	DayLow  float64
+
+	Volume float64
+	// AvgVolume average volume of up to AvgVolumeDays previous candles,
+	// zero if there are no previous candles
+	AvgVolume     float64
+	AvgVolumeDays int
+
+	RangeHigh float64
+	RangeLow  float64
+}
+
+// Summarize computes summary of daily candles sorted by time,
+// range covers all candles, average volume covers avgVolumeDays
+// candles before the last one
+func Summarize(candles []TOHLCV, avgVolumeDays int) (*Summary, error) {
+	if len(candles) == 0 {
+		return nil, ErrNoCandles
+	}
+
+	last := candles[len(candles)-1]
+	summary := &Summary{
+		Last:          last.Close,
+		LastTimestamp: last.Timestamp,
+		DayHigh:       last.High,
+		DayLow:        last.Low,
+		Volume:        last.Volume,
+		RangeHigh:     last.High,
+		RangeLow:      last.Low,
+	}
+
+	if len(candles) > 1 {
+		summary.PrevClose = candles[len(candles)-2].Close
+		summary.HasPrevClose = true
+		summary.Change = summary.Last - summary.PrevClose
+		if summary.PrevClose != 0 {
+			summary.ChangePercent = summary.Change / summary.PrevClose * 100
+		}
+	}
+
+	previous := candles[:len(candles)-1]
+	if len(previous) > avgVolumeDays {
+		previous = previous[len(previous)-avgVolumeDays:]
+	}
+	if len(previous) > 0 {
+		var volume float64
+		for _, candle := range previous {
+			volume += candle.Volume
+		}
+		summary.AvgVolume = volume / float64(len(previous))
+		summary.AvgVolumeDays = len(previous)
+	}
+
+	for _, candle := range candles {
+		if candle.High > summary.RangeHigh {
+			summary.RangeHigh = candle.High
+		}
+		if candle.Low < summary.RangeLow {
+			summary.RangeLow = candle.Low
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,118 @@
+package portfolio
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+)
+
+// Prices daily candles of traded instruments and exchange rates
+// used to value portfolio in base currency
+type Prices struct {
+	Base string
+	// Candles candles by ticker
+	Candles map[string][]ohlc.TOHLCV
+	// Rates candles of currency price in base currency by currency
+	Rates map[string][]ohlc.TOHLCV
+}
+
+// NewPrices creates empty prices in base currency
+func NewPrices(base string) *Prices {
+	return &Prices{
+		Base:    base,
+		Candles: make(map[string][]ohlc.TOHLCV),
+		Rates:   make(map[string][]ohlc.TOHLCV),
+	}
+}
+
+// closeAt returns close of last candle started not after ts,
+// candles are sorted by timestamp
+func closeAt(candles []ohlc.TOHLCV, ts int64) (float64, bool) {
+	i := sort.Search(len(candles), func(i int) bool {
+		return candles[i].Timestamp > ts
+	})
+	if i == 0 {
+		return 0, false
+	}
+	return candles[i-1].Close, true
+}
+
+// Price returns close price of ticker at ts
+func (p *Prices) Price(ticker string, ts int64) (float64, bool) {
+	return closeAt(p.Candles[ticker], ts)
+}
+
+// Rate returns price of currency in base currency at ts
+func (p *Prices) Rate(currency string, ts int64) (float64, bool) {
+	if currency == p.Base {
+		return 1, true
+	}
+	return closeAt(p.Rates[currency], ts)
+}
+
+// Valuation position valued at price, Value, Cost and PnL are in base currency
+type Valuation struct {
+	*Position
+	Price float64
+	Value float64
+	Cost  float64
+}
+
+// PnL returns unrealized profit in base currency
+func (v *Valuation) PnL() float64 {
+	return v.Value - v.Cost
+}
+
+// Value values position at ts, false if price or exchange rate is unknown
+func (p *Prices) Value(position *Position, ts int64) (*Valuation, bool) {
+	price, ok := p.Price(position.Ticker, ts)
+	if !ok {
+		return nil, false
+	}
+	rate, ok := p.Rate(position.Currency, ts)
+	if !ok {
+		return nil, false
+	}
+	return &Valuation{
+		Position: position,
+		Price:    price,
+		Value:    position.Quantity * price * rate,
+		Cost:     position.Cost() * rate,
+	}, true
+}
+
+// EquityCurve returns value and cost basis of open positions in base currency
+// on close of each day with candles since from, trades made during day are included
+func EquityCurve(p *Portfolio, prices *Prices, from time.Time) []ohlc.EquityPoint {
+	days := make(map[int64]struct{})
+	for _, candles := range prices.Candles {
+		for _, candle := range candles {
+			if candle.Timestamp >= from.Unix() {
+				days[candle.Timestamp] = struct{}{}
+			}
+		}
+	}
+	timestamps := make([]int64, 0, len(days))
+	for ts := range days {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	points := make([]ohlc.EquityPoint, 0, len(timestamps))
+	for _, ts := range timestamps {
+		dayEnd := time.Unix(ts, 0).Add(24*time.Hour - time.Second)
+		point := ohlc.EquityPoint{Timestamp: ts}
+		for _, position := range p.Positions(dayEnd) {
+			if !position.Open() {
+				continue
+			}
+			if valuation, ok := prices.Value(position, ts); ok {
+				point.Value += valuation.Value
+				point.Cost += valuation.Cost
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
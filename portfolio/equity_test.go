@@ -0,0 +1,136 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+)
+
+var day = time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+
+// dailyCandles returns candles with closes on consecutive days since day
+func dailyCandles(closes ...float64) []ohlc.TOHLCV {
+	candles := make([]ohlc.TOHLCV, 0, len(closes))
+	for i, close := range closes {
+		candles = append(candles, ohlc.TOHLCV{Timestamp: day.AddDate(0, 0, i).Unix(), OHLCV: ohlc.OHLCV{Close: close}})
+	}
+	return candles
+}
+
+func testPrices() *Prices {
+	prices := NewPrices("RUB")
+	prices.Candles["SBER"] = dailyCandles(250, 260, 270)
+	prices.Candles["AAPL"] = dailyCandles(150, 160, 170)
+	prices.Rates["USD"] = dailyCandles(70, 75, 80)
+	return prices
+}
+
+func TestPricesValue(t *testing.T) {
+	prices := testPrices()
+	secondDay := day.AddDate(0, 0, 1).Unix()
+
+	tests := []struct {
+		name      string
+		position  *Position
+		ts        int64
+		wantOK    bool
+		wantPrice float64
+		wantValue float64
+		wantCost  float64
+	}{
+		{
+			name:      "base currency",
+			position:  &Position{Ticker: "SBER", Currency: "RUB", Quantity: 10, AverageCost: 240},
+			ts:        secondDay,
+			wantOK:    true,
+			wantPrice: 260,
+			wantValue: 2600,
+			wantCost:  2400,
+		},
+		{
+			name:      "converted by USDRUB",
+			position:  &Position{Ticker: "AAPL", Currency: "USD", Quantity: 2, AverageCost: 100},
+			ts:        secondDay,
+			wantOK:    true,
+			wantPrice: 160,
+			wantValue: 2 * 160 * 75,
+			wantCost:  2 * 100 * 75,
+		},
+		{
+			name:      "last close during day",
+			position:  &Position{Ticker: "AAPL", Currency: "USD", Quantity: 1, AverageCost: 100},
+			ts:        secondDay + 3600,
+			wantOK:    true,
+			wantPrice: 160,
+			wantValue: 160 * 75,
+			wantCost:  100 * 75,
+		},
+		{
+			name:     "before first candle",
+			position: &Position{Ticker: "SBER", Currency: "RUB", Quantity: 1},
+			ts:       day.Unix() - 1,
+		},
+		{
+			name:     "unknown rate",
+			position: &Position{Ticker: "AAPL", Currency: "EUR", Quantity: 1},
+			ts:       secondDay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valuation, ok := prices.Value(tt.position, tt.ts)
+			if ok != tt.wantOK {
+				t.Fatalf("Value() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !almostEqual(valuation.Price, tt.wantPrice) || !almostEqual(valuation.Value, tt.wantValue) ||
+				!almostEqual(valuation.Cost, tt.wantCost) {
+				t.Errorf("Value() = price %v, value %v, cost %v, want %v, %v, %v",
+					valuation.Price, valuation.Value, valuation.Cost, tt.wantPrice, tt.wantValue, tt.wantCost)
+			}
+			if !almostEqual(valuation.PnL(), tt.wantValue-tt.wantCost) {
+				t.Errorf("PnL() = %v, want %v", valuation.PnL(), tt.wantValue-tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestEquityCurve(t *testing.T) {
+	p := &Portfolio{}
+	trades := []*Trade{
+		{Time: day.Add(12 * time.Hour), Side: SideBuy, Ticker: "SBER", Quantity: 10, Price: 245, Currency: "RUB"},
+		{Time: day.AddDate(0, 0, 1).Add(12 * time.Hour), Side: SideBuy, Ticker: "AAPL", Quantity: 2, Price: 155, Currency: "USD"},
+		{Time: day.AddDate(0, 0, 2).Add(12 * time.Hour), Side: SideSell, Ticker: "SBER", Quantity: 10, Price: 265, Currency: "RUB"},
+	}
+	for _, trade := range trades {
+		if err := p.Add(trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []ohlc.EquityPoint{
+		{Timestamp: day.Unix(), Value: 10 * 250, Cost: 10 * 245},
+		// AAPL is converted with USDRUB of the same day
+		{Timestamp: day.AddDate(0, 0, 1).Unix(), Value: 10*260 + 2*160*75, Cost: 10*245 + 2*155*75},
+		// closed SBER position is not valued
+		{Timestamp: day.AddDate(0, 0, 2).Unix(), Value: 2 * 170 * 80, Cost: 2 * 155 * 80},
+	}
+	got := EquityCurve(p, testPrices(), day)
+	if len(got) != len(want) {
+		t.Fatalf("EquityCurve() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Timestamp != want[i].Timestamp || !almostEqual(got[i].Value, want[i].Value) || !almostEqual(got[i].Cost, want[i].Cost) {
+			t.Errorf("point %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// days before from are skipped
+	if got := EquityCurve(p, testPrices(), day.AddDate(0, 0, 2)); len(got) != 1 || got[0].Timestamp != want[2].Timestamp {
+		t.Errorf("EquityCurve() since last day = %+v, want only %+v", got, want[2])
+	}
+}
@@ -0,0 +1,149 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// quantityEpsilon quantities below are treated as zero,
+// currency positions have fractional quantities
+const quantityEpsilon = 1e-9
+
+var (
+	// ErrBadTrade error for trade with non positive quantity or price
+	ErrBadTrade = errors.New("quantity and price must be positive")
+	// ErrNotEnoughQuantity error for selling more than held
+	ErrNotEnoughQuantity = errors.New("not enough quantity to sell")
+)
+
+// Side side of trade
+type Side string
+
+// Trade sides
+const (
+	SideBuy  Side = "buy"
+	SideSell Side = "sell"
+)
+
+// Trade paper trade of portfolio
+type Trade struct {
+	Time     time.Time `json:"time"`
+	Side     Side      `json:"side"`
+	Ticker   string    `json:"ticker"`
+	Quantity float64   `json:"quantity"`
+	Price    float64   `json:"price"`
+	Currency string    `json:"currency"`
+}
+
+// Position open position or closed position with realized P&L,
+// prices are in position currency
+type Position struct {
+	Ticker      string
+	Currency    string
+	Quantity    float64
+	AverageCost float64
+	Realized    float64
+}
+
+// Cost returns cost basis of position
+func (p *Position) Cost() float64 {
+	return p.Quantity * p.AverageCost
+}
+
+// Open reports if position has quantity
+func (p *Position) Open() bool {
+	return p.Quantity > quantityEpsilon
+}
+
+// apply adds trade to position, average cost changes only on buys
+func (p *Position) apply(trade *Trade) error {
+	switch trade.Side {
+	case SideBuy:
+		p.AverageCost = (p.Cost() + trade.Quantity*trade.Price) / (p.Quantity + trade.Quantity)
+		p.Quantity += trade.Quantity
+	case SideSell:
+		if trade.Quantity > p.Quantity+quantityEpsilon {
+			return ErrNotEnoughQuantity
+		}
+		p.Realized += trade.Quantity * (trade.Price - p.AverageCost)
+		p.Quantity -= trade.Quantity
+		if !p.Open() {
+			p.Quantity, p.AverageCost = 0, 0
+		}
+	default:
+		return errors.Errorf("unknown trade side %q", trade.Side)
+	}
+	return nil
+}
+
+// Portfolio paper portfolio, positions are computed from trades
+type Portfolio struct {
+	Trades []*Trade `json:"trades"`
+}
+
+// Add validates trade against current positions and appends it
+func (p *Portfolio) Add(trade *Trade) error {
+	if !(trade.Quantity > 0) || !(trade.Price > 0) || math.IsInf(trade.Quantity, 0) || math.IsInf(trade.Price, 0) {
+		return ErrBadTrade
+	}
+	position := p.Position(trade.Ticker)
+	if err := position.apply(trade); err != nil {
+		return err
+	}
+	p.Trades = append(p.Trades, trade)
+	return nil
+}
+
+// Position returns position of ticker, empty if ticker was not traded
+func (p *Portfolio) Position(ticker string) *Position {
+	for _, position := range p.Positions(time.Time{}) {
+		if position.Ticker == ticker {
+			return position
+		}
+	}
+	return &Position{Ticker: ticker}
+}
+
+// Positions returns positions by trades made until t sorted by ticker,
+// zero t means all trades
+func (p *Portfolio) Positions(t time.Time) []*Position {
+	byTicker := make(map[string]*Position)
+	for _, trade := range p.Trades {
+		if !t.IsZero() && trade.Time.After(t) {
+			continue
+		}
+		position, ok := byTicker[trade.Ticker]
+		if !ok {
+			position = &Position{Ticker: trade.Ticker, Currency: trade.Currency}
+			byTicker[trade.Ticker] = position
+		}
+		// trades are validated on Add
+		_ = position.apply(trade)
+	}
+
+	positions := make([]*Position, 0, len(byTicker))
+	for _, position := range byTicker {
+		positions = append(positions, position)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Ticker < positions[j].Ticker
+	})
+	return positions
+}
+
+// Start returns time of first trade
+func (p *Portfolio) Start() (time.Time, bool) {
+	if len(p.Trades) == 0 {
+		return time.Time{}, false
+	}
+	start := p.Trades[0].Time
+	for _, trade := range p.Trades[1:] {
+		if trade.Time.Before(start) {
+			start = trade.Time
+		}
+	}
+	return start, true
+}
@@ -0,0 +1,106 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+const floatTolerance = 1e-9
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < floatTolerance
+}
+
+func TestPositionApply(t *testing.T) {
+	buy := func(quantity, price float64) *Trade {
+		return &Trade{Side: SideBuy, Ticker: "SBER", Quantity: quantity, Price: price}
+	}
+	sell := func(quantity, price float64) *Trade {
+		return &Trade{Side: SideSell, Ticker: "SBER", Quantity: quantity, Price: price}
+	}
+
+	tests := []struct {
+		name         string
+		trades       []*Trade
+		wantErr      error
+		wantQuantity float64
+		wantAverage  float64
+		wantRealized float64
+	}{
+		{
+			name:         "average cost across buys",
+			trades:       []*Trade{buy(10, 100), buy(30, 200)},
+			wantQuantity: 40,
+			wantAverage:  175,
+		},
+		{
+			name:         "partial sell realizes pnl and keeps average",
+			trades:       []*Trade{buy(10, 100), buy(10, 200), sell(5, 180)},
+			wantQuantity: 15,
+			wantAverage:  150,
+			wantRealized: 150,
+		},
+		{
+			name:         "closing sell resets average",
+			trades:       []*Trade{buy(10, 100), sell(10, 90)},
+			wantRealized: -100,
+		},
+		{
+			name:         "oversell is rejected",
+			trades:       []*Trade{buy(10, 100), sell(11, 120)},
+			wantErr:      ErrNotEnoughQuantity,
+			wantQuantity: 10,
+			wantAverage:  100,
+		},
+		{
+			name:    "sell without position",
+			trades:  []*Trade{sell(1, 100)},
+			wantErr: ErrNotEnoughQuantity,
+		},
+		{
+			name:         "fractional currency quantities",
+			trades:       []*Trade{buy(0.1, 75.5), buy(0.2, 76.1), sell(0.15, 77)},
+			wantQuantity: 0.15,
+			wantAverage:  75.9,
+			wantRealized: 0.165,
+		},
+		{
+			name: "fractional remainder closes position",
+			// 0.1 + 0.2 leaves 4e-17 after selling 0.3
+			trades:       []*Trade{buy(0.1, 75), buy(0.2, 75), sell(0.3, 76)},
+			wantRealized: 0.3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := &Position{Ticker: "SBER"}
+			var err error
+			for _, trade := range tt.trades {
+				if err = position.apply(trade); err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if !almostEqual(position.Quantity, tt.wantQuantity) || !almostEqual(position.AverageCost, tt.wantAverage) ||
+				!almostEqual(position.Realized, tt.wantRealized) {
+				t.Errorf("position = %+v, want quantity %v, average %v, realized %v",
+					position, tt.wantQuantity, tt.wantAverage, tt.wantRealized)
+			}
+			if position.Open() != (tt.wantQuantity > 0) {
+				t.Errorf("Open() = %v, want %v", position.Open(), tt.wantQuantity > 0)
+			}
+		})
+	}
+}
+
+func TestPositionApplyUnknownSide(t *testing.T) {
+	position := &Position{Ticker: "SBER"}
+	if err := position.apply(&Trade{Side: "short", Quantity: 1, Price: 1}); err == nil {
+		t.Error("apply() with unknown side succeeded")
+	}
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket token bucket rate limiter
+type Bucket struct {
+	mu sync.Mutex
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// now returns current time, replaced in tests
+	now func() time.Time
+}
+
+// NewBucket creates full bucket refilled with rate tokens per second
+// and holding at most burst tokens, rate <= 0 disables limiting
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// refill adds tokens for time passed since last call, must be called under lock
+func (b *Bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// Allow takes token if it is available
+func (b *Bucket) Allow() bool {
+	if b.rate <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(b.now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Reserve takes token and returns how long to wait before using it,
+// waiting callers are served in order of reservation
+func (b *Bucket) Reserve() time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(b.now())
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait blocks until token is available or ctx is done
+func (b *Bucket) Wait(ctx context.Context) error {
+	delay := b.Reserve()
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// cancel returns reserved token back
+func (b *Bucket) cancel() {
+	b.mu.Lock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+	b.mu.Unlock()
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock time moved by tests
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestBucket(rate float64, burst int) (*Bucket, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2022, 1, 10, 12, 0, 0, 0, time.UTC)}
+	b := NewBucket(rate, burst)
+	b.now = clock.Now
+	b.last = clock.Now()
+	return b, clock
+}
+
+func TestBucketAllow(t *testing.T) {
+	b, clock := newTestBucket(2, 3)
+	for i := 0; i < 3; i++ {
+		if !b.Allow() {
+			t.Fatalf("call %d of burst is not allowed", i)
+		}
+	}
+	if b.Allow() {
+		t.Fatal("call over burst is allowed")
+	}
+
+	clock.Advance(400 * time.Millisecond)
+	if b.Allow() {
+		t.Error("token is allowed before refill")
+	}
+	clock.Advance(100 * time.Millisecond)
+	if !b.Allow() {
+		t.Error("token is not allowed after refill")
+	}
+
+	// idle bucket holds at most burst tokens
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		b.Allow()
+	}
+	if b.Allow() {
+		t.Error("bucket holds more than burst tokens")
+	}
+}
+
+func TestBucketReserve(t *testing.T) {
+	b, clock := newTestBucket(2, 1)
+	if delay := b.Reserve(); delay != 0 {
+		t.Errorf("first reserve delay = %v, want 0", delay)
+	}
+	// waiting callers are served in order
+	for _, want := range []time.Duration{500 * time.Millisecond, time.Second} {
+		if delay := b.Reserve(); delay != want {
+			t.Errorf("reserve delay = %v, want %v", delay, want)
+		}
+	}
+	// in a second both reserved tokens are refilled
+	clock.Advance(time.Second)
+	if delay := b.Reserve(); delay != 500*time.Millisecond {
+		t.Errorf("reserve delay after second = %v, want 500ms", delay)
+	}
+}
+
+func TestBucketWaitCanceled(t *testing.T) {
+	b, _ := newTestBucket(0.001, 1)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait error = %v, want context.Canceled", err)
+	}
+	// canceled reservation returns token, so next caller waits the same time
+	if delay := b.Reserve(); delay != 1000*time.Second {
+		t.Errorf("reserve delay = %v, want 1000s", delay)
+	}
+}
+
+func TestBucketDisabled(t *testing.T) {
+	b, _ := newTestBucket(0, 1)
+	for i := 0; i < 100; i++ {
+		if !b.Allow() || b.Reserve() != 0 {
+			t.Fatal("disabled bucket limits calls")
+		}
+	}
+}
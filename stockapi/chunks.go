@@ -0,0 +1,144 @@
+package stockapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+)
+
+// maxWindows max count of api calls of one candles request, at default rate they take 10 seconds
+const maxWindows = 20
+
+// ErrRangeTooLong error for period which needs more than maxWindows api calls
+var ErrRangeTooLong = errors.New("period is too long for interval")
+
+// MaxRange returns the longest period of candles of interval which can be requested
+func MaxRange(interval CandlestickInterval) (time.Duration, error) {
+	native, err := transformToTinkoffCandleInterval(interval)
+	if err != nil {
+		return 0, err
+	}
+	return maxWindows * native.MaxRange, nil
+}
+
+// CheckRange returns ErrRangeTooLong if [from, to) is longer than MaxRange of interval
+func CheckRange(from, to time.Time, interval CandlestickInterval) error {
+	maxRange, err := MaxRange(interval)
+	if err != nil {
+		return err
+	}
+	if to.Sub(from) > maxRange {
+		return fmt.Errorf("%w: %s allows at most %d days", ErrRangeTooLong, interval, int(maxRange/oneDay))
+	}
+	return nil
+}
+
+// timeWindow part of requested period fetched with one api call
+type timeWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// splitWindows splits [from, to) into consecutive windows not longer than maxRange
+func splitWindows(from, to time.Time, maxRange time.Duration) []timeWindow {
+	if !to.After(from) {
+		return nil
+	}
+	if maxRange <= 0 {
+		return []timeWindow{{From: from, To: to}}
+	}
+
+	windows := make([]timeWindow, 0, int(to.Sub(from)/maxRange)+1)
+	for start := from; start.Before(to); start = start.Add(maxRange) {
+		end := start.Add(maxRange)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, timeWindow{From: start, To: end})
+	}
+	return windows
+}
+
+// fetchWindows calls fetch for each window with at most concurrency calls at once,
+// first error cancels remaining calls, candles are merged and deduplicated by timestamp
+func fetchWindows(ctx context.Context, windows []timeWindow, concurrency int, fetch func(ctx context.Context, window timeWindow) ([]ohlc.TOHLCV, error)) ([]ohlc.TOHLCV, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]ohlc.TOHLCV, len(windows))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, window := range windows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, window timeWindow) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			candles, err := fetch(ctx, window)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = candles
+		}(i, window)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return mergeCandles(results), nil
+}
+
+// mergeCandles returns candles of all parts sorted by timestamp,
+// candle from later part wins on equal timestamps
+func mergeCandles(parts [][]ohlc.TOHLCV) []ohlc.TOHLCV {
+	var count int
+	for _, part := range parts {
+		count += len(part)
+	}
+	merged := make([]ohlc.TOHLCV, 0, count)
+	for _, part := range parts {
+		merged = append(merged, part...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp < merged[j].Timestamp
+	})
+
+	result := merged[:0]
+	for _, candle := range merged {
+		if n := len(result); n > 0 && result[n-1].Timestamp == candle.Timestamp {
+			result[n-1] = candle
+			continue
+		}
+		result = append(result, candle)
+	}
+	return result
+}
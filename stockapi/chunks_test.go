@@ -0,0 +1,76 @@
+package stockapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSplitWindows(t *testing.T) {
+	from := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	windows := splitWindows(from, from.Add(50*time.Hour), oneDay)
+	if len(windows) != 3 {
+		t.Fatalf("got %d windows, want 3", len(windows))
+	}
+	for i, window := range windows {
+		if i > 0 && !window.From.Equal(windows[i-1].To) {
+			t.Errorf("window %d starts at %v, previous ends at %v", i, window.From, windows[i-1].To)
+		}
+	}
+	if last := windows[2]; last.To.Sub(last.From) != 2*time.Hour {
+		t.Errorf("last window %v - %v", last.From, last.To)
+	}
+	if windows := splitWindows(from, from, oneDay); len(windows) != 0 {
+		t.Errorf("empty period split into %d windows", len(windows))
+	}
+}
+
+func TestCheckRange(t *testing.T) {
+	to := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		interval CandlestickInterval
+		period   time.Duration
+		ok       bool
+	}{
+		{CandlestickInterval1Min, maxWindows * oneDay, true},
+		{CandlestickInterval1Min, 3 * oneYear, false},
+		{CandlestickInterval{Count: 4, Unit: CandlestickInterval1Hour.Unit}, 10 * oneWeek, true},
+		{CandlestickInterval1Hour, maxWindows*oneWeek + time.Hour, false},
+		{CandlestickInterval1Day, 10 * oneYear, true},
+		{CandlestickInterval1Month, 100 * oneYear, true},
+	} {
+		err := CheckRange(to.Add(-tc.period), to, tc.interval)
+		if tc.ok && err != nil {
+			t.Errorf("%s for %v: %v", tc.interval, tc.period, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrRangeTooLong) {
+			t.Errorf("%s for %v: error %v, want ErrRangeTooLong", tc.interval, tc.period, err)
+		}
+	}
+}
+
+func TestTinkoffNativeInterval(t *testing.T) {
+	hours := func(count int) CandlestickInterval {
+		return CandlestickInterval{Count: count, Unit: CandlestickInterval1Hour.Unit}
+	}
+	for _, tc := range []struct {
+		interval CandlestickInterval
+		native   CandlestickInterval
+	}{
+		{CandlestickInterval1Hour, CandlestickInterval1Hour},
+		// tinkoff 2 and 4 hour candles are aligned to UTC, not to session
+		{hours(2), CandlestickInterval1Hour},
+		{hours(4), CandlestickInterval1Hour},
+		{hours(8), CandlestickInterval1Hour},
+		{CandlestickInterval1Day, CandlestickInterval1Day},
+	} {
+		native, err := transformToTinkoffCandleInterval(tc.interval)
+		if err != nil {
+			t.Errorf("%s: %v", tc.interval, err)
+			continue
+		}
+		if native.Interval != tc.native {
+			t.Errorf("%s: native %s, want %s", tc.interval, native.Interval, tc.native)
+		}
+	}
+}
@@ -5,73 +5,92 @@ import (
 	"errors"
 	"time"
 
+	"github.com/Apakhov/stocks-bot/instruments"
 	"github.com/Apakhov/stocks-bot/ohlc"
 )
 
 var (
 	// ErrBadCandlestickInterval error for bad CandlestickInterval
 	ErrBadCandlestickInterval = errors.New("can not parse CandlestickInterval")
+	// ErrBadOrderBookDepth error for depth out of [1, MaxOrderBookDepth]
+	ErrBadOrderBookDepth = errors.New("bad order book depth")
 )
 
+// MaxOrderBookDepth max count of order book levels on each side
+const MaxOrderBookDepth = 20
+
 // CandlestickInterval interval for one candlestick
-type CandlestickInterval int
+type CandlestickInterval = ohlc.Interval
 
 // Some intervals
-const (
-	CandlestickInterval1Min CandlestickInterval = iota
-	CandlestickInterval5Min
-	CandlestickInterval15Min
-	CandlestickInterval1Hour
-	CandlestickInterval1Day
-	CandlestickInterval1Week
-	CandlestickInterval1Month
-	CandlestickIntervalUnknown
+var (
+	CandlestickInterval1Min    = CandlestickInterval{Count: 1, Unit: ohlc.UnitMinute}
+	CandlestickInterval5Min    = CandlestickInterval{Count: 5, Unit: ohlc.UnitMinute}
+	CandlestickInterval15Min   = CandlestickInterval{Count: 15, Unit: ohlc.UnitMinute}
+	CandlestickInterval1Hour   = CandlestickInterval{Count: 1, Unit: ohlc.UnitHour}
+	CandlestickInterval1Day    = CandlestickInterval{Count: 1, Unit: ohlc.UnitDay}
+	CandlestickInterval1Week   = CandlestickInterval{Count: 1, Unit: ohlc.UnitWeek}
+	CandlestickInterval1Month  = CandlestickInterval{Count: 1, Unit: ohlc.UnitMonth}
+	CandlestickIntervalUnknown CandlestickInterval
 )
 
-// ParseCandlestickInterval returns CandlestickInterval if exists
+// ParseCandlestickInterval returns CandlestickInterval if exists,
+// accepts any "Nmin", "Nhour", "Nday", "Nweek", "Nmon" and "quarter"
 func ParseCandlestickInterval(interval string) (CandlestickInterval, error) {
-	switch interval {
-	case "1min":
-		return CandlestickInterval1Min, nil
-	case "5min":
-		return CandlestickInterval5Min, nil
-	case "15min":
-		return CandlestickInterval15Min, nil
-	case "1hour":
-		return CandlestickInterval1Hour, nil
-	case "1day":
-		return CandlestickInterval1Day, nil
-	case "1week":
-		return CandlestickInterval1Week, nil
-	case "1mon":
-		return CandlestickInterval1Month, nil
-	default:
+	parsed, err := ohlc.ParseInterval(interval)
+	if err != nil {
 		return CandlestickIntervalUnknown, ErrBadCandlestickInterval
 	}
+	return parsed, nil
+}
+
+// StockClient client for getting stocks
+type StockClient interface {
+	GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error)
 }
 
-func (i CandlestickInterval) String() string {
-	switch i {
-	case CandlestickInterval1Min:
-		return "1 Min"
-	case CandlestickInterval5Min:
-		return "5 Min"
-	case CandlestickInterval15Min:
-		return "15 Min"
-	case CandlestickInterval1Hour:
-		return "1 Hour"
-	case CandlestickInterval1Day:
-		return "1 Day"
-	case CandlestickInterval1Week:
-		return "1 Week"
-	case CandlestickInterval1Month:
-		return "1 Month"
+// OrderBookClient client for getting order books
+type OrderBookClient interface {
+	GetOrderBook(ctx context.Context, depth int, ticker string) (*ohlc.OrderBook, error)
+}
+
+// InstrumentClient client with registry of available instruments
+type InstrumentClient interface {
+	Instruments() *instruments.Registry
+}
+
+// compose returns candles client also implementing OrderBookClient
+// and InstrumentClient if orderBook and instrumentClient are not nil
+func compose(candles StockClient, orderBook OrderBookClient, instrumentClient InstrumentClient) StockClient {
+	switch {
+	case orderBook != nil && instrumentClient != nil:
+		return struct {
+			StockClient
+			OrderBookClient
+			InstrumentClient
+		}{candles, orderBook, instrumentClient}
+	case orderBook != nil:
+		return struct {
+			StockClient
+			OrderBookClient
+		}{candles, orderBook}
+	case instrumentClient != nil:
+		return struct {
+			StockClient
+			InstrumentClient
+		}{candles, instrumentClient}
 	default:
-		return ""
+		return candles
 	}
 }
 
-// StockClient client for getting stocks
-type StockClient interface {
-	GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error)
+// nativeInterval returns index of provider interval equal to interval or of the coarsest one
+// candles of interval can be resampled from, provider intervals go from finest to coarsest
+func nativeInterval(interval CandlestickInterval, count int, native func(i int) CandlestickInterval) (int, error) {
+	for i := count - 1; i >= 0; i-- {
+		if native(i) == interval || interval.Divides(native(i)) {
+			return i, nil
+		}
+	}
+	return 0, ErrBadCandlestickInterval
 }
@@ -0,0 +1,90 @@
+package stockapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+)
+
+// BaseCurrency currency exchange rates are quoted in
+const BaseCurrency = "RUB"
+
+var (
+	// ErrUnsupportedCurrency error for currency without exchange rate
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+	// FXTickers tickers of currency prices in BaseCurrency
+	FXTickers = map[string]string{
+		"USD": "USDRUB",
+		"EUR": "EURRUB",
+	}
+)
+
+// Currencies returns supported currencies, BaseCurrency first
+func Currencies() []string {
+	return []string{BaseCurrency, "USD", "EUR"}
+}
+
+// ParseCurrency returns currency code if conversion to it is supported
+func ParseCurrency(s string) (string, error) {
+	currency := strings.ToUpper(s)
+	if _, ok := FXTickers[currency]; ok || currency == BaseCurrency {
+		return currency, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnsupportedCurrency, s)
+}
+
+// GetCandlesticksIn returns candlesticks with prices converted to currency
+// by exchange rates of the same interval, empty currency means instrument currency
+func GetCandlesticksIn(ctx context.Context, client StockClient, from, to time.Time, interval CandlestickInterval, ticker, currency string) (*ohlc.CandlesticksData, error) {
+	data, err := client.GetCandlesticks(ctx, from, to, interval, ticker)
+	if err != nil || currency == "" || data.Currency == currency {
+		return data, err
+	}
+
+	rates, err := conversionRates(ctx, client, from, to, interval, data.Currency, currency)
+	if err != nil {
+		return nil, err
+	}
+	return ohlc.Convert(data, rates, currency)
+}
+
+// conversionRates returns prices of src currency in dst currency
+func conversionRates(ctx context.Context, client StockClient, from, to time.Time, interval CandlestickInterval, src, dst string) ([]ohlc.TOHLCV, error) {
+	fxRates := func(currency string) ([]ohlc.TOHLCV, error) {
+		fxTicker, ok := FXTickers[currency]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnsupportedCurrency, currency)
+		}
+		data, err := client.GetCandlesticks(ctx, from, to, interval, fxTicker)
+		if err != nil {
+			return nil, fmt.Errorf("can not get %s rates: %w", currency, err)
+		}
+		return data.TOHLCs, nil
+	}
+
+	switch {
+	case dst == BaseCurrency:
+		return fxRates(src)
+	case src == BaseCurrency:
+		rates, err := fxRates(dst)
+		if err != nil {
+			return nil, err
+		}
+		return ohlc.InvertRates(rates), nil
+	default:
+		srcRates, err := fxRates(src)
+		if err != nil {
+			return nil, err
+		}
+		dstRates, err := fxRates(dst)
+		if err != nil {
+			return nil, err
+		}
+		return ohlc.CrossRates(srcRates, dstRates)
+	}
+}
@@ -0,0 +1,241 @@
+package stockapi
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultCSVCurrency currency of csv quotes if it is not configured
+const DefaultCSVCurrency = "USD"
+
+// NYSESession session used to align resampled US candles
+var NYSESession = SessionOf(calendar.NYSE)
+
+// csvIntervals native intervals of yahoo-style csv quotes from finest to coarsest
+var csvIntervals = []struct {
+	Interval CandlestickInterval
+	Code     string
+}{
+	{CandlestickInterval1Min, "1m"},
+	{CandlestickInterval{Count: 2, Unit: ohlc.UnitMinute}, "2m"},
+	{CandlestickInterval5Min, "5m"},
+	{CandlestickInterval15Min, "15m"},
+	{CandlestickInterval{Count: 30, Unit: ohlc.UnitMinute}, "30m"},
+	{CandlestickInterval1Hour, "60m"},
+	{CandlestickInterval1Day, "1d"},
+	{CandlestickInterval1Week, "1wk"},
+	{CandlestickInterval1Month, "1mo"},
+	{CandlestickInterval{Count: 3, Unit: ohlc.UnitMonth}, "3mo"},
+}
+
+// csvTimeLayouts layouts of csv time column, times without zone are in NYSESession location
+var csvTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// CSVStockClient client for quotes in yahoo-style csv over http:
+// header with Date (or Datetime, Timestamp), Open, High, Low, Close and Volume columns
+type CSVStockClient struct {
+	urlTemplate string
+	currency    string
+	client      *http.Client
+	logger      *zap.Logger
+}
+
+// NewCSVStockClient creates CSVStockClient, urlTemplate placeholders {ticker}, {from}, {to}
+// and {interval} are replaced with ticker, unix times of period and interval code ("1d", "5m", ...)
+func NewCSVStockClient(urlTemplate, currency string, logger *zap.Logger) *CSVStockClient {
+	if currency == "" {
+		currency = DefaultCSVCurrency
+	}
+	return &CSVStockClient{
+		urlTemplate: urlTemplate,
+		currency:    currency,
+		client:      &http.Client{Timeout: httpClientTimeout},
+		logger:      logger,
+	}
+}
+
+// GetCandlesticks returns candlesticks for specified period
+func (c *CSVStockClient) GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error) {
+	i, err := nativeInterval(interval, len(csvIntervals), func(i int) CandlestickInterval {
+		return csvIntervals[i].Interval
+	})
+	if err != nil {
+		return nil, err
+	}
+	native := csvIntervals[i]
+	resample := native.Interval != interval
+	if resample {
+		// first bucket is complete only if fetched from its start
+		from = interval.Start(from, NYSESession)
+	}
+
+	logger := logging.FromContext(ctx, c.logger).With(
+		zap.String("ticker", ticker),
+		zap.Stringer("interval", interval),
+		zap.Stringer("native_interval", native.Interval),
+	)
+	start := time.Now()
+	candles, err := c.candles(ctx, ticker, from, to, native.Code)
+	if err != nil {
+		logger.Warn("csv candles request failed", zap.Duration("elapsed", time.Since(start)), zap.Error(err))
+		return nil, err
+	}
+	logger.Debug("csv candles request done", zap.Duration("elapsed", time.Since(start)), zap.Int("candles", len(candles)))
+
+	if resample {
+		candles = ohlc.Resample(candles, interval, NYSESession)
+	}
+	return &ohlc.CandlesticksData{
+		TOHLCs:   candles,
+		Name:     ticker,
+		Ticker:   ticker,
+		Currency: c.currency,
+		Interval: interval.String(),
+	}, nil
+}
+
+func (c *CSVStockClient) candles(ctx context.Context, ticker string, from, to time.Time, code string) ([]ohlc.TOHLCV, error) {
+	requestURL := strings.NewReplacer(
+		"{ticker}", url.PathEscape(ticker),
+		"{from}", strconv.FormatInt(from.Unix(), 10),
+		"{to}", strconv.FormatInt(to.Unix(), 10),
+		"{interval}", code,
+	).Replace(c.urlTemplate)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not create request")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not do request")
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrUnknownTicker
+	case resp.StatusCode != http.StatusOK:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &UpstreamError{
+			StatusCode: resp.StatusCode,
+			Err:        errors.Errorf("csv quotes of %s: %s", ticker, strings.TrimSpace(string(body))),
+		}
+	}
+
+	candles, err := parseCSVCandles(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not parse csv quotes")
+	}
+	// providers may round period to whole days
+	result := candles[:0]
+	for _, candle := range candles {
+		if candle.Timestamp >= from.Unix() && candle.Timestamp <= to.Unix() {
+			result = append(result, candle)
+		}
+	}
+	return result, nil
+}
+
+// parseCSVCandles parses csv quotes, rows with missing values ("null") are skipped
+func parseCSVCandles(r io.Reader) ([]ohlc.TOHLCV, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "can not read header")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	timeColumn := -1
+	for _, name := range []string{"date", "datetime", "timestamp", "time"} {
+		if i, ok := columns[name]; ok {
+			timeColumn = i
+			break
+		}
+	}
+	if timeColumn < 0 {
+		return nil, errors.New("no time column")
+	}
+	priceColumns := make([]int, 0, 5)
+	for _, name := range []string{"open", "high", "low", "close", "volume"} {
+		i, ok := columns[name]
+		if !ok {
+			return nil, errors.Errorf("no %s column", name)
+		}
+		priceColumns = append(priceColumns, i)
+	}
+
+	var candles []ohlc.TOHLCV
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ts, err := parseCSVTime(record[timeColumn])
+		if err != nil {
+			return nil, err
+		}
+		values := make([]float64, len(priceColumns))
+		complete := true
+		for i, column := range priceColumns {
+			values[i], err = strconv.ParseFloat(strings.TrimSpace(record[column]), 64)
+			if err != nil {
+				complete = false
+				break
+			}
+		}
+		if !complete {
+			continue
+		}
+		candles = append(candles, ohlc.TOHLCV{
+			Timestamp: ts.Unix(),
+			OHLCV: ohlc.OHLCV{
+				Open:   values[0],
+				High:   values[1],
+				Low:    values[2],
+				Close:  values[3],
+				Volume: values[4],
+			},
+		})
+	}
+	return candles, nil
+}
+
+// parseCSVTime parses unix seconds or one of csvTimeLayouts
+func parseCSVTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, NYSESession.Location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("can not parse time %q", s)
+}
@@ -0,0 +1,106 @@
+package stockapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const csvQuotes = `Date,Open,High,Low,Close,Adj Close,Volume
+2021-12-01,100,102,99,101,101,1000
+2021-12-02,null,null,null,null,null,null
+2021-12-03,101,105,100,104,104,2000
+2021-12-06,104,106,103,105,105,3000
+`
+
+func TestParseCSVCandles(t *testing.T) {
+	candles, err := parseCSVCandles(strings.NewReader(csvQuotes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 3 {
+		t.Fatalf("got %d candles, want 3, null row is skipped", len(candles))
+	}
+	first := candles[0]
+	if first.Timestamp != time.Date(2021, 12, 1, 0, 0, 0, 0, NYSESession.Location).Unix() ||
+		first.Open != 100 || first.High != 102 || first.Low != 99 || first.Close != 101 || first.Volume != 1000 {
+		t.Errorf("first candle = %+v", first)
+	}
+
+	for _, bad := range []string{
+		"Open,High,Low,Close,Volume\n1,2,3,4,5\n",
+		"Date,Open,High,Low,Close\n2021-12-01,1,2,3,4\n",
+		"Date,Open,High,Low,Close,Volume\nyesterday,1,2,3,4,5\n",
+	} {
+		if _, err := parseCSVCandles(strings.NewReader(bad)); err == nil {
+			t.Errorf("no error for %q", bad)
+		}
+	}
+}
+
+func TestParseCSVTime(t *testing.T) {
+	want := time.Date(2021, 12, 1, 9, 30, 0, 0, NYSESession.Location)
+	for _, s := range []string{
+		"1638369000",
+		"2021-12-01T09:30:00-05:00",
+		"2021-12-01 09:30:00-05:00",
+		"2021-12-01 09:30:00",
+	} {
+		got, err := parseCSVTime(s)
+		if err != nil {
+			t.Errorf("parseCSVTime(%q): %v", s, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("parseCSVTime(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestCSVStockClient(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		switch r.URL.Path {
+		case "/AAPL":
+			w.Write([]byte(csvQuotes))
+		case "/NONE":
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+	client := NewCSVStockClient(server.URL+"/{ticker}?period1={from}&period2={to}&interval={interval}", "", zap.NewNop())
+
+	from := time.Date(2021, 12, 1, 0, 0, 0, 0, NYSESession.Location)
+	to := time.Date(2021, 12, 5, 0, 0, 0, 0, NYSESession.Location)
+	data, err := client.GetCandlesticks(context.Background(), from, to, CandlestickInterval1Day, "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/AAPL?period1=1638334800&period2=1638680400&interval=1d"; requested != want {
+		t.Errorf("requested %s, want %s", requested, want)
+	}
+	if data.Currency != DefaultCSVCurrency {
+		t.Errorf("currency = %s, want %s", data.Currency, DefaultCSVCurrency)
+	}
+	if len(data.TOHLCs) != 2 {
+		t.Errorf("got %d candles, want 2, candles out of period are dropped", len(data.TOHLCs))
+	}
+
+	if _, err := client.GetCandlesticks(context.Background(), from, to, CandlestickInterval1Day, "NONE"); !errors.Is(err, ErrUnknownTicker) {
+		t.Errorf("error of 404 = %v, want ErrUnknownTicker", err)
+	}
+	_, err = client.GetCandlesticks(context.Background(), from, to, CandlestickInterval1Day, "MSFT")
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("error of 429 = %v, want UpstreamError", err)
+	}
+}
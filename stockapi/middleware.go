@@ -0,0 +1,247 @@
+package stockapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
+	"github.com/Apakhov/stocks-bot/ratelimit"
+
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen error for calls rejected while upstream is considered down
+var ErrCircuitOpen = errors.New("upstream circuit is open")
+
+// Methods of wrapped client passed to middlewares
+const (
+	MethodCandles     = "candles"
+	MethodOrderBook   = "orderbook"
+	MethodInstruments = "instruments"
+)
+
+// UpstreamError error response of upstream api with http status
+type UpstreamError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("upstream responded %d: %v", e.StatusCode, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports if call failed because of upstream and may succeed later:
+// 429 and 5xx responses, network errors and timeouts
+func IsRetryable(err error) bool {
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.StatusCode == http.StatusTooManyRequests || upstreamErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Call one call of wrapped client
+type Call func(ctx context.Context) error
+
+// Middleware wraps calls of method
+type Middleware func(method string, next Call) Call
+
+// Wrap returns client calling next through middlewares, the first middleware is the outermost,
+// order book and instruments of next stay available through type assertions
+func Wrap(next StockClient, middlewares ...Middleware) StockClient {
+	var orderBook OrderBookClient
+	if orderBookClient, ok := next.(OrderBookClient); ok {
+		orderBook = &wrappedOrderBookClient{next: orderBookClient, middlewares: middlewares}
+	}
+	instrumentClient, _ := next.(InstrumentClient)
+	return compose(&wrappedClient{next: next, middlewares: middlewares}, orderBook, instrumentClient)
+}
+
+// chain applies middlewares to call
+func chain(middlewares []Middleware, method string, call Call) Call {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		call = middlewares[i](method, call)
+	}
+	return call
+}
+
+type wrappedClient struct {
+	next        StockClient
+	middlewares []Middleware
+}
+
+// GetCandlesticks returns candlesticks of next client
+func (c *wrappedClient) GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error) {
+	var data *ohlc.CandlesticksData
+	err := chain(c.middlewares, MethodCandles, func(ctx context.Context) error {
+		var err error
+		data, err = c.next.GetCandlesticks(ctx, from, to, interval, ticker)
+		return err
+	})(ctx)
+	return data, err
+}
+
+type wrappedOrderBookClient struct {
+	next        OrderBookClient
+	middlewares []Middleware
+}
+
+// GetOrderBook returns order book of next client
+func (c *wrappedOrderBookClient) GetOrderBook(ctx context.Context, depth int, ticker string) (*ohlc.OrderBook, error) {
+	var book *ohlc.OrderBook
+	err := chain(c.middlewares, MethodOrderBook, func(ctx context.Context) error {
+		var err error
+		book, err = c.next.GetOrderBook(ctx, depth, ticker)
+		return err
+	})(ctx)
+	return book, err
+}
+
+// RateLimit waits for bucket token before each call, calls which had to wait are counted as throttled
+func RateLimit(bucket *ratelimit.Bucket, metrics *PolicyMetrics) Middleware {
+	return func(method string, next Call) Call {
+		return func(ctx context.Context) error {
+			if !bucket.Allow() {
+				metrics.Throttled.WithLabelValues(method).Inc()
+				if err := bucket.Wait(ctx); err != nil {
+					return err
+				}
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// Timeout limits duration of each call
+func Timeout(timeout time.Duration) Middleware {
+	return func(method string, next Call) Call {
+		return func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx)
+		}
+	}
+}
+
+// Retry repeats retryable calls up to retries times, delay before retry n is random
+// in [0, min(maxBackoff, backoff*2^n)) so clients do not retry in lockstep
+func Retry(retries int, backoff, maxBackoff time.Duration, metrics *PolicyMetrics, logger *zap.Logger) Middleware {
+	return func(method string, next Call) Call {
+		return func(ctx context.Context) error {
+			for attempt := 0; ; attempt++ {
+				err := next(ctx)
+				if err == nil || attempt >= retries || !IsRetryable(err) || ctx.Err() != nil {
+					return err
+				}
+
+				delay := backoff << uint(attempt)
+				if delay > maxBackoff || delay <= 0 {
+					delay = maxBackoff
+				}
+				delay = time.Duration(rand.Int63n(int64(delay) + 1))
+				metrics.Retried.WithLabelValues(method).Inc()
+				logging.FromContext(ctx, logger).Warn("retrying upstream call",
+					zap.String("method", method),
+					zap.Int("attempt", attempt+1),
+					zap.Duration("delay", delay),
+					zap.Error(err),
+				)
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				}
+			}
+		}
+	}
+}
+
+// CircuitBreaker rejects calls with ErrCircuitOpen for cooldown after failures
+// consecutive retryable errors, then lets one probe call through, successful probe closes circuit
+func CircuitBreaker(failures int, cooldown time.Duration, metrics *PolicyMetrics, logger *zap.Logger) Middleware {
+	b := &breaker{threshold: failures, cooldown: cooldown, logger: logger}
+	return func(method string, next Call) Call {
+		return func(ctx context.Context) error {
+			if !b.allow(time.Now()) {
+				metrics.Rejected.WithLabelValues(method).Inc()
+				return ErrCircuitOpen
+			}
+			err := next(ctx)
+			b.record(time.Now(), err)
+			return err
+		}
+	}
+}
+
+// breaker state of circuit breaker shared by all methods
+type breaker struct {
+	mu sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	open      bool
+	openedAt  time.Time
+	probing   bool
+
+	logger *zap.Logger
+}
+
+// allow reports if call can be made, only one probe call is allowed after cooldown
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+	if b.probing || now.Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record updates state with result of allowed call, only success closes circuit;
+// canceled calls and other not retryable errors, e.g. unknown ticker, leave state unchanged
+func (b *breaker) record(now time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		if b.open {
+			b.logger.Info("upstream circuit closed")
+		}
+		b.failures = 0
+		b.open = false
+		return
+	}
+	if errors.Is(err, context.Canceled) || !IsRetryable(err) {
+		return
+	}
+
+	b.failures++
+	if b.open || b.failures >= b.threshold {
+		if !b.open {
+			b.logger.Warn("upstream circuit opened", zap.Int("failures", b.failures), zap.Duration("cooldown", b.cooldown))
+		}
+		b.open = true
+		b.openedAt = now
+	}
+}
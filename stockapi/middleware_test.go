@@ -0,0 +1,73 @@
+package stockapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestBreakerRecord(t *testing.T) {
+	upstreamErr := &UpstreamError{StatusCode: http.StatusBadGateway}
+	now := time.Unix(0, 0)
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantFailures int
+		wantOpen     bool
+	}{
+		{"upstream failures open", []error{upstreamErr, upstreamErr}, 2, true},
+		{"success resets", []error{upstreamErr, nil}, 0, false},
+		{"unknown ticker keeps failures", []error{upstreamErr, ErrUnknownTicker}, 1, false},
+		{"unknown ticker keeps circuit open", []error{upstreamErr, upstreamErr, ErrUnknownTicker}, 2, true},
+		{"range error keeps circuit open", []error{upstreamErr, upstreamErr, fmt.Errorf("%w: too long", ErrRangeTooLong)}, 2, true},
+		{"canceled keeps circuit open", []error{upstreamErr, upstreamErr, context.Canceled}, 2, true},
+		{"success closes", []error{upstreamErr, upstreamErr, nil}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &breaker{threshold: 2, cooldown: time.Minute, logger: zap.NewNop()}
+			for _, err := range tt.errs {
+				b.record(now, err)
+			}
+			if b.failures != tt.wantFailures || b.open != tt.wantOpen {
+				t.Errorf("failures, open = %d, %v, want %d, %v", b.failures, b.open, tt.wantFailures, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	metrics := NewPolicyMetrics(nil)
+	var upstream error
+	breakerCall := CircuitBreaker(1, 50*time.Millisecond, metrics, zap.NewNop())(MethodCandles, func(ctx context.Context) error {
+		return upstream
+	})
+	// call calls through breaker with upstream returning err
+	call := func(err error) error {
+		upstream = err
+		return breakerCall(context.Background())
+	}
+
+	call(&UpstreamError{StatusCode: http.StatusServiceUnavailable})
+	if err := call(nil); err != ErrCircuitOpen {
+		t.Fatalf("call during cooldown error = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	// local error of probe does not close circuit, but lets next call probe again
+	if err := call(ErrUnknownTicker); err != ErrUnknownTicker {
+		t.Fatalf("probe error = %v, want ErrUnknownTicker", err)
+	}
+	if err := call(nil); err != nil {
+		t.Fatalf("second probe error = %v, want nil", err)
+	}
+	if err := call(ErrUnknownTicker); err != ErrUnknownTicker {
+		t.Fatalf("call after close error = %v, want ErrUnknownTicker", err)
+	}
+}
@@ -0,0 +1,280 @@
+package stockapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultMOEXURL base url of MOEX ISS api
+	DefaultMOEXURL = "https://iss.moex.com"
+
+	moexTimeLayout = "2006-01-02 15:04:05"
+	// moexPrimaryBoard main board of shares, preferred when security trades on several boards
+	moexPrimaryBoard  = "TQBR"
+	moexMaxPages      = 100
+	httpClientTimeout = 30 * time.Second
+)
+
+// moexCurrencies ISS currency codes which differ from ISO ones
+var moexCurrencies = map[string]string{
+	"SUR": "RUB",
+}
+
+// moexIntervals native ISS intervals from finest to coarsest
+var moexIntervals = []struct {
+	Interval CandlestickInterval
+	Code     int
+}{
+	{CandlestickInterval1Min, 1},
+	{CandlestickInterval{Count: 10, Unit: ohlc.UnitMinute}, 10},
+	{CandlestickInterval1Hour, 60},
+	{CandlestickInterval1Day, 24},
+	{CandlestickInterval1Week, 7},
+	{CandlestickInterval1Month, 31},
+	{CandlestickInterval{Count: 3, Unit: ohlc.UnitMonth}, 4},
+}
+
+// moexSecurity description of share on MOEX
+type moexSecurity struct {
+	Ticker            string
+	Name              string
+	Currency          string
+	MinPriceIncrement float64
+}
+
+// MOEXStockClient client for MOEX ISS api, serves shares and ETFs of stock market
+type MOEXStockClient struct {
+	baseURL string
+	client  *http.Client
+
+	securitiesMu sync.RWMutex
+	securities   map[string]*moexSecurity
+
+	logger *zap.Logger
+}
+
+// NewMOEXStockClient creates MOEXStockClient, empty baseURL means DefaultMOEXURL
+func NewMOEXStockClient(baseURL string, logger *zap.Logger) *MOEXStockClient {
+	if baseURL == "" {
+		baseURL = DefaultMOEXURL
+	}
+	return &MOEXStockClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		client:     &http.Client{Timeout: httpClientTimeout},
+		securities: make(map[string]*moexSecurity),
+		logger:     logger,
+	}
+}
+
+// issBlock table of ISS json response
+type issBlock struct {
+	Columns []string        `json:"columns"`
+	Data    [][]interface{} `json:"data"`
+}
+
+// rows returns rows of block as maps by column name
+func (b *issBlock) rows() []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(b.Data))
+	for _, data := range b.Data {
+		row := make(map[string]interface{}, len(b.Columns))
+		for i, column := range b.Columns {
+			if i < len(data) {
+				row[column] = data[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func issString(row map[string]interface{}, column string) string {
+	s, _ := row[column].(string)
+	return s
+}
+
+func issFloat(row map[string]interface{}, column string) float64 {
+	f, _ := row[column].(float64)
+	return f
+}
+
+// get requests ISS path and decodes response blocks
+func (c *MOEXStockClient) get(ctx context.Context, path string, query url.Values) (map[string]*issBlock, error) {
+	query.Set("iss.meta", "off")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not create request")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not do request")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &UpstreamError{
+			StatusCode: resp.StatusCode,
+			Err:        errors.Errorf("moex %s: %s", path, strings.TrimSpace(string(body))),
+		}
+	}
+
+	var blocks map[string]*issBlock
+	if err := json.Unmarshal(body, &blocks); err != nil {
+		return nil, errors.Wrap(err, "can not decode response")
+	}
+	return blocks, nil
+}
+
+// security returns cached description of share
+func (c *MOEXStockClient) security(ctx context.Context, ticker string) (*moexSecurity, error) {
+	c.securitiesMu.RLock()
+	security, ok := c.securities[ticker]
+	c.securitiesMu.RUnlock()
+	if ok {
+		return security, nil
+	}
+
+	blocks, err := c.get(ctx, "/iss/engines/stock/markets/shares/securities/"+url.PathEscape(ticker)+".json", url.Values{
+		"iss.only": {"securities"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	block, ok := blocks["securities"]
+	if !ok || len(block.Data) == 0 {
+		return nil, ErrUnknownTicker
+	}
+
+	rows := block.rows()
+	row := rows[0]
+	for _, r := range rows {
+		if issString(r, "BOARDID") == moexPrimaryBoard {
+			row = r
+			break
+		}
+	}
+	currency := issString(row, "CURRENCYID")
+	if iso, ok := moexCurrencies[currency]; ok {
+		currency = iso
+	}
+	name := issString(row, "SECNAME")
+	if name == "" {
+		name = issString(row, "SHORTNAME")
+	}
+	security = &moexSecurity{
+		Ticker:            issString(row, "SECID"),
+		Name:              name,
+		Currency:          currency,
+		MinPriceIncrement: issFloat(row, "MINSTEP"),
+	}
+
+	c.securitiesMu.Lock()
+	c.securities[ticker] = security
+	c.securitiesMu.Unlock()
+	return security, nil
+}
+
+// GetCandlesticks returns candlesticks for specified period
+func (c *MOEXStockClient) GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error) {
+	i, err := nativeInterval(interval, len(moexIntervals), func(i int) CandlestickInterval {
+		return moexIntervals[i].Interval
+	})
+	if err != nil {
+		return nil, err
+	}
+	native := moexIntervals[i]
+	resample := native.Interval != interval
+	// ISS serves MOEX shares only, so MOEX session is used for every ticker
+	if resample {
+		// first bucket is complete only if fetched from its start
+		from = interval.Start(from, MOEXSession)
+	}
+
+	security, err := c.security(ctx, ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.FromContext(ctx, c.logger).With(
+		zap.String("ticker", ticker),
+		zap.Stringer("interval", interval),
+		zap.Stringer("native_interval", native.Interval),
+	)
+	start := time.Now()
+	candles, err := c.candles(ctx, security.Ticker, from, to, native.Code)
+	if err != nil {
+		logger.Warn("moex candles request failed", zap.Duration("elapsed", time.Since(start)), zap.Error(err))
+		return nil, errors.Wrap(err, "can not get candles")
+	}
+	logger.Debug("moex candles request done", zap.Duration("elapsed", time.Since(start)), zap.Int("candles", len(candles)))
+
+	if resample {
+		candles = ohlc.Resample(candles, interval, MOEXSession)
+	}
+	return &ohlc.CandlesticksData{
+		TOHLCs:            candles,
+		Name:              security.Name,
+		Ticker:            security.Ticker,
+		Currency:          security.Currency,
+		MinPriceIncrement: security.MinPriceIncrement,
+		Interval:          interval.String(),
+	}, nil
+}
+
+// candles requests candles page by page until empty page
+func (c *MOEXStockClient) candles(ctx context.Context, ticker string, from, to time.Time, code int) ([]ohlc.TOHLCV, error) {
+	loc := MOEXSession.Location
+	query := url.Values{
+		"from":     {from.In(loc).Format(moexTimeLayout)},
+		"till":     {to.In(loc).Format(moexTimeLayout)},
+		"interval": {strconv.Itoa(code)},
+	}
+
+	var result []ohlc.TOHLCV
+	for page := 0; page < moexMaxPages; page++ {
+		query.Set("start", strconv.Itoa(len(result)))
+		blocks, err := c.get(ctx, "/iss/engines/stock/markets/shares/securities/"+url.PathEscape(ticker)+"/candles.json", query)
+		if err != nil {
+			return nil, err
+		}
+		block, ok := blocks["candles"]
+		if !ok || len(block.Data) == 0 {
+			return result, nil
+		}
+
+		for _, row := range block.rows() {
+			begin, err := time.ParseInLocation(moexTimeLayout, issString(row, "begin"), loc)
+			if err != nil {
+				return nil, errors.Wrap(err, "can not parse candle time")
+			}
+			result = append(result, ohlc.TOHLCV{
+				Timestamp: begin.Unix(),
+				OHLCV: ohlc.OHLCV{
+					Open:   issFloat(row, "open"),
+					High:   issFloat(row, "high"),
+					Low:    issFloat(row, "low"),
+					Close:  issFloat(row, "close"),
+					Volume: issFloat(row, "volume"),
+				},
+			})
+		}
+	}
+	return nil, errors.Errorf("more than %d pages of candles", moexMaxPages)
+}
@@ -0,0 +1,120 @@
+package stockapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const issSecurities = `{"securities": {
+	"columns": ["SECID", "BOARDID", "SHORTNAME", "SECNAME", "CURRENCYID", "MINSTEP"],
+	"data": [
+		["SBER", "SMAL", "Сбербанк", "Сбербанк (лоты)", "SUR", 1],
+		["SBER", "TQBR", "Сбербанк", "Сбербанк России ПАО ао", "SUR", 0.01]
+	]
+}}`
+
+// issCandlePages pages of candles by start parameter
+var issCandlePages = map[string]string{
+	"0": `{"candles": {"columns": ["open", "close", "high", "low", "volume", "begin"], "data": [
+		[100, 101, 102, 99, 10, "2021-12-01 10:00:00"],
+		[101, 102, 103, 100, 20, "2021-12-01 11:00:00"]
+	]}}`,
+	"2": `{"candles": {"columns": ["open", "close", "high", "low", "volume", "begin"], "data": [
+		[102, 103, 104, 101, 30, "2021-12-01 12:00:00"]
+	]}}`,
+	"3": `{"candles": {"columns": ["open", "close", "high", "low", "volume", "begin"], "data": []}}`,
+}
+
+func newISSServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("iss.meta") != "off" {
+			t.Errorf("iss.meta is not off in %s", r.URL)
+		}
+		switch r.URL.Path {
+		case "/iss/engines/stock/markets/shares/securities/SBER.json":
+			w.Write([]byte(issSecurities))
+		case "/iss/engines/stock/markets/shares/securities/SBER/candles.json":
+			page, ok := issCandlePages[r.URL.Query().Get("start")]
+			if !ok {
+				t.Errorf("unexpected page %s", r.URL.Query().Get("start"))
+			}
+			w.Write([]byte(page))
+		case "/iss/engines/stock/markets/shares/securities/NONE.json":
+			w.Write([]byte(`{"securities": {"columns": ["SECID"], "data": []}}`))
+		default:
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+}
+
+func TestMOEXSecurity(t *testing.T) {
+	server := newISSServer(t)
+	defer server.Close()
+	client := NewMOEXStockClient(server.URL, zap.NewNop())
+
+	security, err := client.security(context.Background(), "SBER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := moexSecurity{Ticker: "SBER", Name: "Сбербанк России ПАО ао", Currency: "RUB", MinPriceIncrement: 0.01}
+	if *security != want {
+		t.Errorf("security = %+v, want %+v", *security, want)
+	}
+
+	if _, err := client.security(context.Background(), "NONE"); !errors.Is(err, ErrUnknownTicker) {
+		t.Errorf("security of unknown ticker error = %v, want ErrUnknownTicker", err)
+	}
+}
+
+func TestMOEXCandlesPaging(t *testing.T) {
+	server := newISSServer(t)
+	defer server.Close()
+	client := NewMOEXStockClient(server.URL, zap.NewNop())
+
+	from := time.Date(2021, 12, 1, 0, 0, 0, 0, MOEXSession.Location)
+	data, err := client.GetCandlesticks(context.Background(), from, from.AddDate(0, 0, 1), CandlestickInterval1Hour, "SBER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Currency != "RUB" || data.MinPriceIncrement != 0.01 {
+		t.Errorf("currency %s, increment %v, want RUB and 0.01", data.Currency, data.MinPriceIncrement)
+	}
+	if len(data.TOHLCs) != 3 {
+		t.Fatalf("got %d candles, want 3", len(data.TOHLCs))
+	}
+	for i, candle := range data.TOHLCs {
+		wantTime := from.Add(time.Duration(10+i) * time.Hour).Unix()
+		if candle.Timestamp != wantTime || candle.Open != float64(100+i) || candle.Volume != float64(10*(i+1)) {
+			t.Errorf("candle %d = %+v", i, candle)
+		}
+	}
+}
+
+func TestMOEXUpstreamError(t *testing.T) {
+	server := newISSServer(t)
+	defer server.Close()
+	client := NewMOEXStockClient(server.URL, zap.NewNop())
+
+	_, err := client.GetCandlesticks(context.Background(), time.Now().Add(-time.Hour), time.Now(), CandlestickInterval1Hour, "GAZP")
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		t.Fatalf("error = %v, want UpstreamError", err)
+	}
+	if upstreamErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", upstreamErr.StatusCode, http.StatusServiceUnavailable)
+	}
+	if !IsRetryable(err) {
+		t.Error("503 is not retryable")
+	}
+	if !strings.Contains(err.Error(), strconv.Itoa(http.StatusServiceUnavailable)) {
+		t.Errorf("error %q has no status", err)
+	}
+}
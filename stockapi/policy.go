@@ -0,0 +1,125 @@
+package stockapi
+
+import (
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ratelimit"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPolicyRate            = 2
+	defaultPolicyBurst           = 10
+	defaultPolicyTimeout         = 15
+	defaultPolicyRetries         = 3
+	defaultPolicyRetryBackoff    = 0.2
+	defaultPolicyRetryMaxBackoff = 5
+	defaultPolicyBreakerFailures = 5
+	defaultPolicyBreakerCooldown = 30
+)
+
+// PolicyConfig config of upstream calls policy, zero fields use defaults,
+// negative Rate, Timeout, Retries or BreakerFailures disable the middleware
+type PolicyConfig struct {
+	// Rate allowed upstream requests per second of each provider, tinkoff client makes
+	// several requests for candles of long periods and each of them waits for the limit
+	Rate float64 `json:"Rate"`
+	// Burst allowed calls burst
+	Burst int `json:"Burst"`
+	// Timeout seconds for one call attempt
+	Timeout float64 `json:"Timeout"`
+	// Retries max retries of call failed with 429, 5xx or network error
+	Retries int `json:"Retries"`
+	// RetryBackoff seconds of first retry backoff, doubled on each next retry
+	RetryBackoff float64 `json:"RetryBackoff"`
+	// RetryMaxBackoff max seconds of retry backoff
+	RetryMaxBackoff float64 `json:"RetryMaxBackoff"`
+	// BreakerFailures consecutive failures opening circuit
+	BreakerFailures int `json:"BreakerFailures"`
+	// BreakerCooldown seconds circuit stays open
+	BreakerCooldown float64 `json:"BreakerCooldown"`
+}
+
+func (c PolicyConfig) withDefaults() PolicyConfig {
+	if c.Rate == 0 {
+		c.Rate = defaultPolicyRate
+	}
+	if c.Burst <= 0 {
+		c.Burst = defaultPolicyBurst
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultPolicyTimeout
+	}
+	if c.Retries == 0 {
+		c.Retries = defaultPolicyRetries
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = defaultPolicyRetryBackoff
+	}
+	if c.RetryMaxBackoff <= 0 {
+		c.RetryMaxBackoff = defaultPolicyRetryMaxBackoff
+	}
+	if c.BreakerFailures == 0 {
+		c.BreakerFailures = defaultPolicyBreakerFailures
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = defaultPolicyBreakerCooldown
+	}
+	return c
+}
+
+// PolicyMetrics metrics of upstream calls policy by method
+type PolicyMetrics struct {
+	Throttled *prometheus.CounterVec
+	Retried   *prometheus.CounterVec
+	Rejected  *prometheus.CounterVec
+}
+
+// NewPolicyMetrics creates policy metrics registered in registerer, nil registerer leaves them unregistered
+func NewPolicyMetrics(registerer prometheus.Registerer) *PolicyMetrics {
+	metrics := &PolicyMetrics{
+		Throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stockapi_throttled_total",
+			Help: "Upstream calls which waited for rate limit.",
+		}, []string{"method"}),
+		Retried: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stockapi_retried_total",
+			Help: "Retries of failed upstream calls.",
+		}, []string{"method"}),
+		Rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stockapi_rejected_total",
+			Help: "Upstream calls rejected by open circuit.",
+		}, []string{"method"}),
+	}
+	if registerer != nil {
+		registerer.MustRegister(metrics.Throttled, metrics.Retried, metrics.Rejected)
+	}
+	return metrics
+}
+
+// NewPolicy returns middlewares of one upstream: retry, circuit breaker, rate limiter and timeout,
+// so each retry goes through breaker and limiter and gets its own timeout.
+// Each call creates its own breaker and token bucket
+func NewPolicy(cfg PolicyConfig, metrics *PolicyMetrics, logger *zap.Logger) []Middleware {
+	cfg = cfg.withDefaults()
+	seconds := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second))
+	}
+
+	var middlewares []Middleware
+	if cfg.Retries > 0 {
+		middlewares = append(middlewares, Retry(cfg.Retries, seconds(cfg.RetryBackoff), seconds(cfg.RetryMaxBackoff), metrics, logger))
+	}
+	if cfg.BreakerFailures > 0 {
+		middlewares = append(middlewares, CircuitBreaker(cfg.BreakerFailures, seconds(cfg.BreakerCooldown), metrics, logger))
+	}
+	if cfg.Rate > 0 {
+		middlewares = append(middlewares, RateLimit(ratelimit.NewBucket(cfg.Rate, cfg.Burst), metrics))
+	}
+	if cfg.Timeout > 0 {
+		middlewares = append(middlewares, Timeout(seconds(cfg.Timeout)))
+	}
+	return middlewares
+}
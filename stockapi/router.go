@@ -0,0 +1,229 @@
+package stockapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/instruments"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"go.uber.org/zap"
+)
+
+// Provider names used in routes
+const (
+	ProviderTinkoff = "tinkoff"
+	ProviderMOEX    = "moex"
+	ProviderCSV     = "csv"
+)
+
+// knownProviders providers which can be used in routes
+var knownProviders = map[string]bool{
+	ProviderTinkoff: true,
+	ProviderMOEX:    true,
+	ProviderCSV:     true,
+}
+
+// Exchanges instruments are routed by, instruments of other exchanges use ExchangeOther route
+const (
+	ExchangeMOEX  = "MOEX"
+	ExchangeUS    = "US"
+	ExchangeOther = ""
+)
+
+const (
+	defaultRouteMOEX  = ProviderTinkoff + "," + ProviderMOEX
+	defaultRouteUS    = ProviderTinkoff + "," + ProviderCSV
+	defaultRouteOther = ProviderTinkoff
+)
+
+// ProvidersConfig config of additional quote providers and routing between them,
+// routes are comma separated provider names tried in order until one succeeds
+type ProvidersConfig struct {
+	// MOEXURL base url of MOEX ISS api, empty disables provider
+	MOEXURL string `json:"MOEXURL"`
+	// CSVURL url template of csv quotes, see NewCSVStockClient, empty disables provider
+	CSVURL string `json:"CSVURL"`
+	// CSVCurrency currency of csv quotes, USD by default
+	CSVCurrency string `json:"CSVCurrency"`
+	// RouteMOEX providers of shares traded in RUB, "tinkoff,moex" by default
+	RouteMOEX string `json:"RouteMOEX"`
+	// RouteUS providers of shares traded in USD, "tinkoff,csv" by default
+	RouteUS string `json:"RouteUS"`
+	// RouteOther providers of other instruments, "tinkoff" by default
+	RouteOther string `json:"RouteOther"`
+}
+
+// NewStockClient creates tinkoff client and, if configured, MOEX and csv providers behind RouterStockClient.
+// Each provider gets its own policy: tinkoff applies it to every upstream request,
+// other providers are wrapped as a whole
+func NewStockClient(tinkoffToken string, cfg ProvidersConfig, policy PolicyConfig, metrics *PolicyMetrics, logger *zap.Logger) (StockClient, error) {
+	tinkoff, err := NewTinkoffStockClient(tinkoffToken, NewPolicy(policy, metrics, logger), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := map[string]StockClient{ProviderTinkoff: tinkoff}
+	if cfg.MOEXURL != "" {
+		moexLogger := logger.Named(ProviderMOEX)
+		providers[ProviderMOEX] = Wrap(NewMOEXStockClient(cfg.MOEXURL, moexLogger), NewPolicy(policy, metrics, moexLogger)...)
+	}
+	if cfg.CSVURL != "" {
+		csvLogger := logger.Named(ProviderCSV)
+		providers[ProviderCSV] = Wrap(NewCSVStockClient(cfg.CSVURL, cfg.CSVCurrency, csvLogger), NewPolicy(policy, metrics, csvLogger)...)
+	}
+	if len(providers) == 1 {
+		return tinkoff, nil
+	}
+
+	routes := map[string]string{
+		ExchangeMOEX:  cfg.RouteMOEX,
+		ExchangeUS:    cfg.RouteUS,
+		ExchangeOther: cfg.RouteOther,
+	}
+	defaults := map[string]string{
+		ExchangeMOEX:  defaultRouteMOEX,
+		ExchangeUS:    defaultRouteUS,
+		ExchangeOther: defaultRouteOther,
+	}
+	for exchange, route := range routes {
+		if route == "" {
+			routes[exchange] = defaults[exchange]
+		}
+	}
+	registry := tinkoff.(InstrumentClient).Instruments()
+	return NewRouterStockClient(ProviderTinkoff, providers, routes, RegistryListing(registry), logger)
+}
+
+// Listing exchange of instrument and its ticker there
+type Listing struct {
+	Exchange string
+	Ticker   string
+}
+
+// ListingFunc returns listing of ticker, ok is false for unknown ticker
+type ListingFunc func(ticker string) (listing Listing, ok bool)
+
+// RegistryListing returns listings by instruments of registry:
+// stocks and ETFs in RUB are listed on MOEX, in USD — on US exchanges
+func RegistryListing(registry *instruments.Registry) ListingFunc {
+	return func(ticker string) (Listing, bool) {
+		instrument, ok := registry.Lookup(ticker)
+		if !ok {
+			return Listing{}, false
+		}
+		listing := Listing{Exchange: ExchangeOther, Ticker: instrument.Ticker}
+		if instrument.Type == instruments.TypeStock || instrument.Type == instruments.TypeETF {
+			switch instrument.Currency {
+			case BaseCurrency:
+				listing.Exchange = ExchangeMOEX
+			case "USD":
+				listing.Exchange = ExchangeUS
+			}
+		}
+		return listing, true
+	}
+}
+
+// CalendarOf returns trading calendar of ticker listing, MOEX for unknown tickers
+func CalendarOf(client StockClient, ticker string) *calendar.Calendar {
+	instrumentClient, ok := client.(InstrumentClient)
+	if !ok {
+		return calendar.MOEX
+	}
+	if listing, ok := RegistryListing(instrumentClient.Instruments())(ticker); ok && listing.Exchange == ExchangeUS {
+		return calendar.NYSE
+	}
+	return calendar.MOEX
+}
+
+// SessionOf returns session of cal used to align resampled candles
+func SessionOf(cal *calendar.Calendar) ohlc.Session {
+	return ohlc.Session{Location: cal.Location, Open: cal.Open}
+}
+
+// RouterStockClient routes candle requests to providers by exchange of instrument,
+// next provider of route is tried when previous one fails
+type RouterStockClient struct {
+	primary   string
+	providers map[string]StockClient
+	routes    map[string][]string
+	listing   ListingFunc
+	logger    *zap.Logger
+}
+
+// NewRouterStockClient creates RouterStockClient, routes map exchanges to comma separated
+// provider names, route of unknown exchange is routes[ExchangeOther].
+// Primary provider gets tickers as requested, others get listing ticker;
+// order book and instruments of primary stay available through type assertions
+func NewRouterStockClient(primary string, providers map[string]StockClient, routes map[string]string, listing ListingFunc, logger *zap.Logger) (StockClient, error) {
+	primaryClient, ok := providers[primary]
+	if !ok {
+		return nil, fmt.Errorf("unknown primary provider %q", primary)
+	}
+
+	r := &RouterStockClient{
+		primary:   primary,
+		providers: providers,
+		routes:    make(map[string][]string, len(routes)),
+		listing:   listing,
+		logger:    logger,
+	}
+	for exchange, route := range routes {
+		for _, name := range strings.Split(route, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := providers[name]; ok {
+				r.routes[exchange] = append(r.routes[exchange], name)
+				continue
+			}
+			// known providers which are not configured are skipped
+			if name != "" && !knownProviders[name] {
+				return nil, fmt.Errorf("unknown provider %q in route of %q", name, exchange)
+			}
+		}
+	}
+	if len(r.routes[ExchangeOther]) == 0 {
+		r.routes[ExchangeOther] = []string{primary}
+	}
+
+	orderBook, _ := primaryClient.(OrderBookClient)
+	instrumentClient, _ := primaryClient.(InstrumentClient)
+	return compose(r, orderBook, instrumentClient), nil
+}
+
+// GetCandlesticks returns candlesticks of the first provider of route which succeeds,
+// ErrUnknownTicker is returned only if no provider knows ticker
+func (r *RouterStockClient) GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error) {
+	listing, known := r.listing(ticker)
+	route := r.routes[listing.Exchange]
+	if len(route) == 0 {
+		route = r.routes[ExchangeOther]
+	}
+
+	logger := logging.FromContext(ctx, r.logger).With(zap.String("ticker", ticker), zap.String("exchange", listing.Exchange))
+	var firstErr error
+	for _, name := range route {
+		providerTicker := ticker
+		if known && name != r.primary {
+			providerTicker = listing.Ticker
+		}
+		data, err := r.providers[name].GetCandlesticks(ctx, from, to, interval, providerTicker)
+		if err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		logger.Warn("provider failed", zap.String("provider", name), zap.Error(err))
+		if firstErr == nil || errors.Is(firstErr, ErrUnknownTicker) {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,134 @@
+package stockapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Apakhov/stocks-bot/ohlc"
+
+	"go.uber.org/zap"
+)
+
+// fakeProvider StockClient returning err or candles of requested ticker
+type fakeProvider struct {
+	err     error
+	tickers []string
+	// cancel is called on request if set
+	cancel func()
+}
+
+func (p *fakeProvider) GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error) {
+	p.tickers = append(p.tickers, ticker)
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &ohlc.CandlesticksData{Ticker: ticker}, nil
+}
+
+func testListing(ticker string) (Listing, bool) {
+	switch ticker {
+	case "SBER":
+		return Listing{Exchange: ExchangeMOEX, Ticker: "SBER"}, true
+	case "SPCE":
+		return Listing{Exchange: ExchangeUS, Ticker: "SPCE.US"}, true
+	}
+	return Listing{}, false
+}
+
+func newTestRouter(t *testing.T, providers map[string]StockClient) StockClient {
+	client, err := NewRouterStockClient(ProviderTinkoff, providers, map[string]string{
+		ExchangeMOEX: "tinkoff,moex",
+		ExchangeUS:   "tinkoff,csv",
+	}, testListing, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRouterFallback(t *testing.T) {
+	upstreamErr := &UpstreamError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")}
+	tinkoff := &fakeProvider{err: upstreamErr}
+	moex := &fakeProvider{}
+	csv := &fakeProvider{}
+	router := newTestRouter(t, map[string]StockClient{ProviderTinkoff: tinkoff, ProviderMOEX: moex, ProviderCSV: csv})
+
+	if _, err := router.GetCandlesticks(context.Background(), time.Time{}, time.Time{}, CandlestickInterval1Day, "SBER"); err != nil {
+		t.Fatal(err)
+	}
+	if len(tinkoff.tickers) != 1 || len(moex.tickers) != 1 || len(csv.tickers) != 0 {
+		t.Errorf("MOEX route calls: tinkoff %v, moex %v, csv %v", tinkoff.tickers, moex.tickers, csv.tickers)
+	}
+
+	data, err := router.GetCandlesticks(context.Background(), time.Time{}, time.Time{}, CandlestickInterval1Day, "SPCE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Ticker != "SPCE.US" || tinkoff.tickers[1] != "SPCE" {
+		t.Errorf("primary got %s, fallback got %s: want requested ticker and listing ticker", tinkoff.tickers[1], data.Ticker)
+	}
+
+	// unknown tickers use ExchangeOther route of primary only
+	if _, err := router.GetCandlesticks(context.Background(), time.Time{}, time.Time{}, CandlestickInterval1Day, "XXX"); err != upstreamErr {
+		t.Errorf("error = %v, want error of primary", err)
+	}
+}
+
+func TestRouterUnknownTicker(t *testing.T) {
+	upstreamErr := &UpstreamError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("unavailable")}
+	for _, tc := range []struct {
+		name          string
+		tinkoff, moex error
+		want          error
+	}{
+		{"all unknown", ErrUnknownTicker, ErrUnknownTicker, ErrUnknownTicker},
+		{"unknown then failed", ErrUnknownTicker, upstreamErr, upstreamErr},
+		{"failed then unknown", upstreamErr, ErrUnknownTicker, upstreamErr},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newTestRouter(t, map[string]StockClient{
+				ProviderTinkoff: &fakeProvider{err: tc.tinkoff},
+				ProviderMOEX:    &fakeProvider{err: tc.moex},
+			})
+			_, err := router.GetCandlesticks(context.Background(), time.Time{}, time.Time{}, CandlestickInterval1Day, "SBER")
+			if !errors.Is(err, tc.want) {
+				t.Errorf("error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestRouterContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tinkoff := &fakeProvider{err: context.Canceled, cancel: cancel}
+	moex := &fakeProvider{}
+	router := newTestRouter(t, map[string]StockClient{ProviderTinkoff: tinkoff, ProviderMOEX: moex})
+
+	if _, err := router.GetCandlesticks(ctx, time.Time{}, time.Time{}, CandlestickInterval1Day, "SBER"); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if len(moex.tickers) != 0 {
+		t.Error("next provider is called after context is done")
+	}
+}
+
+func TestRouterRoutes(t *testing.T) {
+	providers := map[string]StockClient{ProviderTinkoff: &fakeProvider{}}
+	// known providers which are not configured are skipped
+	if _, err := NewRouterStockClient(ProviderTinkoff, providers, map[string]string{ExchangeMOEX: "tinkoff, moex"}, testListing, zap.NewNop()); err != nil {
+		t.Errorf("route with not configured provider: %v", err)
+	}
+	if _, err := NewRouterStockClient(ProviderTinkoff, providers, map[string]string{ExchangeUS: "tinkoff,yahoo"}, testListing, zap.NewNop()); err == nil {
+		t.Error("no error for unknown provider in route")
+	}
+	if _, err := NewRouterStockClient(ProviderMOEX, providers, nil, testListing, zap.NewNop()); err == nil {
+		t.Error("no error for not configured primary provider")
+	}
+}
@@ -2,12 +2,18 @@ package stockapi
 
 import (
 	"context"
+	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/Apakhov/stocks-bot/calendar"
+	"github.com/Apakhov/stocks-bot/instruments"
+	"github.com/Apakhov/stocks-bot/logging"
 	"github.com/Apakhov/stocks-bot/ohlc"
 
 	sdk "github.com/TinkoffCreditSystems/invest-openapi-go-sdk"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 var (
@@ -15,63 +21,155 @@ var (
 	ErrUnknownTicker = errors.New("ticker is unknown")
 )
 
-// TinkoffStockDescription description.
-type TinkoffStockDescription struct {
-	FIGI     string
-	Ticker   string
-	Name     string
-	Currency string
+const (
+	// InstrumentsRefreshInterval how often list of tinkoff instruments is reloaded
+	InstrumentsRefreshInterval = 6 * time.Hour
+
+	// maxChunkConcurrency max number of concurrent candle requests of one GetCandlesticks call
+	maxChunkConcurrency = 4
+)
+
+// tinkoffAliases tickers kept for compatibility with featured lists
+var tinkoffAliases = map[string]string{
+	"USDRUB": "USD000UTSTOM",
+	"EURRUB": "EUR_RUB__TOM",
 }
 
 // TinkoffStockClient client for tinkoff api
 type TinkoffStockClient struct {
-	client *sdk.SandboxRestClient
-	stocks map[string]*TinkoffStockDescription
+	client      *sdk.SandboxRestClient
+	registry    *instruments.Registry
+	middlewares []Middleware
+	logger      *zap.Logger
 }
 
-// NewTinkoffStockClient creates new TinkoffStockClient
-func NewTinkoffStockClient(token string) (StockClient, error) {
+// NewTinkoffStockClient creates new TinkoffStockClient, each request to api goes through middlewares,
+// e.g. NewPolicy, instruments are refreshed in background every InstrumentsRefreshInterval
+func NewTinkoffStockClient(token string, middlewares []Middleware, logger *zap.Logger) (StockClient, error) {
 	client := sdk.NewSandboxRestClient(token)
-	tcsStocks, err := client.Stocks(context.Background())
-	if err != nil {
-		return nil, errors.Wrap(err, "can not initialize list of available stocks")
+	c := &TinkoffStockClient{
+		client:      client,
+		middlewares: middlewares,
+		logger:      logger,
 	}
-
-	stocks := make(map[string]*TinkoffStockDescription, len(tcsStocks))
-	for _, stock := range tcsStocks {
-		description := &TinkoffStockDescription{
-			FIGI:     stock.FIGI,
-			Ticker:   stock.Ticker,
-			Name:     stock.Name,
-			Currency: string(stock.Currency),
-		}
-		stocks[stock.Ticker] = description
+	c.registry = instruments.NewRegistry(c.loadInstruments, tinkoffAliases, logger.Named("instruments"))
+	if err := c.registry.Refresh(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "can not initialize list of available instruments")
 	}
+	go c.registry.Run(context.Background(), InstrumentsRefreshInterval)
+
+	return c, nil
+}
+
+// Instruments returns registry of available instruments
+func (c *TinkoffStockClient) Instruments() *instruments.Registry {
+	return c.registry
+}
 
-	stocks["USDRUB"] = &TinkoffStockDescription{
-		FIGI:     "BBG0013HGFT4",
-		Ticker:   "USD000UTSTOM",
-		Name:     "USD",
-		Currency: "RUB",
+func (c *TinkoffStockClient) loadInstruments(ctx context.Context) ([]*instruments.Instrument, error) {
+	loaders := []struct {
+		typ  instruments.Type
+		load func(ctx context.Context) ([]sdk.Instrument, error)
+	}{
+		{instruments.TypeStock, c.client.Stocks},
+		{instruments.TypeETF, c.client.ETFs},
+		{instruments.TypeBond, c.client.Bonds},
+		{instruments.TypeCurrency, c.client.Currencies},
 	}
 
-	return &TinkoffStockClient{
-		client: client,
-		stocks: stocks,
-	}, nil
+	var result []*instruments.Instrument
+	for _, loader := range loaders {
+		var tcsInstruments []sdk.Instrument
+		err := chain(c.middlewares, MethodInstruments, func(ctx context.Context) error {
+			var err error
+			tcsInstruments, err = loader.load(ctx)
+			return upstreamError(err)
+		})(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not load %ss", loader.typ)
+		}
+		for _, instrument := range tcsInstruments {
+			result = append(result, &instruments.Instrument{
+				FIGI:              instrument.FIGI,
+				Ticker:            instrument.Ticker,
+				ISIN:              instrument.ISIN,
+				Name:              instrument.Name,
+				Type:              loader.typ,
+				Lot:               instrument.Lot,
+				MinPriceIncrement: instrument.MinPriceIncrement,
+				Currency:          string(instrument.Currency),
+			})
+		}
+	}
+	return result, nil
 }
 
 // GetCandlesticks returns candlesticks for specified period
 func (c *TinkoffStockClient) GetCandlesticks(ctx context.Context, from, to time.Time, interval CandlestickInterval, ticker string) (*ohlc.CandlesticksData, error) {
-	tcsDescription, ok := c.stocks[ticker]
+	tcsDescription, ok := c.registry.Lookup(ticker)
 	if !ok {
 		return nil, ErrUnknownTicker
 	}
 
-	candles, err := c.client.Candles(ctx, from, to, transformToTinkoffCandleInterval(interval), tcsDescription.FIGI)
+	native, err := transformToTinkoffCandleInterval(interval)
 	if err != nil {
+		return nil, err
+	}
+	if err := CheckRange(from, to, interval); err != nil {
+		return nil, err
+	}
+	resample := native.Interval != interval
+	// buckets are aligned to session of exchange instrument is traded on
+	session := SessionOf(CalendarOf(c, ticker))
+	if resample {
+		// first bucket is complete only if fetched from its start
+		from = interval.Start(from, session)
+	}
+	windows := splitWindows(from, to, native.MaxRange)
+
+	logger := logging.FromContext(ctx, c.logger).With(
+		zap.String("ticker", ticker),
+		zap.String("figi", tcsDescription.FIGI),
+		zap.String("type", string(tcsDescription.Type)),
+		zap.Stringer("interval", interval),
+		zap.Stringer("native_interval", native.Interval),
+		zap.Int("chunks", len(windows)),
+	)
+	start := time.Now()
+	tohlcs, err := fetchWindows(ctx, windows, maxChunkConcurrency, func(ctx context.Context, window timeWindow) ([]ohlc.TOHLCV, error) {
+		return c.fetchCandles(ctx, window, native.Tinkoff, tcsDescription.FIGI)
+	})
+	if err != nil {
+		logger.Warn("tinkoff candles request failed", zap.Duration("elapsed", time.Since(start)), zap.Error(err))
 		return nil, errors.Wrap(err, "can not get candles")
 	}
+	logger.Debug("tinkoff candles request done", zap.Duration("elapsed", time.Since(start)), zap.Int("candles", len(tohlcs)))
+
+	if resample {
+		tohlcs = ohlc.Resample(tohlcs, interval, session)
+	}
+	return &ohlc.CandlesticksData{
+		TOHLCs:            tohlcs,
+		Name:              tcsDescription.Name,
+		Ticker:            tcsDescription.Ticker,
+		Currency:          tcsDescription.Currency,
+		MinPriceIncrement: tcsDescription.MinPriceIncrement,
+		Interval:          interval.String(),
+	}, nil
+}
+
+// fetchCandles requests candles of one window through middlewares,
+// so failed window is retried alone and each attempt gets its own timeout
+func (c *TinkoffStockClient) fetchCandles(ctx context.Context, window timeWindow, interval sdk.CandleInterval, figi string) ([]ohlc.TOHLCV, error) {
+	var candles []sdk.Candle
+	err := chain(c.middlewares, MethodCandles, func(ctx context.Context) error {
+		var err error
+		candles, err = c.client.Candles(ctx, window.From, window.To, interval, figi)
+		return upstreamError(err)
+	})(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "window %s - %s", window.From.Format(time.RFC3339), window.To.Format(time.RFC3339))
+	}
 
 	tohlcs := make([]ohlc.TOHLCV, 0, len(candles))
 	for _, candle := range candles {
@@ -86,32 +184,120 @@ func (c *TinkoffStockClient) GetCandlesticks(ctx context.Context, from, to time.
 			},
 		})
 	}
-	return &ohlc.CandlesticksData{
-		TOHLCs:   tohlcs,
-		Name:     tcsDescription.Name,
-		Ticker:   tcsDescription.Ticker,
-		Currency: tcsDescription.Currency,
-		Interval: interval.String(),
+	return tohlcs, nil
+}
+
+// tinkoffInterval native tinkoff interval and max period of one candles request
+type tinkoffInterval struct {
+	Interval CandlestickInterval
+	Tinkoff  sdk.CandleInterval
+	MaxRange time.Duration
+}
+
+const (
+	oneDay  = 24 * time.Hour
+	oneWeek = 7 * oneDay
+	oneYear = 365 * oneDay
+)
+
+// tinkoffIntervals native tinkoff intervals from finest to coarsest,
+// 2 and 4 hour candles of tinkoff are aligned to UTC, so they are resampled from hours by session
+var tinkoffIntervals = []tinkoffInterval{
+	{CandlestickInterval1Min, sdk.CandleInterval1Min, oneDay},
+	{CandlestickInterval{Count: 2, Unit: ohlc.UnitMinute}, sdk.CandleInterval2Min, oneDay},
+	{CandlestickInterval{Count: 3, Unit: ohlc.UnitMinute}, sdk.CandleInterval3Min, oneDay},
+	{CandlestickInterval5Min, sdk.CandleInterval5Min, oneDay},
+	{CandlestickInterval{Count: 10, Unit: ohlc.UnitMinute}, sdk.CandleInterval10Min, oneDay},
+	{CandlestickInterval15Min, sdk.CandleInterval15Min, oneDay},
+	{CandlestickInterval{Count: 30, Unit: ohlc.UnitMinute}, sdk.CandleInterval30Min, oneDay},
+	{CandlestickInterval1Hour, sdk.CandleInterval1Hour, oneWeek},
+	{CandlestickInterval1Day, sdk.CandleInterval1Day, oneYear},
+	{CandlestickInterval1Week, sdk.CandleInterval1Week, 2 * oneYear},
+	{CandlestickInterval1Month, sdk.CandleInterval1Month, 10 * oneYear},
+}
+
+// MOEXSession session used to align resampled MOEX candles
+var MOEXSession = SessionOf(calendar.MOEX)
+
+// transformToTinkoffCandleInterval returns native interval equal to interval
+// or the coarsest native interval candles of interval can be resampled from
+func transformToTinkoffCandleInterval(interval CandlestickInterval) (tinkoffInterval, error) {
+	i, err := nativeInterval(interval, len(tinkoffIntervals), func(i int) CandlestickInterval {
+		return tinkoffIntervals[i].Interval
+	})
+	if err != nil {
+		return tinkoffInterval{}, err
+	}
+	return tinkoffIntervals[i], nil
+}
+
+// GetOrderBook returns order book with depth levels on each side
+func (c *TinkoffStockClient) GetOrderBook(ctx context.Context, depth int, ticker string) (*ohlc.OrderBook, error) {
+	if depth < 1 || depth > MaxOrderBookDepth {
+		return nil, ErrBadOrderBookDepth
+	}
+	tcsDescription, ok := c.registry.Lookup(ticker)
+	if !ok {
+		return nil, ErrUnknownTicker
+	}
+
+	logger := logging.FromContext(ctx, c.logger).With(
+		zap.String("ticker", ticker),
+		zap.String("figi", tcsDescription.FIGI),
+		zap.String("type", string(tcsDescription.Type)),
+		zap.Int("depth", depth),
+	)
+	start := time.Now()
+	var book sdk.RestOrderBook
+	err := chain(c.middlewares, MethodOrderBook, func(ctx context.Context) error {
+		var err error
+		book, err = c.client.Orderbook(ctx, depth, tcsDescription.FIGI)
+		return upstreamError(err)
+	})(ctx)
+	if err != nil {
+		logger.Warn("tinkoff orderbook request failed", zap.Duration("elapsed", time.Since(start)), zap.Error(err))
+		return nil, errors.Wrap(err, "can not get orderbook")
+	}
+	logger.Debug("tinkoff orderbook request done", zap.Duration("elapsed", time.Since(start)),
+		zap.Int("bids", len(book.Bids)), zap.Int("asks", len(book.Asks)))
+
+	minPriceIncrement := book.MinPriceIncrement
+	if minPriceIncrement == 0 {
+		minPriceIncrement = tcsDescription.MinPriceIncrement
+	}
+
+	return &ohlc.OrderBook{
+		Ticker:            tcsDescription.Ticker,
+		Name:              tcsDescription.Name,
+		Currency:          tcsDescription.Currency,
+		Bids:              transformPriceLevels(book.Bids),
+		Asks:              transformPriceLevels(book.Asks),
+		LastPrice:         book.LastPrice,
+		MinPriceIncrement: minPriceIncrement,
+		Trading:           book.TradeStatus == sdk.NormalTrading,
 	}, nil
 }
 
-func transformToTinkoffCandleInterval(interval CandlestickInterval) sdk.CandleInterval {
-	switch interval {
-	case CandlestickInterval1Min:
-		return sdk.CandleInterval1Min
-	case CandlestickInterval5Min:
-		return sdk.CandleInterval5Min
-	case CandlestickInterval15Min:
-		return sdk.CandleInterval15Min
-	case CandlestickInterval1Hour:
-		return sdk.CandleInterval1Hour
-	case CandlestickInterval1Day:
-		return sdk.CandleInterval1Day
-	case CandlestickInterval1Week:
-		return sdk.CandleInterval1Week
-	case CandlestickInterval1Month:
-		return sdk.CandleInterval1Month
-	default:
-		panic("wrong CandlestickInterval value")
+// tinkoffStatusRe http status in tinkoff sdk errors
+var tinkoffStatusRe = regexp.MustCompile(`code=(\d{3})`)
+
+// upstreamError marks tinkoff sdk error with http status of response if it has one, nil stays nil
+func upstreamError(err error) error {
+	if err == nil {
+		return nil
+	}
+	match := tinkoffStatusRe.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	statusCode, _ := strconv.Atoi(match[1])
+	return &UpstreamError{StatusCode: statusCode, Err: err}
+}
+
+func transformPriceLevels(levels []sdk.RestPriceQuantity) []ohlc.PriceLevel {
+	result := make([]ohlc.PriceLevel, 0, len(levels))
+	for _, level := range levels {
+		result = append(result, ohlc.PriceLevel{Price: level.Price, Quantity: level.Quantity})
 	}
+	return result
 }
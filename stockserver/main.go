@@ -7,111 +7,373 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/Apakhov/stocks-bot/calendar"
 	"github.com/Apakhov/stocks-bot/chartgen"
 	"github.com/Apakhov/stocks-bot/config"
+	"github.com/Apakhov/stocks-bot/instruments"
+	"github.com/Apakhov/stocks-bot/logging"
+	"github.com/Apakhov/stocks-bot/ohlc"
 	"github.com/Apakhov/stocks-bot/stockapi"
 	"github.com/Apakhov/stocks-bot/tcpproto"
 
 	"github.com/fasthttp/router"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/valyala/fasthttp"
 	"go.uber.org/zap"
 )
 
+const (
+	requestIDHeader = "X-Request-ID"
+
+	chartRequestParts     = 7
+	orderBookRequestParts = 4
+
+	maxSearchLimit = 50
+	// prevCloseSearchDays days before session searched for previous close, covers long holidays
+	prevCloseSearchDays = 14
+	dateLayout          = "2006-01-02"
+)
+
 // HTTPError represents http api error
 type HTTPError struct {
 	Message string `json:"message"`
 }
 
+// InstrumentSearchResult instrument found by search,
+// key can be used as ticker in chart and order book requests
+type InstrumentSearchResult struct {
+	Key               string  `json:"key"`
+	Ticker            string  `json:"ticker"`
+	FIGI              string  `json:"figi"`
+	ISIN              string  `json:"isin,omitempty"`
+	Name              string  `json:"name"`
+	Type              string  `json:"type"`
+	Currency          string  `json:"currency"`
+	Lot               int     `json:"lot"`
+	MinPriceIncrement float64 `json:"minPriceIncrement"`
+}
+
+// InstrumentSearchResponse response of instruments search
+type InstrumentSearchResponse struct {
+	Instruments []*InstrumentSearchResult `json:"instruments"`
+}
+
 // StockServerMetrics metrics
 type StockServerMetrics struct {
-	ChartRequests *prometheus.CounterVec
+	ChartRequests     *prometheus.CounterVec
+	OrderBookRequests *prometheus.CounterVec
+	SearchRequests    prometheus.Counter
+	StockAPI          *stockapi.PolicyMetrics
 }
 
 // StockServer server for stocks
 type StockServer struct {
 	stockAPI       stockapi.StockClient
 	chartGenerator *chartgen.ChartGenerator
+	themes         chartgen.Themes
 
 	metrics *StockServerMetrics
 	logger  *zap.Logger
 }
 
 // NewStockServer creates new stock server
-func NewStockServer(tinkoffToken string) (*StockServer, error) {
-	stockAPIClient, err := stockapi.NewTinkoffStockClient(tinkoffToken)
+func NewStockServer(tinkoffToken string, providers stockapi.ProvidersConfig, policy stockapi.PolicyConfig, themes chartgen.Themes, logConfig logging.Config) (*StockServer, error) {
+	logger, err := logging.New(logConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not initialize logger")
+	}
+	policyMetrics := stockapi.NewPolicyMetrics(prometheus.DefaultRegisterer)
+	stockAPIClient, err := stockapi.NewStockClient(tinkoffToken, providers, policy, policyMetrics, logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "can not initialize stock client")
 	}
 	generator := &chartgen.ChartGenerator{}
-	logger, err := zap.NewProduction()
-	if err != nil {
-		return nil, errors.Wrap(err, "can not initialize logger")
+
+	metrics := &StockServerMetrics{
+		ChartRequests:     prometheus.NewCounterVec(prometheus.CounterOpts{Name: "chart_req"}, []string{"ticker"}),
+		OrderBookRequests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "orderbook_req"}, []string{"ticker"}),
+		SearchRequests:    prometheus.NewCounter(prometheus.CounterOpts{Name: "search_req"}),
+		StockAPI:          policyMetrics,
 	}
+	prometheus.MustRegister(metrics.ChartRequests, metrics.OrderBookRequests, metrics.SearchRequests)
 
 	logger.Info("server created")
 	return &StockServer{
 		stockAPI:       stockAPIClient,
 		chartGenerator: generator,
+		themes:         themes,
 		logger:         logger,
-		metrics: &StockServerMetrics{
-			ChartRequests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "chart_req"}, []string{"ticker"}),
-		},
+		metrics:        metrics,
 	}, nil
 }
 
-func (s *StockServer) handleRequest(ticker, fromStr, toStr, intervalStr string) ([]byte, error) {
-	fmt.Println("handling: ", ticker, fromStr, toStr, intervalStr)
+// chartRequest parameters of chart request, empty optional parameters mean defaults
+type chartRequest struct {
+	Ticker   string
+	From     string
+	To       string
+	Interval string
+
+	Type        string
+	Indicators  string
+	Currency    string
+	Axis        string
+	Scale       string
+	Timezone    string
+	Theme       string
+	Annotations string
+	Levels      string
+}
 
-	from, err := time.Parse(time.RFC3339, fromStr)
+func (s *StockServer) handleRequest(ctx context.Context, req *chartRequest) ([]byte, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Info("handling chart request",
+		zap.String("ticker", req.Ticker),
+		zap.String("from", req.From),
+		zap.String("to", req.To),
+		zap.String("interval", req.Interval),
+		zap.String("type", req.Type),
+		zap.String("indicators", req.Indicators),
+		zap.String("currency", req.Currency),
+		zap.String("axis", req.Axis),
+		zap.String("scale", req.Scale),
+		zap.String("tz", req.Timezone),
+		zap.String("theme", req.Theme),
+		zap.String("annotations", req.Annotations),
+		zap.String("levels", req.Levels),
+	)
+
+	from, err := time.Parse(time.RFC3339, req.From)
 	if err != nil {
 		return nil, fmt.Errorf("can not parse 'from' path part: %w", err)
 	}
 
-	to, err := time.Parse(time.RFC3339, toStr)
+	to, err := time.Parse(time.RFC3339, req.To)
 	if err != nil {
 		return nil, fmt.Errorf("can not parse 'to' path part: %w", err)
 	}
 
-	interval, err := stockapi.ParseCandlestickInterval(intervalStr)
+	interval, err := stockapi.ParseCandlestickInterval(req.Interval)
 	if err != nil {
 		return nil, fmt.Errorf("can not parse 'interval' path part: %w", err)
 	}
+	if err := stockapi.CheckRange(from, to, interval); err != nil {
+		return nil, err
+	}
 
-	candlesticksData, err := s.stockAPI.GetCandlesticks(context.Background(), from, to, interval, ticker)
+	chartOptions, err := chartgen.ParseChartOptions(req.Type, req.Indicators)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse chart options: %w", err)
+	}
+	chartOptions.Axis, err = chartgen.ParseAxis(req.Axis)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse axis: %w", err)
+	}
+	chartOptions.Scale, err = chartgen.ParseScale(req.Scale)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse scale: %w", err)
+	}
+	chartOptions.Theme, err = s.themes.Get(req.Theme)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse theme: %w", err)
+	}
+	if req.Timezone != "" {
+		chartOptions.Location, err = time.LoadLocation(req.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse timezone: %w", err)
+		}
+	}
+	chartOptions.Annotations, err = chartgen.ParseAnnotations(req.Annotations)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse annotations: %w", err)
+	}
+	chartOptions.Levels, err = chartgen.ParseLevels(req.Levels)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse levels: %w", err)
+	}
+
+	currency := req.Currency
+	if currency != "" {
+		currency, err = stockapi.ParseCurrency(currency)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse currency: %w", err)
+		}
+	}
+
+	intraday := interval.Unit == ohlc.UnitMinute || interval.Unit == ohlc.UnitHour
+	if intraday {
+		chartOptions.Calendar = stockapi.CalendarOf(s.stockAPI, req.Ticker)
+	}
+
+	start := time.Now()
+	candlesticksData, err := stockapi.GetCandlesticksIn(ctx, s.stockAPI, from, to, interval, req.Ticker, currency)
 	if err != nil {
 		return nil, fmt.Errorf("can not fetch stock api data: %w", err)
 	}
+	logger.Debug("candlesticks fetched", zap.Duration("elapsed", time.Since(start)))
+
+	if intraday && chartOptions.HasAnnotation(chartgen.AnnotationPrevClose) && len(candlesticksData.TOHLCs) > 0 {
+		last := candlesticksData.TOHLCs[len(candlesticksData.TOHLCs)-1]
+		chartOptions.PrevClose, err = s.previousClose(ctx, req.Ticker, currency, chartOptions.Calendar, time.Unix(last.Timestamp, 0))
+		if err != nil {
+			// chart is still useful without previous close
+			logger.Warn("can not fetch previous close", zap.Error(err))
+		}
+	}
 
-	imageBytes, err := s.chartGenerator.GenerateChart(candlesticksData)
+	start = time.Now()
+	imageBytes, err := s.chartGenerator.GenerateChart(candlesticksData, chartOptions)
 	if err != nil {
 		return nil, fmt.Errorf("can not generate chart image: %w", err)
 	}
+	logger.Debug("chart generated", zap.Duration("elapsed", time.Since(start)), zap.Int("bytes", len(imageBytes)))
 
 	return imageBytes, nil
+}
 
+// previousClose returns close of the last session before session of t
+func (s *StockServer) previousClose(ctx context.Context, ticker, currency string, cal *calendar.Calendar, t time.Time) (float64, error) {
+	sessionOpen := cal.LastSession(t).Open
+	data, err := stockapi.GetCandlesticksIn(ctx, s.stockAPI, sessionOpen.AddDate(0, 0, -prevCloseSearchDays), sessionOpen,
+		stockapi.CandlestickInterval1Day, ticker, currency)
+	if err != nil {
+		return 0, err
+	}
+	// daily candles may start at midnight or at open, so they are compared by date
+	sessionDay := sessionOpen.In(cal.Location).Format(dateLayout)
+	for i := len(data.TOHLCs) - 1; i >= 0; i-- {
+		if time.Unix(data.TOHLCs[i].Timestamp, 0).In(cal.Location).Format(dateLayout) < sessionDay {
+			return data.TOHLCs[i].Close, nil
+		}
+	}
+	return 0, nil
+}
+
+// handleOrderBookRequest returns depth chart and order book it is drawn from,
+// book is returned with chartgen.ErrEmptyOrderBook too
+func (s *StockServer) handleOrderBookRequest(ctx context.Context, ticker, depthStr string) ([]byte, *ohlc.OrderBook, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Info("handling order book request", zap.String("ticker", ticker), zap.String("depth", depthStr))
+
+	depth := stockapi.MaxOrderBookDepth
+	if depthStr != "" {
+		var err error
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not parse depth: %w", err)
+		}
+	}
+
+	orderBookClient, ok := s.stockAPI.(stockapi.OrderBookClient)
+	if !ok {
+		return nil, nil, errors.New("stock api does not provide order books")
+	}
+
+	start := time.Now()
+	book, err := orderBookClient.GetOrderBook(ctx, depth, ticker)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not fetch order book: %w", err)
+	}
+	logger.Debug("order book fetched", zap.Duration("elapsed", time.Since(start)))
+
+	start = time.Now()
+	imageBytes, err := s.chartGenerator.GenerateDepthChart(book)
+	if err != nil {
+		return nil, book, fmt.Errorf("can not generate depth chart image: %w", err)
+	}
+	logger.Debug("depth chart generated", zap.Duration("elapsed", time.Since(start)), zap.Int("bytes", len(imageBytes)))
+
+	return imageBytes, book, nil
+}
+
+func (s *StockServer) handleSearchRequest(ctx context.Context, query, limitStr string) (*InstrumentSearchResponse, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Info("handling search request", zap.String("query", query), zap.String("limit", limitStr))
+
+	if query == "" {
+		return nil, errors.New("empty query")
+	}
+	limit := instruments.DefaultSearchLimit
+	if limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return nil, fmt.Errorf("limit must be from 1 to %d", maxSearchLimit)
+		}
+	}
+
+	instrumentClient, ok := s.stockAPI.(stockapi.InstrumentClient)
+	if !ok {
+		return nil, errors.New("stock api does not provide instruments")
+	}
+	registry := instrumentClient.Instruments()
+
+	found := registry.Search(query, limit)
+	response := &InstrumentSearchResponse{Instruments: make([]*InstrumentSearchResult, 0, len(found))}
+	for _, instrument := range found {
+		response.Instruments = append(response.Instruments, &InstrumentSearchResult{
+			Key:               registry.Key(instrument),
+			Ticker:            instrument.Ticker,
+			FIGI:              instrument.FIGI,
+			ISIN:              instrument.ISIN,
+			Name:              instrument.Name,
+			Type:              string(instrument.Type),
+			Currency:          instrument.Currency,
+			Lot:               instrument.Lot,
+			MinPriceIncrement: instrument.MinPriceIncrement,
+		})
+	}
+	logger.Debug("search done", zap.Int("found", len(found)))
+
+	return response, nil
+}
+
+// requestContext returns context with request id from header
+// and sets it to response
+func (s *StockServer) requestContext(ctx *fasthttp.RequestCtx) context.Context {
+	requestID := string(ctx.Request.Header.Peek(requestIDHeader))
+	if requestID == "" {
+		requestID = logging.NewRequestID()
+	}
+	ctx.Response.Header.Set(requestIDHeader, requestID)
+	return logging.WithRequestID(context.Background(), requestID)
 }
 
 // CandlestickChartHandler handler
 func (s *StockServer) CandlestickChartHttpHandler(ctx *fasthttp.RequestCtx) {
+	reqCtx := s.requestContext(ctx)
+	logger := logging.FromContext(reqCtx, s.logger)
+
 	s.metrics.ChartRequests.WithLabelValues("ALL").Inc()
-	s.logger.Info("got request", zap.String("uri", ctx.URI().String()))
+	logger.Info("got request", zap.String("uri", ctx.URI().String()))
 
 	ticker := ctx.UserValue("ticker").(string)
 	s.metrics.ChartRequests.WithLabelValues(ticker).Inc()
 
-	imageBytes, err := s.handleRequest(
-		ctx.UserValue("ticker").(string),
-		ctx.UserValue("from").(string),
-		ctx.UserValue("to").(string),
-		ctx.UserValue("interval").(string),
-	)
+	args := ctx.QueryArgs()
+	imageBytes, err := s.handleRequest(reqCtx, &chartRequest{
+		Ticker:      ticker,
+		From:        ctx.UserValue("from").(string),
+		To:          ctx.UserValue("to").(string),
+		Interval:    ctx.UserValue("interval").(string),
+		Type:        string(args.Peek("type")),
+		Indicators:  string(args.Peek("indicators")),
+		Currency:    string(args.Peek("currency")),
+		Axis:        string(args.Peek("axis")),
+		Scale:       string(args.Peek("scale")),
+		Timezone:    string(args.Peek("tz")),
+		Theme:       string(args.Peek("theme")),
+		Annotations: string(args.Peek("annotations")),
+		Levels:      string(args.Peek("levels")),
+	})
 
 	if err != nil {
-		fmt.Println("err handling", err)
+		logger.Warn("can not handle request", zap.Error(err))
 		s.WriteBadRequest(ctx, err.Error())
 		return
 	}
@@ -122,6 +384,48 @@ func (s *StockServer) CandlestickChartHttpHandler(ctx *fasthttp.RequestCtx) {
 	}
 }
 
+// OrderBookHttpHandler handler of order book depth chart
+func (s *StockServer) OrderBookHttpHandler(ctx *fasthttp.RequestCtx) {
+	reqCtx := s.requestContext(ctx)
+	logger := logging.FromContext(reqCtx, s.logger)
+	logger.Info("got request", zap.String("uri", ctx.URI().String()))
+
+	ticker := ctx.UserValue("ticker").(string)
+	s.metrics.OrderBookRequests.WithLabelValues(ticker).Inc()
+
+	imageBytes, _, err := s.handleOrderBookRequest(reqCtx, ticker, string(ctx.QueryArgs().Peek("depth")))
+	if err != nil {
+		logger.Warn("can not handle request", zap.Error(err))
+		s.WriteBadRequest(ctx, err.Error())
+		return
+	}
+
+	if err := s.WriteJPG(ctx, imageBytes); err != nil {
+		s.WriteInternalServerError(ctx, "can not write depth chart image")
+		return
+	}
+}
+
+// InstrumentSearchHttpHandler handler of instruments search
+func (s *StockServer) InstrumentSearchHttpHandler(ctx *fasthttp.RequestCtx) {
+	reqCtx := s.requestContext(ctx)
+	logger := logging.FromContext(reqCtx, s.logger)
+	logger.Info("got request", zap.String("uri", ctx.URI().String()))
+
+	s.metrics.SearchRequests.Inc()
+
+	response, err := s.handleSearchRequest(reqCtx, string(ctx.QueryArgs().Peek("q")), string(ctx.QueryArgs().Peek("limit")))
+	if err != nil {
+		logger.Warn("can not handle request", zap.Error(err))
+		s.WriteBadRequest(ctx, err.Error())
+		return
+	}
+
+	if err := s.WriteJSON(ctx, http.StatusOK, response); err != nil {
+		logger.Error("can not write search response", zap.Error(err))
+	}
+}
+
 // WriteBadRequest writes bad request with message
 func (s *StockServer) WriteBadRequest(ctx *fasthttp.RequestCtx, message string) {
 	if err := s.WriteJSON(ctx, http.StatusBadRequest, &HTTPError{Message: message}); err != nil {
@@ -154,76 +458,207 @@ func (s *StockServer) WriteJPG(ctx *fasthttp.RequestCtx, imageData []byte) error
 func (s *StockServer) CandlestickChartTcpHandler(conn net.Conn) {
 	defer conn.Close()
 
-	var ticker, dayAgoStr, nowStr, interval string
+	var parts []string
 	err := tcpproto.ReadMsg(conn, func(buf []byte) error {
-		_, err := tcpproto.ParseStrings(buf, &ticker, &dayAgoStr, &nowStr, &interval)
-		if err != nil {
-			return err
-		}
-
-		return nil
+		_, err := tcpproto.ParseStringSlice(buf, &parts)
+		return err
 	})
 	if err != nil {
-		fmt.Println("Error reading:", err.Error())
+		s.logger.Warn("can not read tcp request", zap.Error(err))
+		return
 	}
 
-	imageBytes, err := s.handleRequest(
-		ticker,
-		dayAgoStr,
-		nowStr,
-		interval,
-	)
+	var requestID string
+	switch {
+	case len(parts) == orderBookRequestParts && parts[0] == tcpproto.OrderBookRequest:
+		requestID = parts[3]
+	case len(parts) >= chartRequestParts && len(parts) <= chartRequestParts+2:
+		requestID = parts[4]
+	default:
+		s.logger.Warn("unknown tcp request", zap.Int("parts", len(parts)))
+		return
+	}
+	if requestID == "" {
+		requestID = logging.NewRequestID()
+	}
+	ctx := logging.WithRequestID(context.Background(), requestID)
+	logger := logging.FromContext(ctx, s.logger)
+
+	start := time.Now()
+	var response []byte
+	if parts[0] == tcpproto.OrderBookRequest {
+		s.metrics.OrderBookRequests.WithLabelValues(parts[1]).Inc()
+		response, err = s.orderBookResponse(ctx, parts[1], parts[2])
+	} else {
+		// ticker, from, to, interval, request id, chart type, indicators, optional currency and theme
+		req := &chartRequest{
+			Ticker:     parts[0],
+			From:       parts[1],
+			To:         parts[2],
+			Interval:   parts[3],
+			Type:       parts[5],
+			Indicators: parts[6],
+		}
+		if len(parts) > chartRequestParts {
+			req.Currency = parts[chartRequestParts]
+		}
+		if len(parts) > chartRequestParts+1 {
+			req.Theme = parts[chartRequestParts+1]
+		}
+		var imageBytes []byte
+		imageBytes, err = s.handleRequest(ctx, req)
+		response = tcpproto.PrepareBytes(nil, imageBytes)
+	}
 	if err != nil {
+		logger.Warn("can not handle tcp request", zap.Error(err))
+		return
+	}
+
+	if err := tcpproto.WriteMsg(conn, response); err != nil {
+		logger.Warn("can not write tcp response", zap.Error(err))
 		return
 	}
+	logger.Info("tcp request done", zap.Int("bytes", len(response)), zap.Duration("elapsed", time.Since(start)))
+}
 
-	fmt.Println("imageBytes sending size", len(imageBytes))
+// orderBookResponse returns depth chart, status and order book json, so bot captions the chart
+// with the same book, unknown tickers and empty books are statuses rather than errors
+func (s *StockServer) orderBookResponse(ctx context.Context, ticker, depth string) ([]byte, error) {
+	imageBytes, book, err := s.handleOrderBookRequest(ctx, ticker, depth)
+	status := tcpproto.OrderBookOK
+	switch {
+	case errors.Is(err, stockapi.ErrUnknownTicker):
+		status = tcpproto.OrderBookUnknownTicker
+	case errors.Is(err, chartgen.ErrEmptyOrderBook):
+		status = tcpproto.OrderBookEmpty
+	case err != nil:
+		return nil, err
+	}
 
-	tcpproto.WriteMsg(conn, tcpproto.PrepareBytes(nil, imageBytes))
+	bookBytes, err := json.Marshal(book)
+	if err != nil {
+		return nil, fmt.Errorf("can not encode order book: %w", err)
+	}
+	response := tcpproto.PrepareBytes(nil, imageBytes)
+	response = tcpproto.PrepareString(response, status)
+	return tcpproto.PrepareBytes(response, bookBytes), nil
 }
 
 func tcpStockServer(stockServer *StockServer, addr string) {
 	l, err := net.Listen("tcp", addr)
 	if err != nil {
-		fmt.Println("Error listening:", err.Error())
+		stockServer.logger.Error("can not listen tcp", zap.String("addr", addr), zap.Error(err))
 		os.Exit(1)
 	}
 	// Close the listener when the application closes.
 	defer l.Close()
-	fmt.Println("Listening on " + "stockserver:1467")
+	stockServer.logger.Info("listening tcp", zap.String("addr", addr))
+	serveTCP(l, stockServer.CandlestickChartTcpHandler, stockServer.logger)
+}
+
+// serveTCP accepts connections until listener is closed
+func serveTCP(l net.Listener, handle func(net.Conn), logger *zap.Logger) {
 	for {
 		// Listen for an incoming connection.
 		conn, err := l.Accept()
 		if err != nil {
-			fmt.Println("Error accepting: ", err.Error())
-			// os.Exit(1)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Warn("can not accept tcp connection", zap.Error(err))
+			continue
 		}
 		// Handle connections in a new goroutine.
-		stockServer.CandlestickChartTcpHandler(conn)
+		go handle(conn)
 	}
 }
 
+// serveMetrics serves prometheus metrics on /metrics
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		panic(err)
+	}
+}
+
+const (
+	envPrefix = "STOCKSERVER"
+	// themesEnvPrefix and holidaysEnvPrefix prefix environment overrides of ThemesFile and HolidaysFile
+	themesEnvPrefix   = "THEMES"
+	holidaysEnvPrefix = "HOLIDAYS"
+)
+
 type Config struct {
-	StocksHost   string `json:"StocksHost"`
-	StockTCPHost string `json:"StockTCPHost"`
-	TinkoffToken string `json:"TinkoffToken"`
+	StocksHost   string                   `json:"StocksHost" validate:"required"`
+	StockTCPHost string                   `json:"StockTCPHost" validate:"required"`
+	TinkoffToken string                   `json:"TinkoffToken" validate:"required"`
+	StockAPI     stockapi.PolicyConfig    `json:"StockAPI"`
+	Providers    stockapi.ProvidersConfig `json:"Providers"`
+	ThemesFile   string                   `json:"ThemesFile"`
+	HolidaysFile string                   `json:"HolidaysFile"`
+	MetricsHost  string                   `json:"MetricsHost"`
+	Log          logging.Config           `json:"Log"`
 }
 
 func main() {
 	var conf Config
-	config.GetConfig(os.Args, &conf)
+	if err := config.GetConfig(os.Args, envPrefix, &conf); err != nil {
+		fmt.Fprintln(os.Stderr, "can not load config:", err)
+		os.Exit(1)
+	}
 
-	stockServer, err := NewStockServer(conf.TinkoffToken)
+	themes, err := loadThemes(conf.ThemesFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := loadHolidays(conf.HolidaysFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	stockServer, err := NewStockServer(conf.TinkoffToken, conf.Providers, conf.StockAPI, themes, conf.Log)
 	if err != nil {
 		panic(err)
 	}
 
 	go tcpStockServer(stockServer, conf.StockTCPHost)
+	if conf.MetricsHost != "" {
+		go serveMetrics(conf.MetricsHost)
+	}
 
 	r := router.New()
 	r.GET("/candlesticks/{ticker}/{from}/{to}/{interval}/chart.jpg", stockServer.CandlestickChartHttpHandler)
+	r.GET("/orderbook/{ticker}/depth.jpg", stockServer.OrderBookHttpHandler)
+	r.GET("/instruments/search", stockServer.InstrumentSearchHttpHandler)
 
 	if err := fasthttp.ListenAndServe(conf.StocksHost, r.Handler); err != nil {
 		panic(err)
 	}
 }
+
+// loadThemes returns built-in themes and custom themes from file, empty path means only built-in ones
+func loadThemes(path string) (chartgen.Themes, error) {
+	var conf chartgen.ThemesConfig
+	if path != "" {
+		loader := &config.Loader{Path: path, EnvPrefix: themesEnvPrefix}
+		if err := loader.Load(&conf); err != nil {
+			return nil, errors.Wrap(err, "can not load themes")
+		}
+	}
+	return chartgen.NewThemes(conf)
+}
+
+// loadHolidays adds holidays from file to exchange calendars, empty path means only built-in ones
+func loadHolidays(path string) error {
+	if path == "" {
+		return nil
+	}
+	var conf calendar.HolidaysConfig
+	loader := &config.Loader{Path: path, EnvPrefix: holidaysEnvPrefix}
+	if err := loader.Load(&conf); err != nil {
+		return errors.Wrap(err, "can not load holidays")
+	}
+	return calendar.AddExchangeHolidays(conf)
+}
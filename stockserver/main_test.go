@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestServeTCPConcurrentClients(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// each handler holds its connection until both clients are being handled,
+	// so sequential handling never answers the first client
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	go serveTCP(l, func(conn net.Conn) {
+		defer conn.Close()
+		started <- struct{}{}
+		<-release
+		conn.Write([]byte{1})
+	}, zap.NewNop())
+
+	conns := make([]net.Conn, 2)
+	for i := range conns {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < len(conns); i++ {
+		select {
+		case <-started:
+		case <-timeout:
+			t.Fatalf("%d of %d connections handled at once", i, len(conns))
+		}
+	}
+	close(release)
+
+	for i, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 1)
+		if _, err := conn.Read(buf); err != nil {
+			t.Errorf("client %d: %v", i, err)
+		}
+	}
+}
@@ -7,6 +7,18 @@ import (
 	"github.com/pkg/errors"
 )
 
+// OrderBookRequest first string of order book request frame,
+// chart request frame starts with ticker
+const OrderBookRequest = "orderbook"
+
+// Statuses of order book response, which is depth chart image, status and order book json,
+// image is empty unless status is OrderBookOK
+const (
+	OrderBookOK            = "ok"
+	OrderBookUnknownTicker = "unknown_ticker"
+	OrderBookEmpty         = "empty"
+)
+
 func PrepareI32(buf []byte, i32 int32) []byte {
 	buf = append(buf, byte(i32>>(3*8)), byte(i32>>(2*8)), byte(i32>>(1*8)), byte(i32>>(0*8)))
 
@@ -36,18 +48,15 @@ func PrepareStrings(buf []byte, strs ...string) []byte {
 }
 
 func WriteMsg(w io.Writer, buf []byte) error {
-	fmt.Println("writing msg")
 	_, err := w.Write(PrepareI32([]byte{}, int32(len(buf))))
 	if err != nil {
 		return errors.Wrap(err, "writing msg len: ")
 	}
-	fmt.Println("writing preps")
 
 	_, err = w.Write(buf)
 	if err != nil {
 		return errors.Wrap(err, "writing strings: ")
 	}
-	fmt.Println("writing bufs")
 
 	return nil
 }
@@ -68,7 +77,6 @@ func ParseI32(buf []byte, i32 *int32) ([]byte, error) {
 func ParseBytes(buf []byte, bytes *[]byte) ([]byte, error) {
 	var bytesLen int32
 	buf, err := ParseI32(buf, &bytesLen)
-	fmt.Println("bytes recv", len(buf))
 	if err != nil {
 		return buf, err
 	}
@@ -115,6 +123,28 @@ func ParseStrings(buf []byte, strs ...*string) ([]byte, error) {
 	return buf, nil
 }
 
+// ParseStringSlice parses all strings of array
+func ParseStringSlice(buf []byte, strs *[]string) ([]byte, error) {
+	var arrLen int32
+	buf, err := ParseI32(buf, &arrLen)
+	if err != nil {
+		return buf, err
+	}
+	if arrLen < 0 || int(arrLen) > len(buf)/4 {
+		return buf, fmt.Errorf("bad strings count %d", arrLen)
+	}
+
+	*strs = make([]string, arrLen)
+	for i := range *strs {
+		buf, err = ParseString(buf, &(*strs)[i])
+		if err != nil {
+			return buf, err
+		}
+	}
+
+	return buf, nil
+}
+
 func ReadMsg(r io.Reader, parse func(buf []byte) error) error {
 	msgLenBuf := make([]byte, 4)
 	_, err := io.ReadFull(r, msgLenBuf)
@@ -133,8 +163,6 @@ func ReadMsg(r io.Reader, parse func(buf []byte) error) error {
 		return fmt.Errorf("failed to read msg: %w", err)
 	}
 
-	fmt.Println("recv msg", msgLenBuf, len(msgBuf), msgBuf[:16])
-
 	err = parse(msgBuf)
 	if err != nil {
 		return fmt.Errorf("failed to parse msg: %w", err)
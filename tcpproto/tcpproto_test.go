@@ -0,0 +1,63 @@
+package tcpproto
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	// chart request: ticker, from, to, interval, request id, chart type, indicators
+	request := []string{"SBER", "1638316800", "1638403200", "1hour", "0123456789abcdef", "candles", ""}
+
+	var buf bytes.Buffer
+	if err := WriteMsg(&buf, PrepareStrings(nil, request...)); err != nil {
+		t.Fatal(err)
+	}
+	var parts []string
+	err := ReadMsg(&buf, func(msg []byte) error {
+		_, err := ParseStringSlice(msg, &parts)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != len(request) {
+		t.Fatalf("read %d parts, want %d", len(parts), len(request))
+	}
+	for i := range request {
+		if parts[i] != request[i] {
+			t.Errorf("part %d = %q, want %q", i, parts[i], request[i])
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes left after frame", buf.Len())
+	}
+}
+
+func TestOrderBookRequestIDOverConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	const requestID = "fedcba9876543210"
+	errs := make(chan error, 1)
+	go func() {
+		errs <- WriteMsg(client, PrepareStrings(nil, OrderBookRequest, "SBER", "10", requestID))
+	}()
+
+	var kind, ticker, depth, gotID string
+	err := ReadMsg(server, func(msg []byte) error {
+		_, err := ParseStrings(msg, &kind, &ticker, &depth, &gotID)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if kind != OrderBookRequest || ticker != "SBER" || depth != "10" || gotID != requestID {
+		t.Errorf("read %q %q %q %q, want order book request of SBER with id %q", kind, ticker, depth, gotID, requestID)
+	}
+}
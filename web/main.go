@@ -1,36 +1,109 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"sync"
 	"text/template"
 
 	"github.com/Apakhov/stocks-bot/config"
+	"github.com/Apakhov/stocks-bot/instruments"
+	"github.com/Apakhov/stocks-bot/logging"
+
+	"go.uber.org/zap"
 )
 
+const envPrefix = "WEB"
+
 type Config struct {
-	HtmlFile   string `json:"HtmlFile"`
-	StocksHost string `json:"StocksHost"`
-	WebHost    string `json:"WebHost"`
+	HtmlFile     string         `json:"HtmlFile" validate:"required"`
+	StocksHost   string         `json:"StocksHost" validate:"required"`
+	WebHost      string         `json:"WebHost" validate:"required"`
+	FeaturedFile string         `json:"FeaturedFile" validate:"required"`
+	Log          logging.Config `json:"Log"`
 }
 
 type HtmlConf struct {
 	StocksHost string
 }
 
+// featuredStore keeps featured instruments, reloaded on SIGHUP
+type featuredStore struct {
+	mu     sync.RWMutex
+	groups []*instruments.Group
+}
+
+func (s *featuredStore) set(featured []*instruments.Featured) {
+	groups := instruments.GroupFeatured(featured)
+	s.mu.Lock()
+	s.groups = groups
+	s.mu.Unlock()
+}
+
+func (s *featuredStore) get() []*instruments.Group {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.groups
+}
+
 func main() {
+	loader := config.NewLoader(os.Args, envPrefix)
 	var conf Config
-	config.GetConfig(os.Args, &conf)
+	if err := loader.Load(&conf); err != nil {
+		fmt.Fprintln(os.Stderr, "can not load config:", err)
+		os.Exit(1)
+	}
+
+	logger, err := logging.New(conf.Log)
+	if err != nil {
+		panic(err)
+	}
+
+	featured, err := instruments.LoadFeatured(conf.FeaturedFile)
+	if err != nil {
+		panic(err)
+	}
+	store := &featuredStore{}
+	store.set(featured)
+
+	loader.OnReload(context.Background(), func() {
+		var newConf Config
+		if err := loader.Load(&newConf); err != nil {
+			logger.Error("can not reload config", zap.Error(err))
+			return
+		}
+		if changed := config.ChangedFields(conf, newConf, "FeaturedFile"); len(changed) > 0 {
+			logger.Warn("config changes are ignored until restart", zap.Strings("fields", changed))
+		}
+
+		featured, err := instruments.LoadFeatured(newConf.FeaturedFile)
+		if err != nil {
+			logger.Error("can not reload featured instruments", zap.Error(err))
+			return
+		}
+		store.set(featured)
+		logger.Info("featured instruments reloaded", zap.Int("count", len(featured)))
+	})
 
 	tmpl := template.Must(template.ParseFiles(conf.HtmlFile))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("make template")
-		tmpl.Execute(w, HtmlConf{StocksHost: conf.StocksHost})
+		logger.Debug("make template", zap.String("uri", r.RequestURI))
+		if err := tmpl.Execute(w, HtmlConf{StocksHost: conf.StocksHost}); err != nil {
+			logger.Warn("can not execute template", zap.Error(err))
+		}
+	})
+	http.HandleFunc("/api/featured", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(store.get()); err != nil {
+			logger.Warn("can not write featured instruments", zap.Error(err))
+		}
 	})
 
-	fmt.Println("start web on ", conf.WebHost)
+	logger.Info("start web", zap.String("addr", conf.WebHost))
 	if err := http.ListenAndServe(conf.WebHost, nil); err != nil {
-		fmt.Println(err.Error())
+		logger.Error("web server stopped", zap.Error(err))
 	}
 }